@@ -4,10 +4,20 @@ import (
 	"bufio"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/apachejuice/chomp/internal/book"
+	"github.com/apachejuice/chomp/internal/chomp"
+	"github.com/apachejuice/chomp/internal/engine"
+	"github.com/apachejuice/chomp/internal/pgn"
+	"github.com/apachejuice/chomp/internal/render"
 	"github.com/apachejuice/chomp/internal/server"
+	"github.com/apachejuice/chomp/internal/uci"
 	"github.com/gin-gonic/gin"
 )
 
@@ -24,6 +34,12 @@ const defaultConfig = `
     },
     "dbConfig": {
         "accountDatabase": "accounts.db"
+    },
+    "engineConfig": {
+        "bookFile": "",
+        "bookDepth": 0,
+        "syzygyPath": "",
+        "weightsFile": ""
     }
 }
 `
@@ -40,6 +56,23 @@ const usageTemplate = `Usage: %s [verb] [arguments]
 		--batch		Don't prompt the user for configurations, instead create a default one
 	run			Runs chomp with the default configuration
 		--debug		Sets debug mode (default release)
+	bench			Measures how fast the move generator is
+	perft FEN DEPTH		Counts the positions DEPTH moves deep from FEN, divided by the first move
+		--suite		Instead checks the counts of the standard reference positions
+		--max=NODES	With --suite, skips counts larger than NODES (default 10000000)
+	uci			Runs chomp's engine as a UCI engine on stdin and stdout, for use in chess GUIs
+	book OUT PGN...		Builds a Polyglot opening book OUT from the games in the PGN files
+		--depth=PLIES	Only adds the first PLIES plies of each game (default 20, 0 for all)
+	weights OUT		Writes the default evaluation weights to OUT as JSON, to be tuned
+	eval FEN		Shows the evaluation of FEN broken down into its terms
+		--weights=FILE	Evaluates with the weights in FILE instead of the default ones
+	board FEN		Shows the board of FEN as a text diagram
+		--unicode	Shows the pieces as figurines
+		--color		Colors the squares and pieces for terminals with ANSI colors
+		--flip		Shows the board from black's side
+		--coords	Labels the files and ranks
+		--svg=OUT	Writes an SVG diagram to OUT instead
+		--png=OUT	Writes a PNG image to OUT instead
 
 Bugreport address: <https://github.com/apachejuice/chomp/issues>
 `
@@ -96,6 +129,27 @@ func main() {
 	case "run":
 		startChomp(args[1:])
 		return
+	case "bench":
+		runBench(args[1:])
+		return
+	case "perft":
+		runPerft(args[1:])
+		return
+	case "uci":
+		runUCI(args[1:])
+		return
+	case "book":
+		runBook(args[1:])
+		return
+	case "weights":
+		runWeights(args[1:])
+		return
+	case "eval":
+		runEval(args[1:])
+		return
+	case "board":
+		runBoard(args[1:])
+		return
 	default:
 		fmt.Printf("Unknown command verb: %s\n", verb)
 		os.Exit(2)
@@ -128,6 +182,8 @@ func runInit(args []string) {
 
 		addr := ask("Enter the address (host:port) you wish to serve at:")
 		accDb := ask("Enter the name for the accounts database file:")
+		bookFile := ask("Enter the Polyglot opening book file for the engine (leave empty for none):")
+		syzygyPath := ask("Enter the directory of the Syzygy tablebase files for the engine (leave empty for none):")
 		c := server.ChompConfig{
 			Version: ver,
 			APIConfig: server.APIConfig{
@@ -140,6 +196,10 @@ func runInit(args []string) {
 			DBConfig: server.DatabaseConfig{
 				AccountDatabase: accDb,
 			},
+			EngineConfig: &server.EngineConfig{
+				BookFile:   bookFile,
+				SyzygyPath: syzygyPath,
+			},
 		}
 
 		useTLS := yesNo("Do you wish to enable TLS? (you need a certificate and key)")
@@ -190,3 +250,314 @@ func initialize() {
 	server.InitLog()
 	server.LoadConfig()
 }
+
+// Positions with a lot going on for the move generator: the starting position,
+// one full of castling, pins and en passant, and a sparse endgame.
+var benchPositions = []string{
+	chomp.StartFEN,
+	"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+	"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+}
+
+func runBench(args []string) {
+	if len(args) != 0 {
+		cmdErrorf("bench: unknown argument: %s", args[0])
+	}
+
+	for _, fen := range benchPositions {
+		g, err := chomp.ParseFEN(fen)
+		if err != nil {
+			cmdErrorf("internal error: %s", err.Error())
+		}
+
+		// run for about a second to get a stable number
+		n := 0
+		start := time.Now()
+		for time.Since(start) < time.Second {
+			for i := 0; i < 1000; i++ {
+				g.LegalMoves()
+			}
+
+			n += 1000
+		}
+
+		elapsed := time.Since(start)
+		fmt.Printf("%-75s %8d ns/op\n", fen, elapsed.Nanoseconds()/int64(n))
+	}
+}
+
+func runPerft(args []string) {
+	suite := false
+	maxNodes := uint64(10000000)
+	rest := []string{}
+	for _, e := range args {
+		if e == "--suite" {
+			suite = true
+		} else if strings.HasPrefix(e, "--max=") {
+			n, err := strconv.ParseUint(strings.TrimPrefix(e, "--max="), 10, 64)
+			if err != nil {
+				cmdErrorf("perft: invalid node count: %s\n", e)
+			}
+
+			maxNodes = n
+		} else if strings.HasPrefix(e, "--") {
+			cmdErrorf("perft: unknown argument: %s\n", e)
+		} else {
+			rest = append(rest, e)
+		}
+	}
+
+	if suite {
+		runPerftSuite(maxNodes)
+		return
+	}
+
+	if len(rest) != 2 {
+		cmdErrorf("perft: expected a FEN and a depth\n")
+	}
+
+	g, err := chomp.ParseFEN(rest[0])
+	if err != nil {
+		cmdErrorf("perft: %s\n", err.Error())
+	}
+
+	depth, err := strconv.Atoi(rest[1])
+	if err != nil || depth < 1 {
+		cmdErrorf("perft: invalid depth: %s\n", rest[1])
+	}
+
+	start := time.Now()
+	divide := g.Divide(depth)
+	elapsed := time.Since(start)
+
+	moves := make([]chomp.Move, 0, len(divide))
+	for m := range divide {
+		moves = append(moves, m)
+	}
+
+	sort.Slice(moves, func(i, j int) bool { return moves[i].UCI() < moves[j].UCI() })
+
+	var total uint64
+	for _, m := range moves {
+		fmt.Printf("%s: %d\n", m.UCI(), divide[m])
+		total += divide[m]
+	}
+
+	fmt.Printf("\nNodes: %d\nTime: %s\n", total, elapsed)
+}
+
+func runPerftSuite(maxNodes uint64) {
+	failed := false
+	for _, pos := range chomp.PerftPositions {
+		g, err := chomp.ParseFEN(pos.FEN)
+		if err != nil {
+			cmdErrorf("internal error: %s\n", err.Error())
+		}
+
+		for i, expected := range pos.Nodes {
+			if expected > maxNodes {
+				break
+			}
+
+			start := time.Now()
+			nodes := g.Perft(i + 1)
+			status := "ok"
+			if nodes != expected {
+				status = "FAIL"
+				failed = true
+			}
+
+			fmt.Printf("%-4s %-12s depth %d: %d nodes (expected %d) in %s\n", status, pos.Name, i+1, nodes, expected, time.Since(start))
+		}
+	}
+
+	if failed {
+		fmt.Println("perft: some counts did not match")
+		os.Exit(1)
+	}
+}
+
+func runUCI(args []string) {
+	if len(args) != 0 {
+		cmdErrorf("uci: unknown argument: %s\n", args[0])
+	}
+
+	if err := uci.Serve(os.Stdin, os.Stdout); err != nil {
+		cmdErrorf("uci: %s\n", err.Error())
+	}
+}
+
+func runBook(args []string) {
+	depth := 20
+	rest := []string{}
+	for _, e := range args {
+		if strings.HasPrefix(e, "--depth=") {
+			n, err := strconv.Atoi(strings.TrimPrefix(e, "--depth="))
+			if err != nil || n < 0 {
+				cmdErrorf("book: invalid depth: %s\n", e)
+			}
+
+			depth = n
+		} else if strings.HasPrefix(e, "--") {
+			cmdErrorf("book: unknown argument: %s\n", e)
+		} else {
+			rest = append(rest, e)
+		}
+	}
+
+	if len(rest) < 2 {
+		cmdErrorf("book: expected an output file and at least one PGN file\n")
+	}
+
+	builder := book.NewBuilder(depth)
+	for _, path := range rest[1:] {
+		f, err := os.Open(path)
+		if err != nil {
+			cmdErrorf("book: %s\n", err.Error())
+		}
+
+		r := pgn.NewReader(f)
+		for {
+			game, err := r.Read()
+			if err == io.EOF {
+				break
+			}
+
+			if err == nil {
+				err = builder.AddGame(game)
+			}
+
+			if err != nil {
+				cmdErrorf("book: %s: %s\n", path, err.Error())
+			}
+		}
+
+		f.Close()
+	}
+
+	b := builder.Book()
+	out, err := os.Create(rest[0])
+	if err != nil {
+		cmdErrorf("book: %s\n", err.Error())
+	}
+
+	defer out.Close()
+	if err = b.Write(out); err != nil {
+		cmdErrorf("book: %s\n", err.Error())
+	}
+
+	fmt.Printf("wrote %s: %d entries from %d games\n", rest[0], b.Len(), builder.Games())
+}
+
+func runWeights(args []string) {
+	if len(args) != 1 {
+		cmdErrorf("weights: expected an output file\n")
+	}
+
+	data, err := json.MarshalIndent(engine.DefaultWeights, "", "    ")
+	if err != nil {
+		cmdErrorf("weights: %s\n", err.Error())
+	}
+
+	if err = os.WriteFile(args[0], append(data, '\n'), 0644); err != nil {
+		cmdErrorf("weights: %s\n", err.Error())
+	}
+}
+
+func runEval(args []string) {
+	w := &engine.DefaultWeights
+	rest := []string{}
+	for _, e := range args {
+		if strings.HasPrefix(e, "--weights=") {
+			var err error
+			w, err = engine.LoadWeights(strings.TrimPrefix(e, "--weights="))
+			if err != nil {
+				cmdErrorf("eval: %s\n", err.Error())
+			}
+		} else if strings.HasPrefix(e, "--") {
+			cmdErrorf("eval: unknown argument: %s\n", e)
+		} else {
+			rest = append(rest, e)
+		}
+	}
+
+	if len(rest) != 1 {
+		cmdErrorf("eval: expected a FEN\n")
+	}
+
+	g, err := chomp.ParseFEN(rest[0])
+	if err != nil {
+		cmdErrorf("eval: %s\n", err.Error())
+	}
+
+	e := w.Evaluate(&g.Board)
+	terms := []struct {
+		name string
+		term engine.Term
+	}{
+		{"material", e.Material},
+		{"piece squares", e.PieceSquares},
+		{"mobility", e.Mobility},
+		{"king safety", e.KingSafety},
+		{"pawn structure", e.PawnStructure},
+	}
+
+	fmt.Printf("%-16s %7s %7s %7s\n", "term", "white", "black", "total")
+	for _, t := range terms {
+		fmt.Printf("%-16s %7d %7d %7d\n", t.name, t.term.White, t.term.Black, t.term.Total)
+	}
+
+	fmt.Printf("%-16s %23d\n", "total", e.Total)
+}
+
+func runBoard(args []string) {
+	o := render.Options{}
+	svg, png := "", ""
+	rest := []string{}
+	for _, e := range args {
+		switch {
+		case e == "--unicode":
+			o.Unicode = true
+		case e == "--color":
+			o.ANSI = true
+		case e == "--flip":
+			o.Flipped = true
+		case e == "--coords":
+			o.Coordinates = true
+		case strings.HasPrefix(e, "--svg="):
+			svg = strings.TrimPrefix(e, "--svg=")
+		case strings.HasPrefix(e, "--png="):
+			png = strings.TrimPrefix(e, "--png=")
+		case strings.HasPrefix(e, "--"):
+			cmdErrorf("board: unknown argument: %s\n", e)
+		default:
+			rest = append(rest, e)
+		}
+	}
+
+	if len(rest) != 1 {
+		cmdErrorf("board: expected a FEN\n")
+	}
+
+	g, err := chomp.ParseFEN(rest[0])
+	if err != nil {
+		cmdErrorf("board: %s\n", err.Error())
+	}
+
+	switch {
+	case svg != "":
+		err = os.WriteFile(svg, []byte(render.SVG(&g.Board, o)), 0644)
+	case png != "":
+		var f *os.File
+		if f, err = os.Create(png); err == nil {
+			err = render.PNG(f, &g.Board, o)
+			f.Close()
+		}
+	default:
+		fmt.Print(render.Text(&g.Board, o))
+	}
+
+	if err != nil {
+		cmdErrorf("board: %s\n", err.Error())
+	}
+}
@@ -0,0 +1,242 @@
+// Package book reads and writes opening books in the Polyglot format.
+// A Polyglot book is a file of 16-byte entries sorted by the Zobrist key of the position they are for,
+// each holding a move and how often it should be played.
+package book
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"sort"
+
+	"github.com/apachejuice/chomp/internal/chomp"
+)
+
+// The size of an entry in a Polyglot book file.
+const entrySize = 16
+
+// The promotion pieces of Polyglot moves, indexed by the number stored in the move.
+var promotions = []chomp.PieceType{
+	chomp.PieceTypeNone, chomp.PieceTypeKnight, chomp.PieceTypeBishop, chomp.PieceTypeRook, chomp.PieceTypeQueen,
+}
+
+// Represents an entry of a book as it is stored in the file.
+type Entry struct {
+	// The Zobrist key of the position
+	Key uint64
+	// The move packed into 16 bits: the target file and rank, the starting file and rank, and the promotion piece,
+	// three bits each from the lowest. Castling is stored as the king capturing its own rook.
+	Move uint16
+	// How good the move is compared to the other moves of the position. Moves are played in proportion to it.
+	Weight uint16
+	// Data learning programs can store about the move
+	Learn uint32
+}
+
+// A move of the book for a position.
+type Move struct {
+	// The move, which is legal in the position
+	Move chomp.Move `json:"move"`
+	// The weight of the move in the book
+	Weight uint16 `json:"weight"`
+}
+
+// An opening book: a collection of moves to play in positions, usually from the opening.
+type Book struct {
+	// Pick only plays book moves in the first this many plies of a game, or in all of them if 0
+	MaxPly int
+
+	entries []Entry
+}
+
+// Opens the Polyglot book file at the given path.
+func Open(path string) (*Book, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+	return Read(f)
+}
+
+// Reads a Polyglot book. The entries are sorted if they aren't already.
+func Read(r io.Reader) (*Book, error) {
+	b := &Book{}
+	br := bufio.NewReader(r)
+	buf := make([]byte, entrySize)
+	for {
+		_, err := io.ReadFull(br, buf)
+		if err == io.EOF {
+			break
+		} else if err == io.ErrUnexpectedEOF {
+			return nil, fmt.Errorf("invalid book: the size is not a multiple of %d bytes", entrySize)
+		} else if err != nil {
+			return nil, err
+		}
+
+		b.entries = append(b.entries, Entry{
+			Key:    binary.BigEndian.Uint64(buf[0:8]),
+			Move:   binary.BigEndian.Uint16(buf[8:10]),
+			Weight: binary.BigEndian.Uint16(buf[10:12]),
+			Learn:  binary.BigEndian.Uint32(buf[12:16]),
+		})
+	}
+
+	b.sort()
+	return b, nil
+}
+
+// Sorts the entries by key, and the entries of each position by weight, the highest first.
+func (b *Book) sort() {
+	sort.Slice(b.entries, func(i, j int) bool {
+		x, y := b.entries[i], b.entries[j]
+		switch {
+		case x.Key != y.Key:
+			return x.Key < y.Key
+		case x.Weight != y.Weight:
+			return x.Weight > y.Weight
+		}
+
+		return x.Move < y.Move
+	})
+}
+
+// Returns the number of entries in the book.
+func (b *Book) Len() int {
+	return len(b.entries)
+}
+
+// Writes the book in the Polyglot format.
+func (b *Book) Write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	buf := make([]byte, entrySize)
+	for _, e := range b.entries {
+		binary.BigEndian.PutUint64(buf[0:8], e.Key)
+		binary.BigEndian.PutUint16(buf[8:10], e.Move)
+		binary.BigEndian.PutUint16(buf[10:12], e.Weight)
+		binary.BigEndian.PutUint32(buf[12:16], e.Learn)
+		if _, err := bw.Write(buf); err != nil {
+			return err
+		}
+	}
+
+	return bw.Flush()
+}
+
+// Returns the entries for the position with the given key.
+func (b *Book) Entries(key uint64) []Entry {
+	i := sort.Search(len(b.entries), func(i int) bool { return b.entries[i].Key >= key })
+	j := i
+	for j < len(b.entries) && b.entries[j].Key == key {
+		j++
+	}
+
+	return b.entries[i:j]
+}
+
+// Returns the book moves for the current position of the game, the ones with the highest weight first.
+// Moves that are not legal in the position, which can be in the book when two positions have the same key,
+// are left out. The depth limit is not taken into account. Games of variants other than Chess960 have no book moves.
+func (b *Book) Moves(g *chomp.Game) []Move {
+	moves := []Move{}
+	if g.Variant != nil {
+		return moves
+	}
+
+	for _, e := range b.Entries(g.Hash()) {
+		m, ok := decodeMove(g, e.Move)
+		if ok {
+			moves = append(moves, Move{Move: m, Weight: e.Weight})
+		}
+	}
+
+	return moves
+}
+
+// Picks a book move for the current position of the game at random, each move being as likely as its weight.
+// Returns false if the book has no move for the position, or the game is past the book's depth limit.
+func (b *Book) Pick(g *chomp.Game) (chomp.Move, bool) {
+	if b.MaxPly > 0 && plyOf(g) >= b.MaxPly {
+		return chomp.Move{}, false
+	}
+
+	moves := b.Moves(g)
+	total := 0
+	for _, m := range moves {
+		total += int(m.Weight)
+	}
+
+	if total == 0 {
+		return chomp.Move{}, false
+	}
+
+	n := rand.Intn(total)
+	for _, m := range moves {
+		n -= int(m.Weight)
+		if n < 0 {
+			return m.Move, true
+		}
+	}
+
+	return chomp.Move{}, false
+}
+
+// Returns the number of plies played in the game before the current position, counted from the fullmove number
+// so that games starting from a FEN are counted right too.
+func plyOf(g *chomp.Game) int {
+	ply := (g.FullmoveNumber - 1) * 2
+	if g.Turn == chomp.ColorBlack {
+		ply++
+	}
+
+	return ply
+}
+
+// Turns a Polyglot move into the legal move of the game it stands for.
+func decodeMove(g *chomp.Game, raw uint16) (chomp.Move, bool) {
+	to := chomp.NewPos(int8(raw&7), int8(raw>>3&7))
+	from := chomp.NewPos(int8(raw>>6&7), int8(raw>>9&7))
+	promotion := int(raw >> 12 & 7)
+	if promotion >= len(promotions) {
+		return chomp.Move{}, false
+	}
+
+	// castling is stored as the king taking its own rook, which games accept whether they are Chess960 or not
+	m := chomp.Move{From: from, To: to, Promotion: promotions[promotion]}
+	legal, err := g.ParseUCI(m.UCI())
+	if err != nil {
+		return chomp.Move{}, false
+	}
+
+	return legal, true
+}
+
+// Packs a legal move of the game into a Polyglot move.
+func encodeMove(g *chomp.Game, m chomp.Move) uint16 {
+	// castling is stored as the king taking its own rook, which is how Chess960 games write it already
+	to := m.To
+	if isCastling(g, m) && m.To.X > m.From.X {
+		to = chomp.NewPos(7, m.From.Y)
+	} else if isCastling(g, m) {
+		to = chomp.NewPos(0, m.From.Y)
+	}
+
+	promotion := 0
+	for i, t := range promotions {
+		if i > 0 && t == m.Promotion {
+			promotion = i
+		}
+	}
+
+	return uint16(to.X) | uint16(to.Y)<<3 | uint16(m.From.X)<<6 | uint16(m.From.Y)<<9 | uint16(promotion)<<12
+}
+
+// Returns whether a legal move of a game that isn't Chess960 is castling, the only move a king makes two squares
+// to the side.
+func isCastling(g *chomp.Game, m chomp.Move) bool {
+	return !g.Chess960 && g.Board.At(m.From).Type() == chomp.PieceTypeKing && (m.To.X-m.From.X == 2 || m.From.X-m.To.X == 2)
+}
@@ -0,0 +1,190 @@
+package book
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/apachejuice/chomp/internal/chomp"
+	"github.com/apachejuice/chomp/internal/pgn"
+)
+
+const games = `[Result "1-0"]
+
+1. e4 e5 2. Nf3 Nc6 3. Bc4 Bc5 4. O-O 1-0
+
+[Result "0-1"]
+
+1. e4 c5 0-1
+
+[Result "1/2-1/2"]
+
+1. d4 d5 1/2-1/2
+`
+
+// Plays the moves, given in UCI notation, from the starting position.
+func playUCI(t *testing.T, moves ...string) *chomp.Game {
+	t.Helper()
+
+	g := chomp.NewGame()
+	for _, s := range moves {
+		m, err := g.ParseUCI(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := g.Apply(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return g
+}
+
+func TestBuildRoundTrip(t *testing.T) {
+	parsed, err := pgn.ReadAll(strings.NewReader(games))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := NewBuilder(0)
+	for _, game := range parsed {
+		if err := b.AddGame(game); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if b.Games() != 3 {
+		t.Errorf("the builder has %d games, want 3", b.Games())
+	}
+
+	var buf bytes.Buffer
+	if err := b.Book().Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	book, err := Read(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(book.entries, b.Book().entries) {
+		t.Errorf("the book read back has the entries %v, want %v", book.entries, b.Book().entries)
+	}
+
+	// e4 won once and lost once, d4 drew once
+	start := chomp.NewGame()
+	moves := book.Moves(start)
+	if len(moves) != 2 || moves[0].Move.UCI() != "e2e4" || moves[0].Weight != 2 || moves[1].Move.UCI() != "d2d4" || moves[1].Weight != 1 {
+		t.Errorf("got the moves %v from the starting position", moves)
+	}
+
+	for i := 0; i < 20; i++ {
+		m, ok := book.Pick(start)
+		if !ok || (m.UCI() != "e2e4" && m.UCI() != "d2d4") {
+			t.Fatalf("picked %s, %t from the starting position", m.UCI(), ok)
+		}
+	}
+
+	// e5 only lost, so c5 is the only answer to e4
+	if m, ok := book.Pick(playUCI(t, "e2e4")); !ok || m.UCI() != "c7c5" {
+		t.Errorf("picked %s, %t after e4", m.UCI(), ok)
+	}
+
+	// castling comes back as the king's move, not as the king taking the rook it is stored as
+	g := playUCI(t, "e2e4", "e7e5", "g1f3", "b8c6", "f1c4", "f8c5")
+	castling, _ := g.ParseUCI("e1g1")
+	if m, ok := book.Pick(g); !ok || m != castling {
+		t.Errorf("picked %s, %t instead of castling", m.UCI(), ok)
+	}
+
+	if _, ok := book.Pick(playUCI(t, "g1f3")); ok {
+		t.Errorf("picked a move in a position that isn't in the book")
+	}
+
+	book.MaxPly = 1
+	if _, ok := book.Pick(playUCI(t, "e2e4")); ok {
+		t.Errorf("picked a move past the depth limit")
+	}
+}
+
+func TestBuildVariants(t *testing.T) {
+	parsed, err := pgn.ReadAll(strings.NewReader("[Variant \"Crazyhouse\"]\n\n1. e4 d5 2. exd5 Qxd5 3. Nc3 Qa5 4. P@d4 *\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := NewBuilder(0)
+	if err := b.AddGame(parsed[0]); err == nil || err.Error() != "crazyhouse games can't be added to a Polyglot book" {
+		t.Errorf("got the error %v for a Crazyhouse game", err)
+	}
+
+	if b.Games() != 0 || len(b.Book().entries) != 0 {
+		t.Errorf("the Crazyhouse game was added")
+	}
+
+	// a book of standard games has nothing for other variants, even from the same position
+	standard, err := pgn.ReadAll(strings.NewReader("1. e4 *\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := b.AddGame(standard[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	g, err := chomp.NewVariantGame(chomp.Crazyhouse{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if moves := b.Book().Moves(g); len(moves) != 0 {
+		t.Errorf("got the moves %v in a Crazyhouse game", moves)
+	}
+}
+
+func TestReadInvalidSize(t *testing.T) {
+	if _, err := Read(bytes.NewReader(make([]byte, entrySize+1))); err == nil {
+		t.Errorf("a book with a partial entry was read")
+	}
+}
+
+func TestEncodeMove(t *testing.T) {
+	tests := []struct {
+		fen, uci string
+		raw      uint16
+	}{
+		{chomp.StartFEN, "e2e4", 1<<9 | 4<<6 | 3<<3 | 4},
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "e1g1", 4<<6 | 7},
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "e1c1", 4 << 6},
+		{"r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1", "e8g8", 7<<9 | 4<<6 | 7<<3 | 7},
+		// only castling is stored as taking the rook, not other king moves
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "e1f1", 4<<6 | 5},
+		// Chess960 castling takes the rook already, even two squares away
+		{"1r1k3r/8/8/8/8/8/8/1R1K3R w BHbh - 0 1", "d1b1", 3<<6 | 1},
+		{"1r1k3r/8/8/8/8/8/8/1R1K3R w BHbh - 0 1", "d1h1", 3<<6 | 7},
+		{"4k3/P7/8/8/8/8/8/4K3 w - - 0 1", "a7a8n", 1<<12 | 6<<9 | 7<<3},
+		{"4k3/P7/8/8/8/8/8/4K3 w - - 0 1", "a7a8q", 4<<12 | 6<<9 | 7<<3},
+	}
+
+	for _, test := range tests {
+		g, err := chomp.ParseFEN(test.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		m, err := g.ParseUCI(test.uci)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if raw := encodeMove(g, m); raw != test.raw {
+			t.Errorf("%s %s: got %#04x, want %#04x", test.fen, test.uci, raw, test.raw)
+		}
+
+		if decoded, ok := decodeMove(g, test.raw); !ok || decoded != m {
+			t.Errorf("%s: %#04x was decoded as %s, %t, want %s", test.fen, test.raw, decoded.UCI(), ok, test.uci)
+		}
+	}
+}
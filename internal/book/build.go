@@ -0,0 +1,102 @@
+package book
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/apachejuice/chomp/internal/chomp"
+	"github.com/apachejuice/chomp/internal/pgn"
+)
+
+// Identifies a move from a position while building a book.
+type positionMove struct {
+	key  uint64
+	move uint16
+}
+
+// Builds a Polyglot book from games. Each move gets points from the result of the games it was played in:
+// two for a win and one for a draw or an unknown result, so moves that won a lot are played the most.
+type Builder struct {
+	// Only the moves of the first this many plies of each game are added, or all of them if 0
+	MaxPly int
+
+	points map[positionMove]uint64
+	games  int
+}
+
+// Creates a builder that adds the moves of the first maxPly plies of each game, or all of them if maxPly is 0.
+func NewBuilder(maxPly int) *Builder {
+	return &Builder{MaxPly: maxPly, points: make(map[positionMove]uint64)}
+}
+
+// Adds the moves of the main line of a game to the book. Variations are not added.
+// Polyglot books only know the moves of standard chess and Chess960, so games of other variants,
+// such as the drops of Crazyhouse, are an error.
+func (b *Builder) AddGame(game *pgn.Game) error {
+	g, err := game.StartPosition()
+	if err != nil {
+		return err
+	}
+
+	if g.Variant != nil {
+		return fmt.Errorf("%s games can't be added to a Polyglot book", g.Variant.Name())
+	}
+
+	for i, n := range game.MainLine() {
+		if b.MaxPly > 0 && i >= b.MaxPly {
+			break
+		}
+
+		points := uint64(1)
+		switch {
+		case game.Result == pgn.ResultWhiteWins && g.Turn == chomp.ColorWhite,
+			game.Result == pgn.ResultBlackWins && g.Turn == chomp.ColorBlack:
+			points = 2
+		case game.Result == pgn.ResultWhiteWins, game.Result == pgn.ResultBlackWins:
+			points = 0
+		}
+
+		pm := positionMove{key: g.Hash(), move: encodeMove(g, n.Move)}
+		b.points[pm] += points
+		if err := g.ApplyPastEnd(n.Move); err != nil {
+			return err
+		}
+	}
+
+	b.games++
+	return nil
+}
+
+// Returns the number of games added to the builder.
+func (b *Builder) Games() int {
+	return b.games
+}
+
+// Returns the book of the moves added so far. Moves that only lost are left out, and the weights are
+// scaled down if needed to fit in 16 bits.
+func (b *Builder) Book() *Book {
+	most := uint64(0)
+	for _, points := range b.points {
+		if points > most {
+			most = points
+		}
+	}
+
+	scale := 1.0
+	if most > math.MaxUint16 {
+		scale = float64(math.MaxUint16) / float64(most)
+	}
+
+	book := &Book{MaxPly: b.MaxPly}
+	for pm, points := range b.points {
+		weight := uint16(float64(points) * scale)
+		if weight == 0 {
+			continue
+		}
+
+		book.entries = append(book.entries, Entry{Key: pm.key, Move: pm.move, Weight: weight})
+	}
+
+	book.sort()
+	return book
+}
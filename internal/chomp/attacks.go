@@ -0,0 +1,120 @@
+package chomp
+
+// Represents a sliding piece that would attack a target if a single piece between them was moved away.
+type XRay struct {
+	// The position of the sliding piece
+	Attacker Position `json:"attacker"`
+	// The position of the piece in between
+	Blocker Position `json:"blocker"`
+	// The position being attacked through the blocker
+	Target Position `json:"target"`
+}
+
+// Represents a piece that can't move off a line without exposing its king to an enemy slider.
+type Pin struct {
+	// The position of the pinned piece
+	Pinned Position `json:"pinned"`
+	// The position of the enemy piece pinning it
+	Pinner Position `json:"pinner"`
+}
+
+// Returns the positions of the pieces of the given color that attack the position.
+func (b *Board) Attackers(p Position, c Color) []Position {
+	bb := b.Bitboards()
+	return (bb.attackersTo(squareOf(p), bb.Occupied) & bb.Colors[colorIndex(c)]).Positions()
+}
+
+// Returns how many pieces of the given color attack each position on the board.
+// The map is indexed like the grid, first by the X and then by the Y coordinate.
+func (b *Board) AttackMap(c Color) [8][8]int {
+	var m [8][8]int
+	bb := b.Bitboards()
+	for sq := 0; sq < 64; sq++ {
+		m[sq%8][sq/8] = (bb.attackersTo(sq, bb.Occupied) & bb.Colors[colorIndex(c)]).Count()
+	}
+
+	return m
+}
+
+// Returns the sliding pieces of the given color that attack the position through exactly one other piece.
+func (b *Board) XRayAttackers(p Position, c Color) []XRay {
+	xrays := []XRay{}
+	for x := int8(0); x < 8; x++ {
+		for y := int8(0); y < 8; y++ {
+			from := NewPos(x, y)
+			piece := b.Grid[x][y]
+			if piece.Color() != c || from == p {
+				continue
+			}
+
+			lined := from.IsLinedUpWith(p)
+			sloped := from.IsOnSameSlope(p)
+			switch piece.Type() {
+			case PieceTypeRook:
+				sloped = false
+			case PieceTypeBishop:
+				lined = false
+			case PieceTypeQueen:
+			default:
+				continue
+			}
+
+			if !lined && !sloped {
+				continue
+			}
+
+			between, _ := PositionsBetween(from, p)
+			blockers := []Position{}
+			for _, q := range between {
+				if b.At(q) != PieceNone {
+					blockers = append(blockers, q)
+				}
+			}
+
+			if len(blockers) == 1 {
+				xrays = append(xrays, XRay{Attacker: from, Blocker: blockers[0], Target: p})
+			}
+		}
+	}
+
+	return xrays
+}
+
+// Returns the pieces of the given color that are pinned to their king.
+func (b *Board) Pinned(c Color) []Pin {
+	pins := []Pin{}
+	king, ok := b.findKing(c)
+	if !ok {
+		return pins
+	}
+
+	for _, xray := range b.XRayAttackers(king, c.Opposite()) {
+		if b.At(xray.Blocker).Color() == c {
+			pins = append(pins, Pin{Pinned: xray.Blocker, Pinner: xray.Attacker})
+		}
+	}
+
+	return pins
+}
+
+// Returns the discovered attacks the given color can make: its own pieces standing between
+// one of its sliders and an enemy piece, so that moving them away attacks that piece.
+func (b *Board) DiscoveredAttacks(c Color) []XRay {
+	discovered := []XRay{}
+	for x := int8(0); x < 8; x++ {
+		for y := int8(0); y < 8; y++ {
+			target := NewPos(x, y)
+			if b.Grid[x][y].Color() != c.Opposite() {
+				continue
+			}
+
+			for _, xray := range b.XRayAttackers(target, c) {
+				if b.At(xray.Blocker).Color() == c {
+					discovered = append(discovered, xray)
+				}
+			}
+		}
+	}
+
+	return discovered
+}
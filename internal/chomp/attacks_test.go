@@ -0,0 +1,122 @@
+package chomp
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func sortedNames(positions []Position) []string {
+	names := make([]string, len(positions))
+	for i, p := range positions {
+		names[i] = p.Name()
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+func mustParseFEN(t *testing.T, fen string) *Game {
+	t.Helper()
+	g, err := ParseFEN(fen)
+	if err != nil {
+		t.Fatalf("%s: %s", fen, err.Error())
+	}
+
+	return g
+}
+
+func TestAttackers(t *testing.T) {
+	b := mustParseFEN(t, "4k3/8/8/3p4/4P3/2N5/B7/3QK3 w - - 0 1").Board
+	tests := []struct {
+		square string
+		color  Color
+		want   []string
+	}{
+		{"d5", ColorWhite, []string{"a2", "c3", "d1", "e4"}},
+		{"e4", ColorBlack, []string{"d5"}},
+		{"e4", ColorWhite, []string{"c3"}},
+		{"f3", ColorWhite, []string{"d1"}},
+		{"f2", ColorWhite, []string{"e1"}},
+		// the pawn on d5 blocks the bishop
+		{"f7", ColorWhite, []string{}},
+		{"d7", ColorBlack, []string{"e8"}},
+		{"h8", ColorWhite, []string{}},
+	}
+
+	for _, test := range tests {
+		p, _ := ParsePosition(test.square)
+		if got := sortedNames(b.Attackers(p, test.color)); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s by %s: got %v, want %v", test.square, test.color, got, test.want)
+		}
+	}
+}
+
+func TestAttackMapMatchesIsAttacked(t *testing.T) {
+	fens := []string{
+		StartFEN,
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		"4k3/8/8/3p4/4P3/2N5/B7/3QK3 w - - 0 1",
+	}
+
+	for _, fen := range fens {
+		b := mustParseFEN(t, fen).Board
+		for _, c := range []Color{ColorWhite, ColorBlack} {
+			m := b.AttackMap(c)
+			for x := int8(0); x < 8; x++ {
+				for y := int8(0); y < 8; y++ {
+					p := NewPos(x, y)
+					if m[x][y] != len(b.Attackers(p, c)) || (m[x][y] > 0) != b.IsAttacked(p, c) {
+						t.Errorf("%s: %s is attacked %d times by %s", fen, p, m[x][y], c)
+					}
+				}
+			}
+		}
+	}
+
+	m := mustParseFEN(t, StartFEN).Board.AttackMap(ColorWhite)
+	if m[5][2] != 3 || m[4][2] != 2 || m[3][1] != 4 || m[4][3] != 0 {
+		t.Errorf("got %d, %d, %d and %d attacks on f3, e3, d2 and e4", m[5][2], m[4][2], m[3][1], m[4][3])
+	}
+}
+
+func TestXRaysAndPins(t *testing.T) {
+	// the black knight is pinned by the white rook, which is pinned by the black bishop in turn
+	b := mustParseFEN(t, "4k3/4n3/8/1b6/8/8/4R3/5K2 w - - 0 1").Board
+	e8, _ := ParsePosition("e8")
+	want := []XRay{{Attacker: NewPos(4, 1), Blocker: NewPos(4, 6), Target: e8}}
+	if got := b.XRayAttackers(e8, ColorWhite); !reflect.DeepEqual(got, want) {
+		t.Errorf("got the x-rays %v on e8, want %v", got, want)
+	}
+
+	if got, want := b.Pinned(ColorBlack), []Pin{{Pinned: NewPos(4, 6), Pinner: NewPos(4, 1)}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got the black pins %v, want %v", got, want)
+	}
+
+	if got, want := b.Pinned(ColorWhite), []Pin{{Pinned: NewPos(4, 1), Pinner: NewPos(1, 4)}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got the white pins %v, want %v", got, want)
+	}
+
+	// two pieces in between make no x-ray, and knights and kings never x-ray
+	b = mustParseFEN(t, "4k3/4n3/4p3/8/8/8/4R3/4K1N1 w - - 0 1").Board
+	if got := b.XRayAttackers(e8, ColorWhite); len(got) != 0 {
+		t.Errorf("got the x-rays %v through two pieces", got)
+	}
+
+	if got := b.Pinned(ColorBlack); len(got) != 0 {
+		t.Errorf("got the pins %v through two pieces", got)
+	}
+}
+
+func TestDiscoveredAttacks(t *testing.T) {
+	b := mustParseFEN(t, "q3k3/8/8/8/N7/8/8/R3K3 w - - 0 1").Board
+	want := []XRay{{Attacker: NewPos(0, 0), Blocker: NewPos(0, 3), Target: NewPos(0, 7)}}
+	if got := b.DiscoveredAttacks(ColorWhite); !reflect.DeepEqual(got, want) {
+		t.Errorf("got the discovered attacks %v, want %v", got, want)
+	}
+
+	// the queen looks at the rook through a knight, but it isn't black's to move away
+	if got := b.DiscoveredAttacks(ColorBlack); len(got) != 0 {
+		t.Errorf("got the discovered attacks %v for black", got)
+	}
+}
@@ -0,0 +1,348 @@
+package chomp
+
+import "math/bits"
+
+// Represents a set of positions on the board, one bit per position.
+// The bit of a position is Y*8 + X, so a1 is the lowest bit and h8 the highest.
+type Bitboard uint64
+
+// Returns the index of the position's bit in a bitboard.
+func squareOf(p Position) int {
+	return int(p.Y)*8 + int(p.X)
+}
+
+// Returns the position of the given bit index.
+func positionOf(sq int) Position {
+	return NewPos(int8(sq%8), int8(sq/8))
+}
+
+// Returns a bitboard with only the given position set.
+func BitboardOf(p Position) Bitboard {
+	if p.Locate() == LocationOutOfBounds {
+		return 0
+	}
+
+	return 1 << squareOf(p)
+}
+
+// Returns whether the position is in the set.
+func (b Bitboard) Has(p Position) bool {
+	return b&BitboardOf(p) != 0
+}
+
+// Returns the number of positions in the set.
+func (b Bitboard) Count() int {
+	return bits.OnesCount64(uint64(b))
+}
+
+// Returns the positions in the set, from a1 to h8.
+func (b Bitboard) Positions() []Position {
+	positions := make([]Position, 0, b.Count())
+	for b != 0 {
+		positions = append(positions, positionOf(b.popLowest()))
+	}
+
+	return positions
+}
+
+// Removes the lowest bit from the set and returns its index.
+func (b *Bitboard) popLowest() int {
+	sq := bits.TrailingZeros64(uint64(*b))
+	*b &= *b - 1
+	return sq
+}
+
+// Returns the index of the lowest bit in the set, or 64 if it is empty.
+func (b Bitboard) lowest() int {
+	return bits.TrailingZeros64(uint64(b))
+}
+
+// Returns the index of a color in arrays of two, white being 0 and black 1.
+func colorIndex(c Color) int {
+	return int(c) / 2
+}
+
+// Represents the pieces of a board as bitboards, which are much faster to generate moves with than the grid.
+type Bitboards struct {
+	// The positions of each piece, indexed by the piece constants
+	Pieces [12]Bitboard `json:"pieces"`
+	// The positions of all white and all black pieces
+	Colors [2]Bitboard `json:"colors"`
+	// The positions of all pieces
+	Occupied Bitboard `json:"occupied"`
+}
+
+// Returns the pieces of the board as bitboards. Invalid pieces in the grid are left out.
+func (b *Board) Bitboards() Bitboards {
+	var bb Bitboards
+	for x := 0; x < 8; x++ {
+		for y := 0; y < 8; y++ {
+			p := b.Grid[x][y]
+			if p.Color() != ColorNone {
+				bb.Pieces[p] |= 1 << (y*8 + x)
+			}
+		}
+	}
+
+	for p := PieceWhitePawn; p <= PieceBlackKing; p++ {
+		bb.Colors[p/6] |= bb.Pieces[p]
+	}
+
+	bb.Occupied = bb.Colors[0] | bb.Colors[1]
+	return bb
+}
+
+// Returns the grid of the pieces in the bitboards.
+// Converting a board with only valid pieces to bitboards and back gives the same grid.
+func (bb *Bitboards) Grid() [8][8]Piece {
+	var grid [8][8]Piece
+	for x := 0; x < 8; x++ {
+		for y := 0; y < 8; y++ {
+			grid[x][y] = PieceNone
+		}
+	}
+
+	for p := PieceWhitePawn; p <= PieceBlackKing; p++ {
+		for b := bb.Pieces[p]; b != 0; {
+			sq := b.popLowest()
+			grid[sq%8][sq/8] = Piece(p)
+		}
+	}
+
+	return grid
+}
+
+// Returns the piece of the given type and color.
+func (bb *Bitboards) piece(t PieceType, c Color) Bitboard {
+	return bb.Pieces[MakePiece(t, c)]
+}
+
+// Moves a piece from one square to another, removing whatever was captured on the target square.
+func (bb *Bitboards) move(piece Piece, from, to int, captured Piece) {
+	fromBit, toBit := Bitboard(1)<<from, Bitboard(1)<<to
+	if captured.Color() != ColorNone {
+		bb.Pieces[captured] &^= toBit
+		bb.Colors[captured/6] &^= toBit
+	}
+
+	bb.Pieces[piece] ^= fromBit | toBit
+	bb.Colors[piece/6] ^= fromBit | toBit
+	bb.Occupied = bb.Colors[0] | bb.Colors[1]
+}
+
+// Puts a piece on the given square, which must be empty.
+func (bb *Bitboards) add(piece Piece, sq int) {
+	bit := Bitboard(1) << sq
+	bb.Pieces[piece] |= bit
+	bb.Colors[piece/6] |= bit
+	bb.Occupied |= bit
+}
+
+// Removes a piece from the given square.
+func (bb *Bitboards) remove(piece Piece, sq int) {
+	bit := Bitboard(1) << sq
+	bb.Pieces[piece] &^= bit
+	bb.Colors[piece/6] &^= bit
+	bb.Occupied &^= bit
+}
+
+// Returns the pieces of both colors that attack the square, given the occupied squares.
+func (bb *Bitboards) attackersTo(sq int, occupied Bitboard) Bitboard {
+	return pawnAttacks[0][sq]&bb.Pieces[PieceBlackPawn] |
+		pawnAttacks[1][sq]&bb.Pieces[PieceWhitePawn] |
+		knightAttacks[sq]&(bb.Pieces[PieceWhiteKnight]|bb.Pieces[PieceBlackKnight]) |
+		kingAttacks[sq]&(bb.Pieces[PieceWhiteKing]|bb.Pieces[PieceBlackKing]) |
+		bishopAttacks(sq, occupied)&(bb.Pieces[PieceWhiteBishop]|bb.Pieces[PieceBlackBishop]|bb.Pieces[PieceWhiteQueen]|bb.Pieces[PieceBlackQueen]) |
+		rookAttacks(sq, occupied)&(bb.Pieces[PieceWhiteRook]|bb.Pieces[PieceBlackRook]|bb.Pieces[PieceWhiteQueen]|bb.Pieces[PieceBlackQueen])
+}
+
+// Returns whether the square is attacked by any piece of the given color.
+func (bb *Bitboards) isAttacked(sq int, by Color) bool {
+	return bb.attackersTo(sq, bb.Occupied)&bb.Colors[colorIndex(by)] != 0
+}
+
+// Returns the positions attacked by the piece on the given position, which are empty or hold a piece
+// of either color. Returns an empty set if there's no piece on the position.
+func (bb *Bitboards) Attacks(p Position) Bitboard {
+	sq := squareOf(p)
+	if p.Locate() == LocationOutOfBounds || bb.Occupied&(1<<sq) == 0 {
+		return 0
+	}
+
+	for piece := PieceWhitePawn; piece <= PieceBlackKing; piece++ {
+		if bb.Pieces[piece]&(1<<sq) == 0 {
+			continue
+		}
+
+		if Piece(piece).Type() == PieceTypePawn {
+			return pawnAttacks[colorIndex(Piece(piece).Color())][sq]
+		}
+
+		return attacksOf(Piece(piece).Type(), sq, bb.Occupied)
+	}
+
+	return 0
+}
+
+// Returns the squares the piece of type t on the square attacks, given the occupied squares.
+// Pawns are not handled here since their attacks depend on their color.
+func attacksOf(t PieceType, sq int, occupied Bitboard) Bitboard {
+	switch t {
+	case PieceTypeKnight:
+		return knightAttacks[sq]
+	case PieceTypeKing:
+		return kingAttacks[sq]
+	case PieceTypeBishop:
+		return bishopAttacks(sq, occupied)
+	case PieceTypeRook:
+		return rookAttacks(sq, occupied)
+	case PieceTypeQueen:
+		return bishopAttacks(sq, occupied) | rookAttacks(sq, occupied)
+	}
+
+	return 0
+}
+
+// Precomputed attacks of the pieces that don't slide, indexed by square.
+// Pawn attacks are indexed by the color index first.
+var (
+	knightAttacks [64]Bitboard
+	kingAttacks   [64]Bitboard
+	pawnAttacks   [2][64]Bitboard
+)
+
+// The data needed to look up the attacks of a sliding piece on a square with magic bitboards.
+// The occupied squares that can block the piece are multiplied by the magic number,
+// and the highest bits of the result give the index of the attacks in the table.
+type magic struct {
+	mask    Bitboard
+	magic   uint64
+	shift   uint
+	attacks []Bitboard
+}
+
+var (
+	rookMagics   [64]magic
+	bishopMagics [64]magic
+)
+
+func rookAttacks(sq int, occupied Bitboard) Bitboard {
+	m := &rookMagics[sq]
+	return m.attacks[(uint64(occupied&m.mask)*m.magic)>>m.shift]
+}
+
+func bishopAttacks(sq int, occupied Bitboard) Bitboard {
+	m := &bishopMagics[sq]
+	return m.attacks[(uint64(occupied&m.mask)*m.magic)>>m.shift]
+}
+
+func init() {
+	for sq := 0; sq < 64; sq++ {
+		p := positionOf(sq)
+		for _, o := range knightOffsets {
+			knightAttacks[sq] |= BitboardOf(p.offset(o[0], o[1]))
+		}
+
+		for _, o := range queenDirections {
+			kingAttacks[sq] |= BitboardOf(p.offset(o[0], o[1]))
+		}
+
+		for _, c := range []Color{ColorWhite, ColorBlack} {
+			dir := pawnDirection(c)
+			pawnAttacks[colorIndex(c)][sq] = BitboardOf(p.offset(-1, dir)) | BitboardOf(p.offset(1, dir))
+		}
+	}
+
+	initMagics(&rookMagics, rookDirections, &rookMagicNumbers)
+	initMagics(&bishopMagics, bishopDirections, &bishopMagicNumbers)
+}
+
+// Returns the squares a piece sliding in the given directions attacks, by walking along the rays.
+// This is slow and only used to fill in the magic tables.
+func slidingAttacks(sq int, occupied Bitboard, directions [][2]int8) Bitboard {
+	var attacks Bitboard
+	for _, d := range directions {
+		for p := positionOf(sq).offset(d[0], d[1]); p.Locate() != LocationOutOfBounds; p = p.offset(d[0], d[1]) {
+			attacks |= BitboardOf(p)
+			if occupied.Has(p) {
+				break
+			}
+		}
+	}
+
+	return attacks
+}
+
+// Returns the squares that can block a piece sliding in the given directions.
+// The last square of each ray is left out, since a piece there can't block anything further.
+func blockerMask(sq int, directions [][2]int8) Bitboard {
+	var mask Bitboard
+	for _, d := range directions {
+		for p := positionOf(sq).offset(d[0], d[1]); p.offset(d[0], d[1]).Locate() != LocationOutOfBounds; p = p.offset(d[0], d[1]) {
+			mask |= BitboardOf(p)
+		}
+	}
+
+	return mask
+}
+
+// The magic numbers for each square. They were found by trying sparse random numbers
+// until one worked, and are kept here so that the search doesn't slow down starting up.
+var rookMagicNumbers = [64]uint64{
+	0x1080004008801020, 0x0840092002c03000, 0x1900200010400900, 0x0880100008000480,
+	0x4200100420080200, 0x8100020100080400, 0x0200040110886200, 0x0200008040220411,
+	0x0404800084400220, 0x0000401000402000, 0x0086001081220440, 0x0408800800100280,
+	0x000a001201040820, 0x8848800200840080, 0x4001000100040200, 0x0442000102105084,
+	0x9080010020804100, 0x0040404000201009, 0x0000808010002009, 0x2200090021d00100,
+	0x0008008008040080, 0x0004004002010040, 0x0011040008015042, 0x00000a0001768104,
+	0x0000800080204009, 0x2010004140002001, 0x9800200280100080, 0x1000100080080080,
+	0x0442000a00049020, 0x2100040080020080, 0x0800120400900148, 0x0010040a00128541,
+	0x2800804000800030, 0x1010002000400041, 0x4000200011004100, 0x0610008410800800,
+	0x0400802402800800, 0xc100020080800400, 0x0002000802000401, 0x0182085882000401,
+	0x0220204000808000, 0x2860100040024022, 0x0001002004110040, 0x99101042000a0020,
+	0x0004080004008080, 0x0010040002008080, 0x2012004881020004, 0x8300842444820011,
+	0x0088403882010200, 0x0820400080210100, 0x0110910040a00300, 0x0801100280080480,
+	0x0242009008200600, 0x1002000489500200, 0x0040800200010080, 0x0091800041000080,
+	0x0000209300488001, 0x04c1002414824001, 0x020020000b001041, 0x7000100004200901,
+	0x8002002004100802, 0x30010002084c0007, 0x0888221800813004, 0x4000002840840112,
+}
+
+var bishopMagicNumbers = [64]uint64{
+	0xa010041108003100, 0x006082020a002900, 0x6810010619200000, 0x08281a0520000408,
+	0x0001104001000400, 0x0018901008048400, 0x00040a0210245280, 0x000200210808a402,
+	0x9140048410821200, 0x0800091010820041, 0x20504804832202c0, 0x0100091401081000,
+	0x8021011140000012, 0x0810020804450400, 0x208b0542109008a2, 0x0080084a08040204,
+	0x0040e2a80811244c, 0x2505022008008108, 0x0430220100420040, 0x010a040420220040,
+	0x1105000290400000, 0x0093001200822120, 0x4000a62048043004, 0x280120048a015004,
+	0x006090002a020814, 0x44042000240800d0, 0x01102800040a4400, 0x1004080080220040,
+	0x0001001011004024, 0x0010044000805040, 0x0914041200820100, 0x0004821012821480,
+	0x0024040500c05021, 0x0088611002080200, 0x0116080a00040020, 0x4000020080080080,
+	0x2450450140840040, 0x0000880201484100, 0x0222020404020092, 0x8081110600002e00,
+	0x2842101105000801, 0x1100809008001025, 0x00020202221c0400, 0x0422014022009020,
+	0x0210046102100c00, 0xc004008082029102, 0x00aa461801101200, 0x0404080080201108,
+	0x020542108c205002, 0x0410544804100100, 0x0040910841100000, 0x0400200042021100,
+	0x00004204850400c0, 0x0200100410a42102, 0x1040020801210102, 0x0805040410420000,
+	0x2884804130100200, 0x800c262201242000, 0x1058000194108800, 0x0014221054420204,
+	0x0104000012a02200, 0x0200881003300100, 0x0140400202840100, 0x0402020801010201,
+}
+
+// Fills in the attack tables for every square using the given magic numbers.
+func initMagics(table *[64]magic, directions [][2]int8, numbers *[64]uint64) {
+	for sq := 0; sq < 64; sq++ {
+		m := &table[sq]
+		m.mask = blockerMask(sq, directions)
+		m.magic = numbers[sq]
+		m.shift = uint(64 - m.mask.Count())
+		m.attacks = make([]Bitboard, 1<<m.mask.Count())
+
+		// go through every subset of the mask
+		var occ Bitboard
+		for {
+			m.attacks[(uint64(occ)*m.magic)>>m.shift] = slidingAttacks(sq, occ, directions)
+			occ = (occ - m.mask) & m.mask
+			if occ == 0 {
+				break
+			}
+		}
+	}
+}
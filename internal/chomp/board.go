@@ -52,6 +52,93 @@ func (p Piece) String() string {
 	return p.Name()
 }
 
+// Returns the letter of the piece in FEN, such as N for a white knight and n for a black one,
+// or a space if this is not a valid piece.
+func (p Piece) Letter() byte {
+	if p.Color() == ColorNone {
+		return ' '
+	}
+
+	return fenPieces[p]
+}
+
+// Returns the Unicode figurine of the piece, such as ♘ for a white knight and ♞ for a black one,
+// or a space if this is not a valid piece.
+func (p Piece) Figurine() rune {
+	if p.Color() == ColorNone {
+		return ' '
+	}
+
+	return figurines[p]
+}
+
+// Returns the color of this piece, or ColorNone if this is not a valid piece.
+func (p Piece) Color() Color {
+	switch {
+	case p >= PieceWhitePawn && p <= PieceWhiteKing:
+		return ColorWhite
+	case p >= PieceBlackPawn && p <= PieceBlackKing:
+		return ColorBlack
+	}
+
+	return ColorNone
+}
+
+// Returns the type of this piece regardless of its color.
+func (p Piece) Type() PieceType {
+	if p.Color() == ColorNone {
+		return PieceTypeNone
+	}
+
+	return PieceType(p % 6)
+}
+
+// Represents the type of a piece, without a color.
+type PieceType int8
+
+// These are in the same order as the piece constants.
+const (
+	PieceTypeNone   = -1
+	PieceTypePawn   = 0
+	PieceTypeRook   = 1
+	PieceTypeKnight = 2
+	PieceTypeBishop = 3
+	PieceTypeQueen  = 4
+	PieceTypeKing   = 5
+)
+
+var pieceTypeNames = map[PieceType]string{
+	PieceTypeNone:   "<none>",
+	PieceTypePawn:   "pawn",
+	PieceTypeRook:   "rook",
+	PieceTypeKnight: "knight",
+	PieceTypeBishop: "bishop",
+	PieceTypeQueen:  "queen",
+	PieceTypeKing:   "king",
+}
+
+func (t PieceType) String() string {
+	name, ok := pieceTypeNames[t]
+	if !ok {
+		return "<invalid>"
+	}
+
+	return name
+}
+
+// Returns the piece of type t and color c.
+func MakePiece(t PieceType, c Color) Piece {
+	if t == PieceTypeNone || c == ColorNone {
+		return PieceNone
+	}
+
+	if c == ColorBlack {
+		return Piece(t) + PieceBlackPawn
+	}
+
+	return Piece(t)
+}
+
 // Represents a color
 type Color int8
 
@@ -61,6 +148,29 @@ const (
 	ColorBlack = 2
 )
 
+// Returns the opposite color: black for white and white for black.
+func (c Color) Opposite() Color {
+	switch c {
+	case ColorWhite:
+		return ColorBlack
+	case ColorBlack:
+		return ColorWhite
+	}
+
+	return ColorNone
+}
+
+func (c Color) String() string {
+	switch c {
+	case ColorWhite:
+		return "white"
+	case ColorBlack:
+		return "black"
+	}
+
+	return "<none>"
+}
+
 // Represents a chess board
 type Board struct {
 	// The grid is represented as an array of numbers. Each number is a constant representing a piece.
@@ -70,4 +180,52 @@ type Board struct {
 	Checked Color `json:"checked"`
 	// Checkmate. If this value is anything else than ColorNone, the game has ended, and the opposite of this color won.
 	Checkmate Color `json:"checkmate"`
+	// Stalemate. If this value is anything else than ColorNone, this color has no legal moves
+	// without being in check, and the game has ended in a draw.
+	Stalemate Color `json:"stalemate"`
+	// The pieces each player holds to drop on the board, indexed by the color index. Only used in Crazyhouse and Bughouse.
+	Pockets [2]Pocket `json:"pockets"`
+	// The positions of pieces that were promoted from pawns, which go back into pockets as pawns when captured.
+	// Only tracked in Crazyhouse and Bughouse.
+	Promoted Bitboard `json:"promoted"`
+}
+
+var backRank = [8]PieceType{
+	PieceTypeRook, PieceTypeKnight, PieceTypeBishop, PieceTypeQueen,
+	PieceTypeKing, PieceTypeBishop, PieceTypeKnight, PieceTypeRook,
+}
+
+// Creates a board with the pieces in their starting positions.
+// White starts from the ranks with the Y coordinates 0 and 1, black from 6 and 7.
+func NewBoard() Board {
+	b := EmptyBoard()
+	for x := 0; x < 8; x++ {
+		b.Grid[x][0] = MakePiece(backRank[x], ColorWhite)
+		b.Grid[x][1] = PieceWhitePawn
+		b.Grid[x][6] = PieceBlackPawn
+		b.Grid[x][7] = MakePiece(backRank[x], ColorBlack)
+	}
+
+	return b
+}
+
+// Creates a board with no pieces on it.
+func EmptyBoard() Board {
+	b := Board{Checked: ColorNone, Checkmate: ColorNone, Stalemate: ColorNone}
+	for x := 0; x < 8; x++ {
+		for y := 0; y < 8; y++ {
+			b.Grid[x][y] = PieceNone
+		}
+	}
+
+	return b
+}
+
+// Returns the piece at the given position, or PieceNone if the position is out of bounds.
+func (b *Board) At(p Position) Piece {
+	if p.Locate() == LocationOutOfBounds {
+		return PieceNone
+	}
+
+	return b.Grid[p.X][p.Y]
 }
@@ -0,0 +1,90 @@
+package chomp
+
+import "fmt"
+
+// The number of Chess960 starting positions.
+const Chess960Positions = 960
+
+// The number of the Chess960 starting position that is the standard one.
+const Chess960Standard = 518
+
+// The ways to place the two knights on five empty squares, indexed by the knight digit of a starting position number.
+var chess960Knights = [10][2]int{{0, 1}, {0, 2}, {0, 3}, {0, 4}, {1, 2}, {1, 3}, {1, 4}, {2, 3}, {2, 4}, {3, 4}}
+
+// Returns the pieces of the back rank of the Chess960 starting position with the given number, from the a file.
+// Positions are numbered from 0 to 959 like Scharnagl did: the number gives the files of the bishops, then the queen,
+// then the knights, and the rooks and the king take the squares left over in that order.
+func chess960BackRank(n int) [8]PieceType {
+	var rank [8]PieceType
+	for x := range rank {
+		rank[x] = PieceTypeNone
+	}
+
+	rank[n%4*2+1] = PieceTypeBishop
+	n /= 4
+	rank[n%4*2] = PieceTypeBishop
+	n /= 4
+
+	// the pieces left are placed on the squares still empty, counted from the a file
+	place := func(t PieceType, i int) {
+		for x := range rank {
+			if rank[x] != PieceTypeNone {
+				continue
+			}
+
+			if i == 0 {
+				rank[x] = t
+				return
+			}
+
+			i--
+		}
+	}
+
+	place(PieceTypeQueen, n%6)
+	n /= 6
+
+	knights := chess960Knights[n]
+	place(PieceTypeKnight, knights[1])
+	place(PieceTypeKnight, knights[0])
+
+	place(PieceTypeRook, 0)
+	place(PieceTypeKing, 0)
+	place(PieceTypeRook, 0)
+	return rank
+}
+
+// Creates a Chess960 game from the starting position with the given number, between 0 and 959.
+// Position 518 is the standard starting position, but castling moves are still made by the king moving onto its rook.
+func NewChess960Game(n int) (*Game, error) {
+	if n < 0 || n >= Chess960Positions {
+		return nil, fmt.Errorf("Chess960 starting position must be between 0 and %d, got %d", Chess960Positions-1, n)
+	}
+
+	rank := chess960BackRank(n)
+	b := EmptyBoard()
+	g := &Game{
+		Turn:           ColorWhite,
+		Castling:       CastlingAll,
+		EnPassant:      ErrorPos,
+		FullmoveNumber: 1,
+		Chess960:       true,
+	}
+
+	rooks := []int8{}
+	for x := int8(0); x < 8; x++ {
+		b.Grid[x][0] = MakePiece(rank[x], ColorWhite)
+		b.Grid[x][1] = PieceWhitePawn
+		b.Grid[x][6] = PieceBlackPawn
+		b.Grid[x][7] = MakePiece(rank[x], ColorBlack)
+		if rank[x] == PieceTypeRook {
+			rooks = append(rooks, x)
+		}
+	}
+
+	g.Board = b
+	g.CastlingFiles = [4]int8{rooks[1], rooks[0], rooks[1], rooks[0]}
+	g.hash = g.computeHash()
+	g.updateStatus()
+	return g, nil
+}
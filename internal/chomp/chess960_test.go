@@ -0,0 +1,90 @@
+package chomp
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestChess960BackRank(t *testing.T) {
+	tests := map[int]string{
+		0:                "BBQNNRKR",
+		Chess960Standard: "RNBQKBNR",
+		959:              "RKRNNQBB",
+	}
+
+	for n, want := range tests {
+		g, err := NewChess960Game(n)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if rank := strings.Split(g.FEN(), "/")[7]; !strings.HasPrefix(rank, want) {
+			t.Errorf("position %d has the back rank %s, want %s", n, rank, want)
+		}
+
+		if g.Perft(2) != 400 {
+			t.Errorf("position %d has %d positions after two plies, want 400", n, g.Perft(2))
+		}
+	}
+
+	if _, err := NewChess960Game(Chess960Positions); err == nil {
+		t.Errorf("a starting position past the last one was created")
+	}
+}
+
+func TestChess960Perft(t *testing.T) {
+	tests := []struct {
+		fen   string
+		nodes []uint64
+	}{
+		{"bqnb1rkr/pp3ppp/3ppn2/2p5/5P2/P2P4/NPP1P1PP/BQ1BNRKR w HFhf - 2 9", []uint64{21, 528, 12189}},
+		{"2nnrbkr/p1qppppp/8/1ppb4/6PP/3PP3/PPP2P2/BQNNRBKR w HEhe - 1 9", []uint64{21, 807, 18002}},
+	}
+
+	for _, test := range tests {
+		g, err := ParseFEN(test.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !g.Chess960 {
+			t.Errorf("%s was not read as Chess960", test.fen)
+		}
+
+		for i, want := range test.nodes {
+			if got := g.Perft(i + 1); got != want {
+				t.Errorf("%s: depth %d: got %d nodes, want %d", test.fen, i+1, got, want)
+			}
+		}
+
+		if g.ShredderFEN() != test.fen {
+			t.Errorf("%s was written back as %s", test.fen, g.ShredderFEN())
+		}
+	}
+}
+
+func TestChess960Castling(t *testing.T) {
+	// the king and the rook start next to each other, and castling swaps them
+	g, err := ParseFEN("4k3/8/8/8/8/8/8/5RK1 w F - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := g.ParseUCI("g1f1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	san, err := g.SAN(m)
+	if err != nil || san != "O-O-O" {
+		t.Errorf("g1f1 is written as %s, %v, want O-O-O", san, err)
+	}
+
+	if err := g.Apply(m); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "4k3/8/8/8/8/8/8/2KR4 b - - 1 1"; g.FEN() != want {
+		t.Errorf("castling gave %s, want %s", g.FEN(), want)
+	}
+}
@@ -0,0 +1,282 @@
+package chomp
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The pieces a player holds to drop on the board, counted by piece type from pawns to queens.
+type Pocket [5]int
+
+// The order pieces are written in when a pocket is written in a FEN record.
+var pocketOrder = []PieceType{PieceTypeQueen, PieceTypeRook, PieceTypeBishop, PieceTypeKnight, PieceTypePawn}
+
+// Returns the number of pieces in the pocket.
+func (p Pocket) Count() int {
+	n := 0
+	for _, count := range p {
+		n += count
+	}
+
+	return n
+}
+
+// Returns the pieces of both pockets in the format of a FEN record, such as QNPp: white's in upper case first,
+// then black's in lower case.
+func (b *Board) pocketsFEN() string {
+	var sb strings.Builder
+	for _, c := range []Color{ColorWhite, ColorBlack} {
+		for _, t := range pocketOrder {
+			for i := 0; i < b.Pockets[colorIndex(c)][t]; i++ {
+				sb.WriteByte(fenPieces[MakePiece(t, c)])
+			}
+		}
+	}
+
+	return sb.String()
+}
+
+// Parses the pockets of a FEN record, written like pocketsFEN writes them, into the board.
+func (b *Board) parsePocketsFEN(pockets string) error {
+	b.Pockets = [2]Pocket{}
+	if pockets == "-" {
+		return nil
+	}
+
+	for _, c := range pockets {
+		piece := strings.IndexRune(fenPieces, c)
+		if piece < 0 || Piece(piece).Type() == PieceTypeKing {
+			return fmt.Errorf("invalid FEN: invalid piece '%c' in pocket", c)
+		}
+
+		b.Pockets[colorIndex(Piece(piece).Color())][Piece(piece).Type()]++
+	}
+
+	return nil
+}
+
+// Drops the piece from the pocket of its color onto the given position.
+// Returns the change to the Zobrist key of the pieces on the board.
+func (b *Board) drop(piece Piece, p Position) uint64 {
+	b.Pockets[colorIndex(piece.Color())][piece.Type()]--
+	return b.put(p, piece)
+}
+
+// Returns the piece the move captures as it goes into a pocket: pawns captured en passant and promoted pieces
+// become pawns. Returns PieceNone if the move captures nothing. The board must be the one before the move.
+func (b *Board) pocketPiece(m Move) Piece {
+	if m.IsDrop() {
+		return PieceNone
+	}
+
+	mover := b.At(m.From)
+	captured := b.At(m.To)
+	switch {
+	case captured == PieceNone && mover.Type() == PieceTypePawn && m.From.X != m.To.X:
+		return MakePiece(PieceTypePawn, mover.Color().Opposite())
+	case captured == PieceNone || captured.Color() == mover.Color():
+		return PieceNone
+	case b.Promoted.Has(m.To):
+		return MakePiece(PieceTypePawn, captured.Color())
+	}
+
+	return captured
+}
+
+// Returns the positions of the promoted pieces after the move. The board must be the one before the move.
+func (b *Board) promotedAfter(m Move) Bitboard {
+	promoted := b.Promoted &^ BitboardOf(m.To)
+	if m.IsDrop() {
+		return promoted
+	}
+
+	if b.Promoted.Has(m.From) || m.Promotion != PieceTypeNone {
+		promoted = promoted&^BitboardOf(m.From) | BitboardOf(m.To)
+	}
+
+	return promoted
+}
+
+// Returns the board as it was before the last move of the game was made.
+func (g *Game) boardBeforeLastMove() *Board {
+	return &g.history[len(g.history)-1].board
+}
+
+// The rules of Crazyhouse: the pieces a player captures go to their pocket, and instead of moving a piece they may
+// drop one from their pocket onto any empty square, even to give check or checkmate. Pawns can't be dropped on
+// the first or last rank. Promoted pieces go back into pockets as pawns when captured.
+// Drops are written like N@f3, with P for pawns. FEN records have the pockets in brackets after the piece placement,
+// such as [QNp], and promoted pieces are marked with a ~ after their letter.
+type Crazyhouse struct{ Standard }
+
+func (Crazyhouse) Name() string {
+	return "crazyhouse"
+}
+
+func (Crazyhouse) LegalMoves(g *Game) []Move {
+	return append(g.Board.legalMoves(g.Turn, g.castlingState(), g.EnPassant), g.legalDrops()...)
+}
+
+func (Crazyhouse) AfterMove(g *Game, m Move, capture bool) {
+	before := g.boardBeforeLastMove()
+	if p := before.pocketPiece(m); p != PieceNone {
+		g.Board.Pockets[colorIndex(p.Color().Opposite())][p.Type()]++
+	}
+
+	g.Board.Promoted = before.promotedAfter(m)
+}
+
+// Captured pieces can always be dropped back, so only bare kings with empty pockets are a draw.
+func (Crazyhouse) InsufficientMaterial(g *Game) bool {
+	return g.Board.onlyKings() && g.Board.Pockets[0].Count() == 0 && g.Board.Pockets[1].Count() == 0
+}
+
+func (Crazyhouse) HasPockets() bool {
+	return true
+}
+
+// Returns the legal drops of the player to move.
+func (g *Game) legalDrops() []Move {
+	pocket := g.Board.Pockets[colorIndex(g.Turn)]
+	if pocket.Count() == 0 {
+		return []Move{}
+	}
+
+	// a drop can't put the player's own king in check, so it is only illegal if it doesn't end a check
+	check := g.Board.InCheck(g.Turn)
+	drops := []Move{}
+	for t, count := range pocket {
+		if count == 0 {
+			continue
+		}
+
+		piece := MakePiece(PieceType(t), g.Turn)
+		for x := int8(0); x < 8; x++ {
+			for y := int8(0); y < 8; y++ {
+				p := NewPos(x, y)
+				if g.Board.Grid[x][y] != PieceNone || (piece.Type() == PieceTypePawn && (y == 0 || y == 7)) {
+					continue
+				}
+
+				if check {
+					after := g.Board
+					after.put(p, piece)
+					if after.InCheck(g.Turn) {
+						continue
+					}
+				}
+
+				drops = append(drops, NewDrop(PieceType(t), p))
+			}
+		}
+	}
+
+	return drops
+}
+
+// The rules of Bughouse, played by two teams of two on two boards at once. Each player's partner plays the other
+// color on the other board, and the pieces a player captures go to their partner's pocket to be dropped there.
+// Otherwise the rules are those of Crazyhouse. The boards are linked by a BughouseMatch; pieces captured on a
+// board that isn't part of one are lost.
+type Bughouse struct{ Crazyhouse }
+
+func (Bughouse) Name() string {
+	return "bughouse"
+}
+
+func (Bughouse) AfterMove(g *Game, m Move, capture bool) {
+	g.Board.Promoted = g.boardBeforeLastMove().promotedAfter(m)
+}
+
+// Partners can always pass more pieces, so no material is insufficient.
+func (Bughouse) InsufficientMaterial(g *Game) bool {
+	return false
+}
+
+// The number of boards of a Bughouse match.
+const BughouseBoards = 2
+
+// A match of Bughouse on two linked boards. The teams are the player of white on the first board with the player
+// of black on the second one, and the player of black on the first board with the player of white on the second.
+type BughouseMatch struct {
+	Boards [BughouseBoards]*Game
+}
+
+// Creates a Bughouse match with both boards at the starting position.
+func NewBughouseMatch() *BughouseMatch {
+	m := &BughouseMatch{}
+	for i := range m.Boards {
+		m.Boards[i] = NewGame()
+		m.Boards[i].Variant = Bughouse{}
+	}
+
+	return m
+}
+
+// Applies a move to the board with the given index and passes the piece it captures to the partner's pocket
+// on the other board. Returns an error if the move is not legal or the match is over.
+// The captured piece stays in the partner's pocket even if the move is undone.
+func (bm *BughouseMatch) Apply(board int, m Move) error {
+	if board < 0 || board >= BughouseBoards {
+		return fmt.Errorf("invalid board %d", board)
+	}
+
+	if bm.Outcome() != OutcomeOngoing {
+		return errGameOver
+	}
+
+	g := bm.Boards[board]
+	if err := g.Apply(m); err != nil {
+		return err
+	}
+
+	captured := g.boardBeforeLastMove().pocketPiece(g.history[len(g.history)-1].move)
+	if captured == PieceNone {
+		return nil
+	}
+
+	// the partner plays the color of the captured piece on the other board
+	partner := bm.Boards[1-board]
+	partner.Board.Pockets[colorIndex(captured.Color())][captured.Type()]++
+	partner.hash = partner.computeHash()
+	partner.updateStatus()
+	return nil
+}
+
+// Returns the team of the player of the given color on the board with the given index:
+// 0 for the team of white on the first board and 1 for the other.
+func BughouseTeam(board int, c Color) int {
+	if (board == 0) == (c == ColorWhite) {
+		return 0
+	}
+
+	return 1
+}
+
+// Returns the outcome of the match, which ends as soon as the game on either board does.
+// OutcomeWhiteWins means the team of white on the first board has won, and OutcomeBlackWins the other team.
+func (bm *BughouseMatch) Outcome() Outcome {
+	for i, g := range bm.Boards {
+		switch g.Outcome() {
+		case OutcomeOngoing:
+			continue
+		case OutcomeDraw:
+			return OutcomeDraw
+		case OutcomeWhiteWins:
+			return winOutcome(bughouseTeamColor(BughouseTeam(i, ColorWhite)))
+		}
+
+		return winOutcome(bughouseTeamColor(BughouseTeam(i, ColorBlack)))
+	}
+
+	return OutcomeOngoing
+}
+
+// Returns the color standing for the team with the given number in the outcome of a match.
+func bughouseTeamColor(team int) Color {
+	if team == 0 {
+		return ColorWhite
+	}
+
+	return ColorBlack
+}
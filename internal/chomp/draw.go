@@ -0,0 +1,213 @@
+package chomp
+
+import "fmt"
+
+// Represents the reason a game ended in a draw.
+type DrawReason int8
+
+const (
+	DrawReasonNone = 0
+	// The player to move has no legal moves and is not in check
+	DrawReasonStalemate = 1
+	// The same position occurred three times and a player claimed a draw
+	DrawReasonThreefoldRepetition = 2
+	// The same position occurred five times
+	DrawReasonFivefoldRepetition = 3
+	// Fifty moves were made by each player without a capture or pawn move, and a player claimed a draw
+	DrawReasonFiftyMoves = 4
+	// Seventy-five moves were made by each player without a capture or pawn move
+	DrawReasonSeventyFiveMoves = 5
+	// Neither player has enough material left to checkmate
+	DrawReasonInsufficientMaterial = 6
+)
+
+var drawReasonNames = map[DrawReason]string{
+	DrawReasonNone:                 "<none>",
+	DrawReasonStalemate:            "stalemate",
+	DrawReasonThreefoldRepetition:  "threefold repetition",
+	DrawReasonFivefoldRepetition:   "fivefold repetition",
+	DrawReasonFiftyMoves:           "fifty-move rule",
+	DrawReasonSeventyFiveMoves:     "seventy-five-move rule",
+	DrawReasonInsufficientMaterial: "insufficient material",
+}
+
+func (r DrawReason) String() string {
+	name, ok := drawReasonNames[r]
+	if !ok {
+		return "<invalid>"
+	}
+
+	return name
+}
+
+var errNoDrawToClaim = fmt.Errorf("no draw can be claimed in this position")
+
+// Returns the key that identifies the current position for the purposes of repetition.
+// Two positions are the same if the same pieces are on the same squares, the same player is to move,
+// and both players have the same castling and en passant options.
+func (g *Game) repetitionKey() uint64 {
+	ep := g.enPassantKey()
+	if ep != 0 && !g.canCaptureEnPassant() {
+		// the en passant position only makes a difference if the capture is actually possible
+		return g.hash ^ ep
+	}
+
+	return g.hash
+}
+
+// Returns whether the player to move has a legal en passant capture.
+func (g *Game) canCaptureEnPassant() bool {
+	pawn := MakePiece(PieceTypePawn, g.Turn)
+	for _, dx := range []int8{-1, 1} {
+		from := g.EnPassant.offset(dx, -pawnDirection(g.Turn))
+		if g.Board.At(from) != pawn {
+			continue
+		}
+
+		for _, m := range g.LegalMovesFrom(from) {
+			if m.To == g.EnPassant {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Returns how many times the current position has occurred in the game, including now.
+func (g *Game) Repetitions() int {
+	key := g.repetitionKey()
+	count := 1
+
+	// positions before the last capture or pawn move can't repeat, so they don't need to be checked
+	for i := len(g.history) - 1; i >= 0 && i >= len(g.history)-g.HalfmoveClock; i-- {
+		if g.history[i].key == key {
+			count++
+		}
+	}
+
+	return count
+}
+
+// Returns whether neither player can possibly checkmate the other with the pieces left on the board.
+// This is the case with king against king, a king and a minor piece against a king,
+// and when the only pieces besides the kings are bishops on squares of the same color.
+func (b *Board) IsInsufficientMaterial() bool {
+	knights := 0
+	bishops := [2]int{}
+	for x := int8(0); x < 8; x++ {
+		for y := int8(0); y < 8; y++ {
+			switch b.Grid[x][y].Type() {
+			case PieceTypePawn, PieceTypeRook, PieceTypeQueen:
+				return false
+			case PieceTypeKnight:
+				knights++
+			case PieceTypeBishop:
+				bishops[(x+y)%2]++
+			}
+		}
+	}
+
+	if knights > 0 {
+		return knights == 1 && bishops[0]+bishops[1] == 0
+	}
+
+	return bishops[0] == 0 || bishops[1] == 0
+}
+
+// Returns whether the player of the given color has the material to checkmate with some series of legal moves.
+// A lone king never can, and neither can a king with one knight or with bishops on squares of one color,
+// unless the other player has pieces that could block their own king in: a pawn, a knight, or a bishop
+// on squares of the other color, or any piece besides a queen for the knight.
+func (b *Board) HasMatingMaterial(c Color) bool {
+	knights := 0
+	bishops := [2]int{}
+	opponent := map[PieceType]int{}
+	opponentBishops := [2]int{}
+	for x := int8(0); x < 8; x++ {
+		for y := int8(0); y < 8; y++ {
+			piece := b.Grid[x][y]
+			if piece.Color() == c.Opposite() {
+				opponent[piece.Type()]++
+				if piece.Type() == PieceTypeBishop {
+					opponentBishops[(x+y)%2]++
+				}
+
+				continue
+			}
+
+			switch piece.Type() {
+			case PieceTypePawn, PieceTypeRook, PieceTypeQueen:
+				return true
+			case PieceTypeKnight:
+				knights++
+			case PieceTypeBishop:
+				bishops[(x+y)%2]++
+			}
+		}
+	}
+
+	switch {
+	case knights == 0 && bishops[0]+bishops[1] == 0:
+		return false
+	case knights == 1 && bishops[0]+bishops[1] == 0:
+		return opponent[PieceTypePawn]+opponent[PieceTypeRook]+opponent[PieceTypeKnight]+opponent[PieceTypeBishop] > 0
+	case knights == 0 && (bishops[0] == 0 || bishops[1] == 0):
+		// the king must be in a corner of bishop squares, with its own pieces on the squares of the other color
+		other := 0
+		if bishops[0] > 0 {
+			other = 1
+		}
+
+		return opponent[PieceTypePawn]+opponent[PieceTypeKnight]+opponentBishops[other] > 0
+	}
+
+	return true
+}
+
+// Returns the reason the game is drawn automatically, without anyone claiming it.
+func (g *Game) automaticDraw() DrawReason {
+	switch {
+	case g.Board.Stalemate != ColorNone:
+		return DrawReasonStalemate
+	case g.Board.Checkmate != ColorNone:
+		// a checkmate on the 75th move still counts
+		return DrawReasonNone
+	case g.variant().InsufficientMaterial(g):
+		return DrawReasonInsufficientMaterial
+	case g.HalfmoveClock >= 150:
+		return DrawReasonSeventyFiveMoves
+	case g.Repetitions() >= 5:
+		return DrawReasonFivefoldRepetition
+	}
+
+	return DrawReasonNone
+}
+
+// Returns the draw the player to move could claim right now, if any.
+func (g *Game) ClaimableDraw() (DrawReason, bool) {
+	if g.IsOver() {
+		return DrawReasonNone, false
+	}
+
+	if g.Repetitions() >= 3 {
+		return DrawReasonThreefoldRepetition, true
+	}
+
+	if g.HalfmoveClock >= 100 {
+		return DrawReasonFiftyMoves, true
+	}
+
+	return DrawReasonNone, false
+}
+
+// Ends the game in a draw if the player to move can claim one by threefold repetition or the fifty-move rule.
+func (g *Game) ClaimDraw() error {
+	reason, ok := g.ClaimableDraw()
+	if !ok {
+		return errNoDrawToClaim
+	}
+
+	g.DrawReason = reason
+	return nil
+}
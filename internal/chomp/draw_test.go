@@ -0,0 +1,213 @@
+package chomp
+
+import "testing"
+
+// Returns the position with the given square name, like e4.
+func square(name string) Position {
+	return NewPos(int8(name[0]-'a'), int8(name[1]-'1'))
+}
+
+// Returns a board with the given pieces on the squares with the given names.
+func boardWith(pieces map[string]Piece) Board {
+	b := EmptyBoard()
+	for name, piece := range pieces {
+		p := square(name)
+		b.Grid[p.X][p.Y] = piece
+	}
+
+	return b
+}
+
+// Applies moves given as the names of the squares a piece moves from and to, like g1f3.
+func playMoves(t *testing.T, g *Game, moves ...string) {
+	t.Helper()
+	for _, m := range moves {
+		if err := g.Apply(NewMove(square(m[:2]), square(m[2:]))); err != nil {
+			t.Fatalf("%s: %s", m, err.Error())
+		}
+	}
+}
+
+var knightShuffle = []string{"g1f3", "g8f6", "f3g1", "f6g8"}
+
+func TestRepetition(t *testing.T) {
+	g := NewGame()
+	playMoves(t, g, knightShuffle...)
+	if g.Repetitions() != 2 {
+		t.Errorf("the starting position occurred %d times, want 2", g.Repetitions())
+	}
+
+	if _, ok := g.ClaimableDraw(); ok {
+		t.Errorf("a draw could be claimed after the starting position occurred twice")
+	}
+
+	playMoves(t, g, knightShuffle...)
+	if reason, ok := g.ClaimableDraw(); !ok || reason != DrawReasonThreefoldRepetition {
+		t.Errorf("got the claimable draw %s, want %s", reason, DrawReason(DrawReasonThreefoldRepetition))
+	}
+
+	if g.IsOver() {
+		t.Errorf("the game ended on threefold repetition without a claim")
+	}
+
+	playMoves(t, g, knightShuffle...)
+	playMoves(t, g, knightShuffle[:3]...)
+	if g.IsOver() {
+		t.Errorf("the game ended before the position occurred five times")
+	}
+
+	playMoves(t, g, knightShuffle[3])
+	if g.DrawReason != DrawReasonFivefoldRepetition || g.Outcome() != OutcomeDraw {
+		t.Errorf("got the draw reason %s and outcome %s, want %s", g.DrawReason, g.Outcome(), DrawReason(DrawReasonFivefoldRepetition))
+	}
+
+	if err := g.Apply(NewMove(square("g1"), square("f3"))); err != errGameOver {
+		t.Errorf("a move after fivefold repetition was applied with the error %v", err)
+	}
+
+	if err := g.Undo(); err != nil || g.IsOver() {
+		t.Errorf("undoing the last move left the game over with the error %v", err)
+	}
+}
+
+func TestRepetitionNeedsSamePlayerToMove(t *testing.T) {
+	g := NewGameFromBoard(boardWith(map[string]Piece{
+		"e1": MakePiece(PieceTypeKing, ColorWhite),
+		"e8": MakePiece(PieceTypeKing, ColorBlack),
+		"h7": MakePiece(PieceTypeRook, ColorBlack),
+	}), ColorWhite)
+
+	// the king takes three moves to come back, so the same squares come back with the other player to move
+	playMoves(t, g, "e1d1", "h7g7", "d1d2", "g7h7", "d2e1")
+	if g.Repetitions() != 1 {
+		t.Errorf("the position occurred %d times, want 1", g.Repetitions())
+	}
+}
+
+func TestMoveRules(t *testing.T) {
+	tests := []struct {
+		clock     int
+		automatic DrawReason
+		claimable DrawReason
+	}{
+		{98, DrawReasonNone, DrawReasonNone},
+		{99, DrawReasonNone, DrawReasonFiftyMoves},
+		{148, DrawReasonNone, DrawReasonFiftyMoves},
+		{149, DrawReasonSeventyFiveMoves, DrawReasonNone},
+	}
+
+	for _, test := range tests {
+		g := NewGameFromBoard(boardWith(map[string]Piece{
+			"e1": MakePiece(PieceTypeKing, ColorWhite),
+			"a1": MakePiece(PieceTypeRook, ColorWhite),
+			"e8": MakePiece(PieceTypeKing, ColorBlack),
+		}), ColorWhite)
+
+		g.HalfmoveClock = test.clock
+		playMoves(t, g, "a1a2")
+		if g.DrawReason != test.automatic {
+			t.Errorf("clock %d: got the draw %s, want %s", test.clock, g.DrawReason, test.automatic)
+		}
+
+		if reason, _ := g.ClaimableDraw(); reason != test.claimable {
+			t.Errorf("clock %d: got the claimable draw %s, want %s", test.clock, reason, test.claimable)
+		}
+	}
+
+	// a pawn move resets the clock
+	g := NewGame()
+	g.HalfmoveClock = 99
+	playMoves(t, g, "e2e4")
+	if g.HalfmoveClock != 0 {
+		t.Errorf("the clock is %d after a pawn move, want 0", g.HalfmoveClock)
+	}
+}
+
+func TestCheckmateOnTheSeventyFifthMove(t *testing.T) {
+	g := NewGameFromBoard(boardWith(map[string]Piece{
+		"g1": MakePiece(PieceTypeKing, ColorWhite),
+		"a1": MakePiece(PieceTypeRook, ColorWhite),
+		"g8": MakePiece(PieceTypeKing, ColorBlack),
+		"f7": MakePiece(PieceTypePawn, ColorBlack),
+		"g7": MakePiece(PieceTypePawn, ColorBlack),
+		"h7": MakePiece(PieceTypePawn, ColorBlack),
+	}), ColorWhite)
+
+	g.HalfmoveClock = 149
+	playMoves(t, g, "a1a8")
+	if g.DrawReason != DrawReasonNone || g.Outcome() != OutcomeWhiteWins {
+		t.Errorf("got the draw %s and outcome %s, want the checkmate to count", g.DrawReason, g.Outcome())
+	}
+}
+
+func TestIsInsufficientMaterial(t *testing.T) {
+	wk, bk := MakePiece(PieceTypeKing, ColorWhite), MakePiece(PieceTypeKing, ColorBlack)
+	tests := []struct {
+		name   string
+		pieces map[string]Piece
+		want   bool
+	}{
+		{"K vs K", map[string]Piece{}, true},
+		{"KB vs K", map[string]Piece{"c1": MakePiece(PieceTypeBishop, ColorWhite)}, true},
+		{"KN vs K", map[string]Piece{"b8": MakePiece(PieceTypeKnight, ColorBlack)}, true},
+		{"KB vs KB on the same color", map[string]Piece{
+			"c1": MakePiece(PieceTypeBishop, ColorWhite),
+			"f8": MakePiece(PieceTypeBishop, ColorBlack),
+		}, true},
+		{"KB vs KB on different colors", map[string]Piece{
+			"c1": MakePiece(PieceTypeBishop, ColorWhite),
+			"c8": MakePiece(PieceTypeBishop, ColorBlack),
+		}, false},
+		{"KNN vs K", map[string]Piece{
+			"b1": MakePiece(PieceTypeKnight, ColorWhite),
+			"g1": MakePiece(PieceTypeKnight, ColorWhite),
+		}, false},
+		{"KBN vs K", map[string]Piece{
+			"c1": MakePiece(PieceTypeBishop, ColorWhite),
+			"g1": MakePiece(PieceTypeKnight, ColorWhite),
+		}, false},
+		{"KP vs K", map[string]Piece{"e2": MakePiece(PieceTypePawn, ColorWhite)}, false},
+		{"KR vs K", map[string]Piece{"a1": MakePiece(PieceTypeRook, ColorWhite)}, false},
+	}
+
+	for _, test := range tests {
+		test.pieces["e1"], test.pieces["e8"] = wk, bk
+		b := boardWith(test.pieces)
+		if got := b.IsInsufficientMaterial(); got != test.want {
+			t.Errorf("%s: got %t, want %t", test.name, got, test.want)
+		}
+	}
+
+	// a game that ends up with bare kings is drawn automatically
+	g := NewGameFromBoard(boardWith(map[string]Piece{
+		"e1": wk,
+		"e8": bk,
+		"e2": MakePiece(PieceTypeRook, ColorBlack),
+	}), ColorWhite)
+
+	playMoves(t, g, "e1e2")
+	if g.DrawReason != DrawReasonInsufficientMaterial {
+		t.Errorf("got the draw %s, want %s", g.DrawReason, DrawReason(DrawReasonInsufficientMaterial))
+	}
+}
+
+func TestClaimDraw(t *testing.T) {
+	g := NewGame()
+	if err := g.ClaimDraw(); err != errNoDrawToClaim {
+		t.Errorf("claiming a draw in the starting position gave the error %v", err)
+	}
+
+	playMoves(t, g, knightShuffle...)
+	playMoves(t, g, knightShuffle...)
+	if err := g.ClaimDraw(); err != nil {
+		t.Fatal(err)
+	}
+
+	if g.DrawReason != DrawReasonThreefoldRepetition || g.Outcome() != OutcomeDraw || !g.IsOver() {
+		t.Errorf("got the draw %s and outcome %s after claiming", g.DrawReason, g.Outcome())
+	}
+
+	if err := g.ClaimDraw(); err != errNoDrawToClaim {
+		t.Errorf("claiming a draw in a game that is over gave the error %v", err)
+	}
+}
@@ -0,0 +1,344 @@
+package chomp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The FEN of the standard starting position.
+const StartFEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+
+// The letters used for pieces in FEN, in the same order as the piece constants.
+const fenPieces = "PRNBQKprnbqk"
+
+var castlingLetters = []struct {
+	letter byte
+	rights CastlingRights
+}{
+	{'K', CastlingWhiteKingside},
+	{'Q', CastlingWhiteQueenside},
+	{'k', CastlingBlackKingside},
+	{'q', CastlingBlackQueenside},
+}
+
+// Parses a position in Forsyth-Edwards Notation.
+// The halfmove clock and fullmove number may be left out, in which case they default to 0 and 1.
+// Castling rights may also be written like in X-FEN or Shredder-FEN, with the file of the rook instead of K or Q.
+// The game is Chess960 if a king or rook with castling rights is not on its square of the standard starting position.
+func ParseFEN(fen string) (*Game, error) {
+	return ParseVariantFEN(Standard{}, fen)
+}
+
+// Parses a position of the given variant in Forsyth-Edwards Notation, like ParseFEN.
+// A field the variant adds, such as the remaining checks of Three-check, comes after the en passant field.
+// The pockets of Crazyhouse and Bughouse come in brackets after the piece placement, and are empty if left out.
+func ParseVariantFEN(v Variant, fen string) (*Game, error) {
+	fields := strings.Fields(fen)
+	extra := ""
+	// the variant adds a field if it writes one for any game, even one with nothing set
+	if v.FENField(&Game{}) != "" && (len(fields) == 5 || len(fields) == 7) {
+		extra = fields[4]
+		fields = append(fields[:4:4], fields[5:]...)
+	}
+
+	if len(fields) != 6 && len(fields) != 4 {
+		return nil, fmt.Errorf("invalid FEN: expected 4 or 6 fields, got %d", len(fields))
+	}
+
+	placement, pockets, hasPockets := strings.Cut(fields[0], "[")
+	if hasPockets && !v.HasPockets() {
+		return nil, fmt.Errorf("invalid FEN: pockets given in a variant without drops")
+	}
+
+	board, err := parseFENBoard(placement)
+	if err != nil {
+		return nil, err
+	}
+
+	if hasPockets {
+		if !strings.HasSuffix(pockets, "]") {
+			return nil, fmt.Errorf("invalid FEN: pockets are missing the closing bracket")
+		}
+
+		if err = board.parsePocketsFEN(strings.TrimSuffix(pockets, "]")); err != nil {
+			return nil, err
+		}
+	}
+
+	g := &Game{Board: board, EnPassant: ErrorPos, FullmoveNumber: 1, CastlingFiles: standardCastlingFiles}
+	if _, ok := v.(Standard); !ok {
+		g.Variant = v
+	}
+
+	if extra != "" {
+		if err = v.ParseFENField(g, extra); err != nil {
+			return nil, err
+		}
+	}
+
+	switch fields[1] {
+	case "w":
+		g.Turn = ColorWhite
+	case "b":
+		g.Turn = ColorBlack
+	default:
+		return nil, fmt.Errorf("invalid FEN: side to move must be 'w' or 'b', got '%s'", fields[1])
+	}
+
+	if err = g.parseFENCastling(fields[2]); err != nil {
+		return nil, err
+	}
+
+	if fields[3] != "-" {
+		ep, err := ParsePosition(fields[3])
+		if err != nil {
+			return nil, fmt.Errorf("invalid FEN: en passant: %s", err.Error())
+		}
+
+		// the pawn that just moved two squares belongs to the side that is not to move.
+		// In Horde, white's pawns on the first rank may move two squares too.
+		rank := backRankOf(g.Turn.Opposite()) + 2*pawnDirection(g.Turn.Opposite())
+		_, horde := v.(Horde)
+		if ep.Y != rank && !(horde && g.Turn == ColorBlack && ep.Y == 1) {
+			return nil, fmt.Errorf("invalid FEN: en passant square %s is not on the right rank", fields[3])
+		}
+
+		g.EnPassant = ep
+	}
+
+	if len(fields) == 6 {
+		g.HalfmoveClock, err = strconv.Atoi(fields[4])
+		if err != nil || g.HalfmoveClock < 0 {
+			return nil, fmt.Errorf("invalid FEN: halfmove clock must be a non-negative number, got '%s'", fields[4])
+		}
+
+		g.FullmoveNumber, err = strconv.Atoi(fields[5])
+		if err != nil || g.FullmoveNumber < 1 {
+			return nil, fmt.Errorf("invalid FEN: fullmove number must be a positive number, got '%s'", fields[5])
+		}
+	}
+
+	g.hash = g.computeHash()
+	g.updateStatus()
+	return g, nil
+}
+
+func parseFENBoard(placement string) (Board, error) {
+	b := EmptyBoard()
+	ranks := strings.Split(placement, "/")
+	if len(ranks) != 8 {
+		return b, fmt.Errorf("invalid FEN: expected 8 ranks, got %d", len(ranks))
+	}
+
+	for i, rank := range ranks {
+		y := int8(7 - i)
+		x := int8(0)
+		for _, c := range rank {
+			if c >= '1' && c <= '8' {
+				x += int8(c - '0')
+				continue
+			}
+
+			// a promoted piece of Crazyhouse is marked after its letter
+			if c == '~' && x > 0 && b.Grid[x-1][y] != PieceNone {
+				b.Promoted |= BitboardOf(NewPos(x-1, y))
+				continue
+			}
+
+			piece := strings.IndexRune(fenPieces, c)
+			if piece < 0 {
+				return b, fmt.Errorf("invalid FEN: unknown piece '%c' on rank %d", c, y+1)
+			}
+
+			if x > 7 {
+				return b, fmt.Errorf("invalid FEN: rank %d has more than 8 squares", y+1)
+			}
+
+			b.Grid[x][y] = Piece(piece)
+			x++
+		}
+
+		if x != 8 {
+			return b, fmt.Errorf("invalid FEN: rank %d has %d squares instead of 8", y+1, x)
+		}
+	}
+
+	return b, nil
+}
+
+// Parses the castling rights of a FEN record into the game, whose board must be set.
+// K and Q stand for the outermost rook on each side of the king, and a file letter for the rook on that file.
+func (g *Game) parseFENCastling(field string) error {
+	g.Castling = CastlingNone
+	if field == "-" {
+		return nil
+	}
+
+	for i := 0; i < len(field); i++ {
+		c := Color(ColorWhite)
+		letter := field[i]
+		if letter >= 'a' && letter <= 'z' {
+			c = ColorBlack
+			letter -= 'a' - 'A'
+		}
+
+		y := backRankOf(c)
+		king, hasKing := g.Board.findKing(c)
+		if hasKing && king.Y != y {
+			hasKing = false
+		}
+
+		// the index of the kingside right of the color, the queenside one being next
+		right := 2 * colorIndex(c)
+		var x int8
+		switch {
+		case letter == 'K':
+			x = g.outermostRook(c, 7, -1)
+		case letter == 'Q':
+			x = g.outermostRook(c, 0, 1)
+			right++
+		case letter >= 'A' && letter <= 'H' && hasKing:
+			x = int8(letter - 'A')
+			if x < king.X {
+				right++
+			}
+		default:
+			return fmt.Errorf("invalid FEN: invalid castling rights '%s'", field)
+		}
+
+		if g.Castling.Has(1 << right) {
+			return fmt.Errorf("invalid FEN: invalid castling rights '%s'", field)
+		}
+
+		g.Castling |= 1 << right
+		g.CastlingFiles[right] = x
+	}
+
+	for i, x := range g.CastlingFiles {
+		king, _ := g.Board.findKing(castlingColor(i))
+		if g.Castling.Has(1<<i) && (x != standardCastlingFiles[i] || king.X != 4) {
+			g.Chess960 = true
+		}
+	}
+
+	return nil
+}
+
+// Returns the file of the rook of the given color furthest from its king on one side, searching the back rank
+// from the given corner file in the given direction until the king. Returns the corner file if there's no rook.
+func (g *Game) outermostRook(c Color, from, dir int8) int8 {
+	y := backRankOf(c)
+	rook := MakePiece(PieceTypeRook, c)
+	for x := from; x >= 0 && x <= 7; x += dir {
+		switch g.Board.At(NewPos(x, y)) {
+		case rook:
+			return x
+		case MakePiece(PieceTypeKing, c):
+			return from
+		}
+	}
+
+	return from
+}
+
+// Returns the piece placement of the board in Forsyth-Edwards Notation.
+// This is only the first field of a FEN record; use Game.FEN for the full record.
+func (b *Board) FEN() string {
+	var sb strings.Builder
+	for y := int8(7); y >= 0; y-- {
+		empty := 0
+		for x := int8(0); x < 8; x++ {
+			p := b.Grid[x][y]
+			if p.Color() == ColorNone {
+				empty++
+				continue
+			}
+
+			if empty > 0 {
+				sb.WriteString(strconv.Itoa(empty))
+				empty = 0
+			}
+
+			sb.WriteByte(fenPieces[p])
+			if b.Promoted.Has(NewPos(x, y)) {
+				sb.WriteByte('~')
+			}
+		}
+
+		if empty > 0 {
+			sb.WriteString(strconv.Itoa(empty))
+		}
+
+		if y > 0 {
+			sb.WriteByte('/')
+		}
+	}
+
+	return sb.String()
+}
+
+// Returns the game's current position in Forsyth-Edwards Notation, with the castling rights of Chess960 games
+// written like in X-FEN.
+func (g *Game) FEN() string {
+	return g.fen(false)
+}
+
+// Returns the game's current position in Shredder-FEN: Forsyth-Edwards Notation with the castling rights
+// written as the files of the rooks, such as HAha for the starting position.
+func (g *Game) ShredderFEN() string {
+	return g.fen(true)
+}
+
+func (g *Game) fen(shredder bool) string {
+	turn := "w"
+	if g.Turn == ColorBlack {
+		turn = "b"
+	}
+
+	ep := "-"
+	if g.EnPassant != ErrorPos {
+		ep = g.EnPassant.Name()
+	}
+
+	if field := g.variant().FENField(g); field != "" {
+		ep += " " + field
+	}
+
+	placement := g.Board.FEN()
+	if g.variant().HasPockets() {
+		placement += "[" + g.Board.pocketsFEN() + "]"
+	}
+
+	return fmt.Sprintf("%s %s %s %s %d %d", placement, turn, g.fenCastling(shredder), ep, g.HalfmoveClock, g.FullmoveNumber)
+}
+
+// Returns the castling rights in the format of a FEN record. The rights are written like in X-FEN:
+// with K or Q if the rook is the outermost one on its side of the king, and with its file otherwise.
+// If shredder is set, they are written like in Shredder-FEN, always with the file of the rook.
+func (g *Game) fenCastling(shredder bool) string {
+	castling := ""
+	for i, l := range castlingLetters {
+		if !g.Castling.Has(l.rights) {
+			continue
+		}
+
+		letter := l.letter
+		c := castlingColor(i)
+		outermost := g.outermostRook(c, standardCastlingFiles[i], 2*int8(i%2)-1)
+		if shredder || outermost != g.CastlingFiles[i] {
+			letter = byte('A' + g.CastlingFiles[i])
+			if c == ColorBlack {
+				letter = byte('a' + g.CastlingFiles[i])
+			}
+		}
+
+		castling += string(letter)
+	}
+
+	if castling == "" {
+		return "-"
+	}
+
+	return castling
+}
@@ -0,0 +1,94 @@
+package chomp
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFENRoundTrip(t *testing.T) {
+	fens := []string{
+		StartFEN,
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		"rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq e6 0 2",
+		"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1",
+		"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 12 40",
+		"4k3/8/8/8/8/8/8/4K2R w K - 99 120",
+	}
+
+	for _, fen := range fens {
+		g, err := ParseFEN(fen)
+		if err != nil {
+			t.Errorf("%s: %s", fen, err.Error())
+			continue
+		}
+
+		if got := g.FEN(); got != fen {
+			t.Errorf("%s: written back as %s", fen, got)
+		}
+	}
+}
+
+func TestFENDefaultClocks(t *testing.T) {
+	g, err := ParseFEN("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq -")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if g.FEN() != StartFEN {
+		t.Errorf("got %s, want %s", g.FEN(), StartFEN)
+	}
+
+	g, err = ParseFEN("k7/8/1K6/8/8/8/8/7Q b - - 3 70")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if g.Turn != ColorBlack || g.HalfmoveClock != 3 || g.FullmoveNumber != 70 {
+		t.Errorf("got %s to move with the clocks %d and %d", g.Turn, g.HalfmoveClock, g.FullmoveNumber)
+	}
+}
+
+func TestFENStatus(t *testing.T) {
+	g, err := ParseFEN("k7/1Q6/1K6/8/8/8/8/8 b - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if g.Board.Checkmate != ColorBlack || g.Outcome() != OutcomeWhiteWins {
+		t.Errorf("a checkmate read from FEN has the outcome %s", g.Outcome())
+	}
+}
+
+func TestParseFENInvalid(t *testing.T) {
+	tests := []struct {
+		fen string
+		err string
+	}{
+		{"", "expected 4 or 6 fields, got 0"},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0", "expected 4 or 6 fields, got 5"},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP w KQkq - 0 1", "expected 8 ranks, got 7"},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR/8 w KQkq - 0 1", "expected 8 ranks, got 9"},
+		{"rnbqkbnr/ppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "rank 7 has 7 squares instead of 8"},
+		{"rnbqkbnr/pppppppp/9/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "unknown piece '9' on rank 6"},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNRR w KQkq - 0 1", "rank 1 has more than 8 squares"},
+		{"rnbqkbnr/pppppppp/8/8/8/4x3/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "unknown piece 'x' on rank 3"},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR x KQkq - 0 1", "side to move must be 'w' or 'b'"},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkx - 0 1", "invalid castling rights 'KQkx'"},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KKq - 0 1", "invalid castling rights 'KKq'"},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq e9 0 1", "en passant: invalid square 'e9'"},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq e3 0 1", "en passant square e3 is not on the right rank"},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - x 1", "halfmove clock must be a non-negative number, got 'x'"},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - -1 1", "halfmove clock must be a non-negative number"},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 one", "fullmove number must be a positive number, got 'one'"},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 0", "fullmove number must be a positive number"},
+	}
+
+	for _, test := range tests {
+		_, err := ParseFEN(test.fen)
+		if err == nil {
+			t.Errorf("%q: no error, want %q", test.fen, test.err)
+		} else if !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%q: got the error %q, want %q", test.fen, err.Error(), test.err)
+		}
+	}
+}
@@ -0,0 +1,458 @@
+package chomp
+
+import "fmt"
+
+// Represents the castling rights of both players as a set of flags.
+type CastlingRights uint8
+
+const (
+	CastlingNone           = 0
+	CastlingWhiteKingside  = 1
+	CastlingWhiteQueenside = 2
+	CastlingBlackKingside  = 4
+	CastlingBlackQueenside = 8
+	CastlingAll            = 15
+)
+
+// Returns whether all of the given rights are set.
+func (r CastlingRights) Has(rights CastlingRights) bool {
+	return r&rights == rights
+}
+
+// The files of the rooks of a standard game, in the order of the castling flags.
+var standardCastlingFiles = [4]int8{7, 0, 7, 0}
+
+// Returns the castling rights of both sides of the given color.
+func castlingRightsOf(c Color) CastlingRights {
+	return (CastlingWhiteKingside | CastlingWhiteQueenside) << (2 * colorIndex(c))
+}
+
+var (
+	errNothingToUndo = fmt.Errorf("no moves to undo")
+	errGameOver      = fmt.Errorf("the game is over")
+)
+
+// The state of a game that is saved before every move so it can be undone.
+type gameState struct {
+	board          Board
+	turn           Color
+	castling       CastlingRights
+	enPassant      Position
+	halfmoveClock  int
+	fullmoveNumber int
+	drawReason     DrawReason
+	checks         [2]int
+	hash           uint64
+	key            uint64
+	move           Move
+}
+
+// Represents a game of chess: the board along with everything needed to know which moves are legal.
+type Game struct {
+	// The pieces on the board
+	Board Board `json:"board"`
+	// The color whose turn it is
+	Turn Color `json:"turn"`
+	// The castling rights both players have left
+	Castling CastlingRights `json:"castling"`
+	// The position a pawn can be captured on en passant, or ErrorPos if there is none.
+	// This is the position the pawn skipped over, not the one it is on.
+	EnPassant Position `json:"enPassant"`
+	// The number of halfmoves since the last capture or pawn move
+	HalfmoveClock int `json:"halfmoveClock"`
+	// The number of the current full move. Starts at 1 and is incremented after black moves.
+	FullmoveNumber int `json:"fullmoveNumber"`
+	// Why the game ended in a draw, or DrawReasonNone if it didn't
+	DrawReason DrawReason `json:"drawReason"`
+	// Whether the game is Chess960, where the pieces of the back rank start on random squares.
+	// Castling moves of Chess960 games are made by the king moving onto its own rook, such as e1h1.
+	Chess960 bool `json:"chess960"`
+	// The files of the rooks the castling rights are for, in the order of the castling flags:
+	// white kingside, white queenside, black kingside and black queenside.
+	// They are the h and a files unless the game is Chess960.
+	CastlingFiles [4]int8 `json:"castlingFiles"`
+	// The variant of chess the game is played in, or nil for standard chess
+	Variant Variant `json:"-"`
+	// The number of times each color has given check, indexed by the color index. Only counted in Three-check.
+	Checks [2]int `json:"checks"`
+
+	hash    uint64
+	history []gameState
+}
+
+// Creates a new game from the starting position.
+func NewGame() *Game {
+	return NewGameFromBoard(NewBoard(), ColorWhite)
+}
+
+// Creates a new game from the given board with the given color to move.
+// Castling rights are given for every king and rook that is still on its starting position.
+func NewGameFromBoard(b Board, turn Color) *Game {
+	g := &Game{
+		Board:          b,
+		Turn:           turn,
+		Castling:       CastlingNone,
+		EnPassant:      ErrorPos,
+		FullmoveNumber: 1,
+		CastlingFiles:  standardCastlingFiles,
+	}
+
+	for i, x := range g.CastlingFiles {
+		c := castlingColor(i)
+		y := backRankOf(c)
+		if b.At(NewPos(4, y)) == MakePiece(PieceTypeKing, c) && b.At(NewPos(x, y)) == MakePiece(PieceTypeRook, c) {
+			g.Castling |= 1 << i
+		}
+	}
+
+	g.hash = g.computeHash()
+	g.updateStatus()
+	return g
+}
+
+// Returns all legal moves for the color whose turn it is.
+func (g *Game) LegalMoves() []Move {
+	return g.variant().LegalMoves(g)
+}
+
+// Returns all legal moves for the piece at the given position, including castling and en passant.
+// If the piece is not of the color whose turn it is, no moves are returned.
+func (g *Game) LegalMovesFrom(p Position) []Move {
+	if g.Board.At(p).Color() != g.Turn {
+		return []Move{}
+	}
+
+	if _, ok := g.variant().(Standard); ok {
+		return g.Board.legalMovesFrom(p, g.castlingState(), g.EnPassant)
+	}
+
+	moves := []Move{}
+	for _, m := range g.LegalMoves() {
+		if m.From == p {
+			moves = append(moves, m)
+		}
+	}
+
+	return moves
+}
+
+// Returns what the move generator needs to know to castle in the game.
+func (g *Game) castlingState() castlingState {
+	return castlingState{rights: g.Castling, files: g.CastlingFiles, chess960: g.Chess960}
+}
+
+// Returns whether the given move is legal, and the legal move it matches.
+// A promotion must name the piece promoted to, a drop the piece dropped, and any other move must name none.
+// Castling may be given either as the king moving onto its rook or as the king moving to where it ends up,
+// whether the game is Chess960 or not, as long as it isn't also a normal king move.
+func (g *Game) findLegalMove(m Move) (Move, bool) {
+	if m.IsDrop() {
+		for _, legal := range g.LegalMoves() {
+			if legal == m {
+				return legal, true
+			}
+		}
+
+		return Move{}, false
+	}
+
+	if m.Promotion == PieceTypePawn {
+		// a move written without a promotion has the zero piece type, which nothing is promoted to
+		m.Promotion = PieceTypeNone
+	}
+
+	legalMoves := g.LegalMovesFrom(m.From)
+	for _, legal := range legalMoves {
+		if legal.To != m.To {
+			continue
+		}
+
+		if legal.Promotion == m.Promotion {
+			return legal, true
+		}
+	}
+
+	for _, legal := range legalMoves {
+		if !g.isCastling(legal) {
+			continue
+		}
+
+		rook := g.castlingRook(legal)
+		king, _ := castlingTargets(legal.From, rook)
+		if m.To == rook || m.To == king {
+			return legal, true
+		}
+	}
+
+	return Move{}, false
+}
+
+// Returns the position of the rook a castling move castles with. The move must be legal in the game.
+func (g *Game) castlingRook(m Move) Position {
+	switch {
+	case g.Board.At(m.To).Type() == PieceTypeRook:
+		return m.To
+	case m.To.X > m.From.X:
+		return NewPos(7, m.From.Y)
+	}
+
+	return NewPos(0, m.From.Y)
+}
+
+// Returns whether the given move is legal in this game.
+func (g *Game) IsLegal(m Move) bool {
+	_, ok := g.findLegalMove(m)
+	return ok
+}
+
+// Applies a move to the game. Returns an error if the move is not legal.
+// The check, checkmate and stalemate status of the board is updated after the move,
+// and so is the draw reason if the game is drawn automatically.
+func (g *Game) Apply(m Move) error {
+	if g.IsOver() {
+		return errGameOver
+	}
+
+	return g.ApplyPastEnd(m)
+}
+
+// Applies a move to the game like Apply, but also once the game is over, as long as the move is legal.
+// This is for replaying games that were played on after the rules ended them, such as records that go on
+// after a draw by insufficient material, or positions given with a halfmove clock past the seventy-five-move rule.
+func (g *Game) ApplyPastEnd(m Move) error {
+	legal, ok := g.findLegalMove(m)
+	if !ok {
+		return fmt.Errorf("illegal move: %s", m)
+	}
+
+	g.push(legal, g.repetitionKey())
+	g.updateStatus()
+	return nil
+}
+
+// Makes a move returned by LegalMoves without checking it or updating the status of the board.
+// This is much faster than Apply, so it is meant for searching through moves; Undo takes the move back.
+// Repetitions are still tracked, but check, checkmate and draws have to be looked for separately.
+func (g *Game) MakeMove(m Move) {
+	g.push(m, g.repetitionKey())
+}
+
+// Makes a move that must be legal, saving the state before it so it can be undone.
+// Unlike Apply, the status of the board is not updated.
+// The key identifies the position before the move for repetition detection.
+func (g *Game) push(legal Move, key uint64) {
+	g.history = append(g.history, gameState{
+		board:          g.Board,
+		turn:           g.Turn,
+		castling:       g.Castling,
+		enPassant:      g.EnPassant,
+		halfmoveClock:  g.HalfmoveClock,
+		fullmoveNumber: g.FullmoveNumber,
+		drawReason:     g.DrawReason,
+		checks:         g.Checks,
+		hash:           g.hash,
+		key:            key,
+		move:           legal,
+	})
+
+	piece := g.Board.At(legal.From)
+	if legal.IsDrop() {
+		piece = MakePiece(legal.Promotion, g.Turn)
+	}
+
+	capture := g.IsCapture(legal)
+
+	// the parts of the key that change with the move are taken out and put back in afterwards
+	g.hash ^= castlingKey(g.Castling) ^ g.enPassantKey() ^ turnKey(g.Turn)
+	if legal.IsDrop() {
+		g.hash ^= g.Board.drop(piece, legal.To)
+	} else {
+		g.hash ^= g.Board.applyMove(legal)
+	}
+
+	g.Castling &^= g.castlingRightsLost(piece, legal)
+
+	g.EnPassant = ErrorPos
+	if piece.Type() == PieceTypePawn && !legal.IsDrop() && abs8(legal.To.Y-legal.From.Y) == 2 {
+		g.EnPassant = NewPos(legal.From.X, (legal.From.Y+legal.To.Y)/2)
+	}
+
+	if piece.Type() == PieceTypePawn || capture {
+		g.HalfmoveClock = 0
+	} else {
+		g.HalfmoveClock++
+	}
+
+	if g.Turn == ColorBlack {
+		g.FullmoveNumber++
+	}
+
+	g.Turn = g.Turn.Opposite()
+	g.hash ^= castlingKey(g.Castling) ^ g.enPassantKey() ^ turnKey(g.Turn)
+
+	if _, ok := g.variant().(Standard); !ok {
+		g.variant().AfterMove(g, legal, capture)
+		g.dropLostCastlingRights()
+		g.hash = g.computeHash()
+	}
+}
+
+// Takes away the castling rights whose king or rook is no longer on its square, which can happen
+// when a variant changes the board after a move.
+func (g *Game) dropLostCastlingRights() {
+	for i, x := range g.CastlingFiles {
+		c := castlingColor(i)
+		king, ok := g.Board.findKing(c)
+		if !ok || king.Y != backRankOf(c) || g.Board.At(NewPos(x, backRankOf(c))) != MakePiece(PieceTypeRook, c) {
+			g.Castling &^= 1 << i
+		}
+	}
+}
+
+// Returns the castling rights lost by making the move: both rights of a king that moves,
+// and the right of a rook that moves or is captured.
+func (g *Game) castlingRightsLost(piece Piece, m Move) CastlingRights {
+	lost := CastlingRights(CastlingNone)
+	if piece.Type() == PieceTypeKing {
+		lost |= castlingRightsOf(piece.Color())
+	}
+
+	for i, x := range g.CastlingFiles {
+		rook := NewPos(x, backRankOf(castlingColor(i)))
+		if m.From == rook || m.To == rook {
+			lost |= 1 << i
+		}
+	}
+
+	return lost
+}
+
+// Returns the color of the castling right with the given index in the order of the castling flags.
+func castlingColor(i int) Color {
+	if i >= 2 {
+		return ColorBlack
+	}
+
+	return ColorWhite
+}
+
+// Undoes the last move applied to the game.
+func (g *Game) Undo() error {
+	if len(g.history) == 0 {
+		return errNothingToUndo
+	}
+
+	last := g.history[len(g.history)-1]
+	g.history = g.history[:len(g.history)-1]
+
+	g.Board = last.board
+	g.Turn = last.turn
+	g.Castling = last.castling
+	g.EnPassant = last.enPassant
+	g.HalfmoveClock = last.halfmoveClock
+	g.FullmoveNumber = last.fullmoveNumber
+	g.DrawReason = last.drawReason
+	g.Checks = last.checks
+	g.hash = last.hash
+	return nil
+}
+
+// Returns a copy of the game that can be changed without affecting this one.
+func (g *Game) Clone() *Game {
+	c := *g
+	c.history = append([]gameState{}, g.history...)
+	return &c
+}
+
+// Returns the moves applied to the game so far, in order.
+func (g *Game) Moves() []Move {
+	moves := make([]Move, len(g.history))
+	for i, s := range g.history {
+		moves[i] = s.move
+	}
+
+	return moves
+}
+
+// Returns the boards of the positions of the game so far, in order: the one it started from,
+// the one after each move and the current one, which is the last.
+func (g *Game) Boards() []Board {
+	boards := make([]Board, len(g.history)+1)
+	for i, s := range g.history {
+		boards[i] = s.board
+	}
+
+	boards[len(g.history)] = g.Board
+	return boards
+}
+
+// Returns the Y coordinate of the first rank of the given color.
+func backRankOf(c Color) int8 {
+	if c == ColorBlack {
+		return 7
+	}
+
+	return 0
+}
+
+// Moves the piece on the board without any checks.
+// Promotions, en passant captures and the rook's move when castling are handled too. Castling can be written
+// either as the king moving two squares or as the king moving onto its own rook, like in Chess960.
+// Returns the change to the Zobrist key caused by the pieces that moved.
+func (b *Board) applyMove(m Move) uint64 {
+	var key uint64
+	piece := b.Grid[m.From.X][m.From.Y]
+	switch piece.Type() {
+	case PieceTypePawn:
+		if m.Promotion != PieceTypeNone {
+			piece = MakePiece(m.Promotion, piece.Color())
+		}
+
+		// a pawn moving diagonally to an empty square is capturing en passant
+		if m.From.X != m.To.X && b.Grid[m.To.X][m.To.Y] == PieceNone {
+			key ^= b.put(NewPos(m.To.X, m.From.Y), PieceNone)
+		}
+	case PieceTypeKing:
+		rookFrom := ErrorPos
+		if b.Grid[m.To.X][m.To.Y] == MakePiece(PieceTypeRook, piece.Color()) {
+			rookFrom = m.To
+		} else if m.To.X-m.From.X == 2 {
+			rookFrom = NewPos(7, m.From.Y)
+		} else if m.From.X-m.To.X == 2 {
+			rookFrom = NewPos(0, m.From.Y)
+		}
+
+		if rookFrom != ErrorPos {
+			// both pieces are taken off first, since in Chess960 they can land on each other's squares
+			kingTo, rookTo := castlingTargets(m.From, rookFrom)
+			rook := b.At(rookFrom)
+			key ^= b.put(m.From, PieceNone)
+			key ^= b.put(rookFrom, PieceNone)
+			key ^= b.put(kingTo, piece)
+			key ^= b.put(rookTo, rook)
+			return key
+		}
+	}
+
+	key ^= b.put(m.To, piece)
+	key ^= b.put(m.From, PieceNone)
+	return key
+}
+
+// Returns the positions the king and the rook end up on when the king on the given position castles
+// with the rook on the other one: the g and f files when castling kingside, and the c and d files when castling queenside.
+func castlingTargets(king, rook Position) (Position, Position) {
+	if rook.X > king.X {
+		return NewPos(6, king.Y), NewPos(5, king.Y)
+	}
+
+	return NewPos(2, king.Y), NewPos(3, king.Y)
+}
+
+// Puts a piece on the given position, replacing whatever was there.
+// Returns the change to the Zobrist key.
+func (b *Board) put(p Position, piece Piece) uint64 {
+	key := pieceKey(b.Grid[p.X][p.Y], p) ^ pieceKey(piece, p)
+	b.Grid[p.X][p.Y] = piece
+	return key
+}
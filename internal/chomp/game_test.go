@@ -0,0 +1,75 @@
+package chomp
+
+import "testing"
+
+func TestPromotionMustMatchMove(t *testing.T) {
+	g := NewGame()
+	e2, e4 := NewPos(4, 1), NewPos(4, 3)
+	if g.IsLegal(Move{From: e2, To: e4, Promotion: PieceTypeKnight}) {
+		t.Errorf("a queen promotion on e2e4 was accepted")
+	}
+
+	if !g.IsLegal(Move{From: e2, To: e4}) {
+		t.Errorf("e2e4 with the zero promotion was rejected")
+	}
+
+	if !g.IsLegal(NewMove(e2, e4)) {
+		t.Errorf("e2e4 was rejected")
+	}
+
+	b := EmptyBoard()
+	b.Grid[4][6] = MakePiece(PieceTypePawn, ColorWhite)
+	b.Grid[4][0] = MakePiece(PieceTypeKing, ColorWhite)
+	b.Grid[0][1] = MakePiece(PieceTypeKing, ColorBlack)
+	g = NewGameFromBoard(b, ColorWhite)
+
+	e7, e8 := NewPos(4, 6), NewPos(4, 7)
+	if g.IsLegal(NewMove(e7, e8)) || g.IsLegal(Move{From: e7, To: e8}) {
+		t.Errorf("a promotion without a piece was accepted")
+	}
+
+	if !g.IsLegal(Move{From: e7, To: e8, Promotion: PieceTypeKnight}) {
+		t.Errorf("e7e8n was rejected")
+	}
+
+	if err := g.Apply(Move{From: e7, To: e8, Promotion: PieceTypeKnight}); err != nil {
+		t.Fatal(err)
+	}
+
+	if g.Board.At(e8) != MakePiece(PieceTypeKnight, ColorWhite) {
+		t.Errorf("the pawn was promoted to %s, want a knight", g.Board.At(e8))
+	}
+
+	if err := g.Undo(); err != nil || g.Board.At(e7) != MakePiece(PieceTypePawn, ColorWhite) {
+		t.Errorf("undoing the promotion left %s on e7 with the error %v", g.Board.At(e7), err)
+	}
+}
+
+func TestTimeoutOutcome(t *testing.T) {
+	tests := []struct {
+		variant Variant
+		fen     string
+		flagged Color
+		outcome Outcome
+	}{
+		{nil, StartFEN, ColorWhite, OutcomeBlackWins},
+		{nil, StartFEN, ColorBlack, OutcomeWhiteWins},
+		// the player left with a bare king can't win on time
+		{nil, "4k3/8/8/8/8/8/8/R3K3 w - - 0 1", ColorWhite, OutcomeDraw},
+		{nil, "4k3/8/8/8/8/8/8/R3K3 w - - 0 1", ColorBlack, OutcomeWhiteWins},
+		{Standard{}, "4k3/8/8/8/8/8/8/R3K3 w - - 0 1", ColorWhite, OutcomeDraw},
+		{KingOfTheHill{}, "4k3/8/8/8/8/8/8/R3K3 w - - 0 1", ColorWhite, OutcomeBlackWins},
+	}
+
+	for _, test := range tests {
+		g, err := ParseFEN(test.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		g.Variant = test.variant
+		if got := g.TimeoutOutcome(test.flagged); got != test.outcome {
+			t.Errorf("%v %s: %s flagged: got %s, want %s", test.variant, test.fen, test.flagged, got, test.outcome)
+		}
+	}
+}
@@ -0,0 +1,283 @@
+package chomp
+
+// Represents a move of a piece from one position to another, or a drop of a piece from a pocket onto the board.
+type Move struct {
+	// The position the piece moves from, or ErrorPos if the move is a drop
+	From Position `json:"from"`
+	// The position the piece moves to
+	To Position `json:"to"`
+	// The type of piece a pawn is promoted to, or the type of piece dropped if the move is a drop.
+	// Ignored if the move is neither.
+	Promotion PieceType `json:"promotion"`
+}
+
+// Creates a new move that is not a promotion.
+func NewMove(from, to Position) Move {
+	return Move{From: from, To: to, Promotion: PieceTypeNone}
+}
+
+// Creates a move dropping a piece of the given type from the pocket of the player to move onto the given position.
+func NewDrop(t PieceType, to Position) Move {
+	return Move{From: ErrorPos, To: to, Promotion: t}
+}
+
+// Returns whether the move drops a piece from a pocket instead of moving one on the board.
+func (m Move) IsDrop() bool {
+	return m.From == ErrorPos
+}
+
+// Returns the move in UCI notation, such as e2e4 or e7e8q.
+func (m Move) String() string {
+	return m.UCI()
+}
+
+var (
+	rookDirections   = [][2]int8{{1, 0}, {-1, 0}, {0, 1}, {0, -1}}
+	bishopDirections = [][2]int8{{1, 1}, {1, -1}, {-1, 1}, {-1, -1}}
+	queenDirections  = append(append([][2]int8{}, rookDirections...), bishopDirections...)
+	knightOffsets    = [][2]int8{{1, 2}, {2, 1}, {2, -1}, {1, -2}, {-1, -2}, {-2, -1}, {-2, 1}, {-1, 2}}
+)
+
+// Returns the position moved by dx and dy. The result may be out of bounds.
+func (p Position) offset(dx, dy int8) Position {
+	return Position{X: p.X + dx, Y: p.Y + dy}
+}
+
+// Returns the direction white or black pawns move in on the Y axis.
+func pawnDirection(c Color) int8 {
+	if c == ColorBlack {
+		return -1
+	}
+
+	return 1
+}
+
+// The castling rights of a game along with what the move generator needs to know to castle.
+type castlingState struct {
+	rights CastlingRights
+	// the files of the rooks, in the order of the castling flags
+	files [4]int8
+	// whether castling moves are made by the king moving onto the rook
+	chess960 bool
+}
+
+// Returns all legal moves for the given color.
+// Castling and en passant are not included, because the board alone does not know whether they are allowed.
+// Use Game.LegalMoves for those.
+func (b *Board) LegalMoves(c Color) []Move {
+	return b.legalMoves(c, castlingState{}, ErrorPos)
+}
+
+// Returns all legal moves for the piece at the given position.
+// If there is no piece there, no moves are returned. Like LegalMoves, this does not include castling or en passant.
+func (b *Board) LegalMovesFrom(p Position) []Move {
+	return b.legalMovesFrom(p, castlingState{}, ErrorPos)
+}
+
+// Returns all legal moves for the given color, with the given castling rights and en passant position.
+func (b *Board) legalMoves(c Color, castling castlingState, ep Position) []Move {
+	bb := b.Bitboards()
+	return b.generate(&bb, c, castling, ep, bb.Colors[colorIndex(c)])
+}
+
+func (b *Board) legalMovesFrom(p Position, castling castlingState, ep Position) []Move {
+	piece := b.At(p)
+	if piece.Color() == ColorNone {
+		return []Move{}
+	}
+
+	bb := b.Bitboards()
+	return b.generate(&bb, piece.Color(), castling, ep, BitboardOf(p))
+}
+
+// Generates the legal moves of the pieces of color c standing on the squares in from.
+// Every move is made on a copy of the bitboards to see whether it leaves the king in check.
+func (b *Board) generate(bb *Bitboards, c Color, castling castlingState, ep Position, from Bitboard) []Move {
+	us := bb.Colors[colorIndex(c)]
+	moves := make([]Move, 0, 48)
+	for pieces := from & us; pieces != 0; {
+		sq := pieces.popLowest()
+		p := positionOf(sq)
+		piece := b.Grid[p.X][p.Y]
+		if piece.Type() == PieceTypePawn {
+			moves = b.pawnMoves(bb, moves, p, c, ep)
+			continue
+		}
+
+		for targets := attacksOf(piece.Type(), sq, bb.Occupied) &^ us; targets != 0; {
+			moves = b.appendIfLegal(bb, moves, NewMove(p, positionOf(targets.popLowest())))
+		}
+
+		if piece.Type() == PieceTypeKing {
+			moves = b.castlingMoves(bb, moves, p, c, castling)
+		}
+	}
+
+	return moves
+}
+
+// Appends the move if it doesn't leave the king of the moving side in check.
+func (b *Board) appendIfLegal(bb *Bitboards, moves []Move, m Move) []Move {
+	if b.leavesKingInCheck(bb, m) {
+		return moves
+	}
+
+	return append(moves, m)
+}
+
+func (b *Board) pawnMoves(bb *Bitboards, moves []Move, p Position, c Color, ep Position) []Move {
+	dir := pawnDirection(c)
+	one := p.offset(0, dir)
+	if one.Locate() != LocationOutOfBounds && !bb.Occupied.Has(one) {
+		moves = b.appendPawnMove(bb, moves, p, one)
+
+		// pawns on their starting rank may move two squares
+		two := p.offset(0, 2*dir)
+		if p.Y == backRankOf(c)+dir && !bb.Occupied.Has(two) {
+			moves = b.appendIfLegal(bb, moves, NewMove(p, two))
+		}
+	}
+
+	attacks := pawnAttacks[colorIndex(c)][squareOf(p)]
+	for captures := attacks & bb.Colors[colorIndex(c.Opposite())]; captures != 0; {
+		moves = b.appendPawnMove(bb, moves, p, positionOf(captures.popLowest()))
+	}
+
+	if attacks.Has(ep) && b.At(NewPos(ep.X, p.Y)) == MakePiece(PieceTypePawn, c.Opposite()) {
+		moves = b.appendIfLegal(bb, moves, NewMove(p, ep))
+	}
+
+	return moves
+}
+
+// The pieces a pawn can promote to
+var promotionTypes = []PieceType{PieceTypeQueen, PieceTypeRook, PieceTypeBishop, PieceTypeKnight}
+
+// Appends a pawn move, which is a promotion to every possible piece if the pawn reaches the last rank.
+func (b *Board) appendPawnMove(bb *Bitboards, moves []Move, from, to Position) []Move {
+	if b.leavesKingInCheck(bb, NewMove(from, to)) {
+		return moves
+	}
+
+	if to.Y == 0 || to.Y == 7 {
+		for _, t := range promotionTypes {
+			moves = append(moves, Move{From: from, To: to, Promotion: t})
+		}
+
+		return moves
+	}
+
+	return append(moves, NewMove(from, to))
+}
+
+// Appends the castling moves the king at p can make. The king ends up on the g or c file with the rook next to it
+// on the inside, and the move is written as the king moving there, or onto the rook in Chess960.
+// The king may not castle out of, through or into check.
+func (b *Board) castlingMoves(bb *Bitboards, moves []Move, p Position, c Color, castling castlingState) []Move {
+	y := backRankOf(c)
+	if p.Y != y || (!castling.chess960 && p.X != 4) || bb.isAttacked(squareOf(p), c.Opposite()) {
+		return moves
+	}
+
+	king := MakePiece(PieceTypeKing, c)
+	rook := MakePiece(PieceTypeRook, c)
+	for i := 2 * colorIndex(c); i < 2*colorIndex(c)+2; i++ {
+		rookFrom := NewPos(castling.files[i], y)
+		kingside := i%2 == 0
+		if !castling.rights.Has(1<<i) || !bb.Pieces[rook].Has(rookFrom) || (rookFrom.X > p.X) != kingside {
+			continue
+		}
+
+		// the squares both pieces cross and land on must be empty, apart from the two of them
+		kingTo, rookTo := castlingTargets(p, rookFrom)
+		others := bb.Occupied &^ (BitboardOf(p) | BitboardOf(rookFrom))
+		if others&(rankSpan(p, kingTo)|rankSpan(rookFrom, rookTo)) != 0 {
+			continue
+		}
+
+		safe := true
+		for x := p.X; x != kingTo.X && safe; {
+			if x < kingTo.X {
+				x++
+			} else {
+				x--
+			}
+
+			safe = !bb.isAttacked(squareOf(NewPos(x, y)), c.Opposite())
+		}
+
+		// the rook may have been shielding the square the king lands on
+		after := *bb
+		after.remove(king, squareOf(p))
+		after.remove(rook, squareOf(rookFrom))
+		after.add(king, squareOf(kingTo))
+		after.add(rook, squareOf(rookTo))
+		if !safe || after.isAttacked(squareOf(kingTo), c.Opposite()) {
+			continue
+		}
+
+		if castling.chess960 {
+			moves = append(moves, NewMove(p, rookFrom))
+		} else {
+			moves = append(moves, NewMove(p, kingTo))
+		}
+	}
+
+	return moves
+}
+
+// Returns the positions on the rank from a to b, both included. The positions must be on the same rank.
+func rankSpan(a, b Position) Bitboard {
+	if a.X > b.X {
+		a, b = b, a
+	}
+
+	var span Bitboard
+	for x := a.X; x <= b.X; x++ {
+		span |= BitboardOf(NewPos(x, a.Y))
+	}
+
+	return span
+}
+
+// Returns whether making the move would leave the king of the moving side in check.
+// The bitboards must match the board.
+func (b *Board) leavesKingInCheck(bb *Bitboards, m Move) bool {
+	piece := b.At(m.From)
+	c := piece.Color()
+	after := *bb
+	captured := b.At(m.To)
+	if piece.Type() == PieceTypePawn && m.From.X != m.To.X && captured == PieceNone {
+		// en passant
+		after.remove(MakePiece(PieceTypePawn, c.Opposite()), squareOf(NewPos(m.To.X, m.From.Y)))
+	}
+
+	after.move(piece, squareOf(m.From), squareOf(m.To), captured)
+	king := after.piece(PieceTypeKing, c)
+	if king == 0 {
+		// positions without a king can't be in check
+		return false
+	}
+
+	return after.isAttacked(king.lowest(), c.Opposite())
+}
+
+// Returns the position of the king of the given color.
+func (b *Board) findKing(c Color) (Position, bool) {
+	king := MakePiece(PieceTypeKing, c)
+	for x := int8(0); x < 8; x++ {
+		for y := int8(0); y < 8; y++ {
+			if b.Grid[x][y] == king {
+				return NewPos(x, y), true
+			}
+		}
+	}
+
+	return ErrorPos, false
+}
+
+// Returns whether the position is attacked by any piece of the given color.
+func (b *Board) IsAttacked(p Position, by Color) bool {
+	bb := b.Bitboards()
+	return bb.isAttacked(squareOf(p), by)
+}
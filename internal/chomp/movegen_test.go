@@ -0,0 +1,34 @@
+package chomp
+
+import "testing"
+
+func TestBitboardsRoundTrip(t *testing.T) {
+	for _, pp := range PerftPositions {
+		g := mustParseFEN(t, pp.FEN)
+		bb := g.Board.Bitboards()
+		b := EmptyBoard()
+		b.Grid = bb.Grid()
+		if b.Grid != g.Board.Grid {
+			t.Errorf("%s: the grid changed going through bitboards", pp.Name)
+		}
+
+		if b.Bitboards() != bb {
+			t.Errorf("%s: the bitboards changed going through the grid", pp.Name)
+		}
+	}
+}
+
+func BenchmarkLegalMoves(b *testing.B) {
+	for _, pp := range PerftPositions {
+		g, err := ParseFEN(pp.FEN)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		b.Run(pp.Name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				g.Board.LegalMoves(g.Turn)
+			}
+		})
+	}
+}
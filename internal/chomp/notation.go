@@ -0,0 +1,273 @@
+package chomp
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The letters used for promotions in UCI notation. Kings are promoted to in Antichess.
+var uciPromotions = map[PieceType]byte{
+	PieceTypeRook:   'r',
+	PieceTypeKnight: 'n',
+	PieceTypeBishop: 'b',
+	PieceTypeQueen:  'q',
+	PieceTypeKing:   'k',
+}
+
+// The figurines of the pieces, indexed by the piece constants.
+var figurines = []rune("♙♖♘♗♕♔♟♜♞♝♛♚")
+
+// Returns the letter a drop of the given piece type is written with, such as N in N@f3.
+func dropLetter(t PieceType) byte {
+	if t == PieceTypePawn {
+		return 'P'
+	}
+
+	return sanLetters[t]
+}
+
+// Returns the move in UCI long algebraic notation, such as e2e4 or e7e8q.
+// Castling is written as the king's move, such as e1g1, and drops with the piece's letter, such as N@f3.
+func (m Move) UCI() string {
+	if m.IsDrop() {
+		return string(dropLetter(m.Promotion)) + "@" + m.To.Name()
+	}
+
+	s := m.From.Name() + m.To.Name()
+	if letter, ok := uciPromotions[m.Promotion]; ok {
+		s += string(letter)
+	}
+
+	return s
+}
+
+// Parses a move in UCI long algebraic notation, such as e2e4, e7e8q or N@f3.
+// The move is not checked against any position; use Game.ParseUCI for that.
+func ParseUCI(uci string) (Move, error) {
+	if len(uci) == 4 && uci[1] == '@' {
+		return parseDrop(uci)
+	}
+
+	if len(uci) != 4 && len(uci) != 5 {
+		return Move{}, fmt.Errorf("invalid UCI move '%s'", uci)
+	}
+
+	from, err := ParsePosition(uci[0:2])
+	if err != nil {
+		return Move{}, fmt.Errorf("invalid UCI move '%s': %s", uci, err.Error())
+	}
+
+	to, err := ParsePosition(uci[2:4])
+	if err != nil {
+		return Move{}, fmt.Errorf("invalid UCI move '%s': %s", uci, err.Error())
+	}
+
+	m := NewMove(from, to)
+	if len(uci) == 5 {
+		m.Promotion = PieceTypeNone
+		for t, letter := range uciPromotions {
+			if uci[4] == letter {
+				m.Promotion = t
+			}
+		}
+
+		if m.Promotion == PieceTypeNone {
+			return Move{}, fmt.Errorf("invalid promotion in UCI move '%s'", uci)
+		}
+	}
+
+	return m, nil
+}
+
+// Parses a drop written like N@f3, or P@e4 or @e4 for pawns. The piece letter may be in either case.
+func parseDrop(s string) (Move, error) {
+	at := strings.IndexByte(s, '@')
+	if at < 0 || at > 1 {
+		return Move{}, fmt.Errorf("invalid drop '%s'", s)
+	}
+
+	t := PieceType(PieceTypePawn)
+	if letter := strings.ToUpper(s)[0]; at == 1 && letter != 'P' {
+		t = sanPieceType(letter)
+	}
+
+	if t == PieceTypeNone || t == PieceTypeKing {
+		return Move{}, fmt.Errorf("invalid piece in drop '%s'", s)
+	}
+
+	to, err := ParsePosition(s[at+1:])
+	if err != nil {
+		return Move{}, fmt.Errorf("invalid drop '%s': %s", s, err.Error())
+	}
+
+	return NewDrop(t, to), nil
+}
+
+// Parses a move in UCI long algebraic notation and returns the legal move it refers to.
+func (g *Game) ParseUCI(uci string) (Move, error) {
+	m, err := ParseUCI(uci)
+	if err != nil {
+		return Move{}, err
+	}
+
+	legal, ok := g.findLegalMove(m)
+	if !ok || (legal.Promotion != PieceTypeNone && m.Promotion == PieceTypeNone) {
+		return Move{}, fmt.Errorf("illegal move '%s'", uci)
+	}
+
+	return legal, nil
+}
+
+// Returns the move in long algebraic notation, such as Ng1-f3, e5xd6 or e7-e8=Q.
+// A + or # is added if the move gives check or checkmate.
+func (g *Game) LAN(m Move) (string, error) {
+	san, err := g.SAN(m)
+	if err != nil {
+		return "", err
+	}
+
+	// castling and drops are written the same way in both
+	if strings.HasPrefix(san, "O-O") || m.IsDrop() {
+		return san, nil
+	}
+
+	legal, _ := g.findLegalMove(m)
+	var sb strings.Builder
+	if letter, ok := sanLetters[g.Board.At(legal.From).Type()]; ok {
+		sb.WriteByte(letter)
+	}
+
+	sb.WriteString(legal.From.Name())
+	if g.IsCapture(legal) {
+		sb.WriteByte('x')
+	} else {
+		sb.WriteByte('-')
+	}
+
+	sb.WriteString(legal.To.Name())
+	if letter, ok := sanLetters[legal.Promotion]; ok {
+		sb.WriteByte('=')
+		sb.WriteByte(letter)
+	}
+
+	// the check markers are the same as in SAN
+	sb.WriteString(san[len(strings.TrimRight(san, "+#")):])
+	return sb.String(), nil
+}
+
+// Parses a move in long algebraic notation, such as Ng1-f3 or e7xd8=Q, and returns the legal move it refers to.
+func (g *Game) ParseLAN(lan string) (Move, error) {
+	s := strings.TrimRight(lan, "+#!?")
+	switch s {
+	case "O-O", "0-0", "O-O-O", "0-0-0":
+		return g.ParseSAN(s)
+	}
+
+	pieceType := PieceType(PieceTypePawn)
+	if len(s) > 0 && sanPieceType(s[0]) != PieceTypeNone {
+		pieceType = sanPieceType(s[0])
+		s = s[1:]
+	}
+
+	promotion := PieceType(PieceTypeNone)
+	if i := strings.IndexByte(s, '='); i >= 0 {
+		promotion = PieceTypeNone
+		if i == len(s)-2 {
+			promotion = sanPieceType(s[i+1])
+		}
+
+		if promotion == PieceTypeNone || promotion == PieceTypeKing {
+			return Move{}, fmt.Errorf("invalid promotion in move '%s'", lan)
+		}
+
+		s = s[:i]
+	}
+
+	if len(s) != 5 || (s[2] != '-' && s[2] != 'x') {
+		return Move{}, fmt.Errorf("invalid move '%s'", lan)
+	}
+
+	from, err := ParsePosition(s[0:2])
+	if err != nil {
+		return Move{}, fmt.Errorf("invalid move '%s': %s", lan, err.Error())
+	}
+
+	to, err := ParsePosition(s[3:5])
+	if err != nil {
+		return Move{}, fmt.Errorf("invalid move '%s': %s", lan, err.Error())
+	}
+
+	if g.Board.At(from).Type() != pieceType {
+		return Move{}, fmt.Errorf("illegal move '%s'", lan)
+	}
+
+	legal, ok := g.findLegalMove(Move{From: from, To: to, Promotion: promotion})
+	if !ok || legal.Promotion != promotion || (s[2] == 'x') != g.IsCapture(legal) {
+		return Move{}, fmt.Errorf("illegal move '%s'", lan)
+	}
+
+	return legal, nil
+}
+
+// Returns the move in figurine algebraic notation, which is SAN with the piece letters replaced by figurines,
+// such as ♘f3 or e8=♕. The figurines are of the color of the moving piece.
+func (g *Game) FigurineSAN(m Move) (string, error) {
+	san, err := g.SAN(m)
+	if err != nil {
+		return "", err
+	}
+
+	c := g.Board.At(m.From).Color()
+	if m.IsDrop() {
+		c = g.Turn
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(san); i++ {
+		t := sanPieceType(san[i])
+		if t == PieceTypeNone {
+			sb.WriteByte(san[i])
+			continue
+		}
+
+		sb.WriteRune(figurines[MakePiece(t, c)])
+	}
+
+	return sb.String(), nil
+}
+
+// Replaces figurines in a move with the matching SAN letters.
+func replaceFigurines(s string) string {
+	return strings.Map(func(r rune) rune {
+		for i, f := range figurines {
+			if f == r {
+				if letter, ok := sanLetters[Piece(i).Type()]; ok {
+					return rune(letter)
+				}
+
+				// pawn figurines are left out in SAN
+				return -1
+			}
+		}
+
+		return r
+	}, s)
+}
+
+// Parses a move written in any of the supported notations: UCI, SAN, figurine SAN or long algebraic notation.
+// Returns the legal move it refers to.
+func (g *Game) ParseMove(s string) (Move, error) {
+	if m, err := g.ParseUCI(s); err == nil {
+		return m, nil
+	}
+
+	if m, err := g.ParseSAN(s); err == nil {
+		return m, nil
+	}
+
+	if m, err := g.ParseLAN(s); err == nil {
+		return m, nil
+	}
+
+	return Move{}, fmt.Errorf("invalid or illegal move '%s'", s)
+}
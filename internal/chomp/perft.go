@@ -0,0 +1,86 @@
+package chomp
+
+// Represents a position with known perft results, used to check that the move generator is correct.
+type PerftPosition struct {
+	// The name the position is commonly known by
+	Name string
+	// The position in Forsyth-Edwards Notation
+	FEN string
+	// The number of leaf nodes at each depth, starting from depth 1
+	Nodes []uint64
+}
+
+// The standard perft reference positions from the Chess Programming Wiki.
+// Between them they cover castling, en passant, promotions, pins and checks.
+var PerftPositions = []PerftPosition{
+	{
+		Name:  "initial",
+		FEN:   StartFEN,
+		Nodes: []uint64{20, 400, 8902, 197281, 4865609, 119060324},
+	},
+	{
+		Name:  "kiwipete",
+		FEN:   "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		Nodes: []uint64{48, 2039, 97862, 4085603, 193690690},
+	},
+	{
+		Name:  "position 3",
+		FEN:   "8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+		Nodes: []uint64{14, 191, 2812, 43238, 674624, 11030083, 178633661},
+	},
+	{
+		Name:  "position 4",
+		FEN:   "r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1",
+		Nodes: []uint64{6, 264, 9467, 422333, 15833292, 706045033},
+	},
+	{
+		Name:  "position 5",
+		FEN:   "rnbq1k1r/pp1Pbppp/2p5/8/2B5/8/PPP1NnPP/RNBQK2R w KQ - 1 8",
+		Nodes: []uint64{44, 1486, 62379, 2103487, 89941194},
+	},
+	{
+		Name:  "position 6",
+		FEN:   "r4rk1/1pp1qppp/p1np1n2/2b1p1B1/2B1P1b1/P1NP1N2/1PP1QPPP/R4RK1 w - - 0 10",
+		Nodes: []uint64{46, 2079, 89890, 3894594, 164075551},
+	},
+}
+
+// Counts the leaf nodes of the tree of legal moves from the current position to the given depth.
+// The game is left as it was. Draws and the end of the game by rule are not taken into account,
+// only whether there are legal moves.
+func (g *Game) Perft(depth int) uint64 {
+	if depth <= 0 {
+		return 1
+	}
+
+	moves := g.LegalMoves()
+	if depth == 1 {
+		return uint64(len(moves))
+	}
+
+	var nodes uint64
+	for _, m := range moves {
+		g.push(m, 0)
+		nodes += g.Perft(depth - 1)
+		g.Undo()
+	}
+
+	return nodes
+}
+
+// Like Perft, but returns the number of leaf nodes after each legal move separately.
+// This helps find the move that a move generator bug is hiding behind.
+func (g *Game) Divide(depth int) map[Move]uint64 {
+	result := make(map[Move]uint64)
+	if depth <= 0 {
+		return result
+	}
+
+	for _, m := range g.LegalMoves() {
+		g.push(m, 0)
+		result[m] = g.Perft(depth - 1)
+		g.Undo()
+	}
+
+	return result
+}
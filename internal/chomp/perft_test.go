@@ -0,0 +1,49 @@
+package chomp
+
+import "testing"
+
+// The most leaf nodes a depth may have to be checked. Deeper levels take too long to run on every test.
+const (
+	perftNodeLimit      = 5000000
+	perftShortNodeLimit = 100000
+)
+
+func TestPerft(t *testing.T) {
+	limit := uint64(perftNodeLimit)
+	if testing.Short() {
+		limit = perftShortNodeLimit
+	}
+
+	for _, pp := range PerftPositions {
+		g, err := ParseFEN(pp.FEN)
+		if err != nil {
+			t.Fatalf("%s: %s", pp.Name, err.Error())
+		}
+
+		for i, want := range pp.Nodes {
+			if want > limit {
+				break
+			}
+
+			if got := g.Perft(i + 1); got != want {
+				t.Errorf("%s: depth %d: got %d nodes, want %d", pp.Name, i+1, got, want)
+			}
+		}
+
+		if g.FEN() != pp.FEN {
+			t.Errorf("%s: perft left the game at %s", pp.Name, g.FEN())
+		}
+	}
+}
+
+func TestDivide(t *testing.T) {
+	g := NewGame()
+	var total uint64
+	for _, nodes := range g.Divide(3) {
+		total += nodes
+	}
+
+	if total != PerftPositions[0].Nodes[2] {
+		t.Errorf("the nodes after each move add up to %d, want %d", total, PerftPositions[0].Nodes[2])
+	}
+}
@@ -23,8 +23,30 @@ type Position struct {
 	Y int8 `json:"y"`
 }
 
+// Returns the name of the position in algebraic notation, such as e4.
+// Positions outside the board are shown with their coordinates.
 func (p Position) String() string {
-	return fmt.Sprintf("[x=%d y=%d]", p.X, p.Y)
+	if p.Locate() == LocationOutOfBounds {
+		return fmt.Sprintf("[x=%d y=%d]", p.X, p.Y)
+	}
+
+	return p.Name()
+}
+
+// Returns the name of the position in algebraic notation, such as e4.
+// The file is given by the X coordinate and the rank by the Y coordinate.
+// The result is meaningless for positions outside the board.
+func (p Position) Name() string {
+	return string([]byte{byte('a' + p.X), byte('1' + p.Y)})
+}
+
+// Parses a position in algebraic notation, such as e4.
+func ParsePosition(s string) (Position, error) {
+	if len(s) != 2 || s[0] < 'a' || s[0] > 'h' || s[1] < '1' || s[1] > '8' {
+		return ErrorPos, fmt.Errorf("invalid square '%s'", s)
+	}
+
+	return NewPos(int8(s[0]-'a'), int8(s[1]-'1')), nil
 }
 
 // Represents an erroneous position.
@@ -82,23 +104,26 @@ func (p Position) IsCornering(a Position) bool {
 
 // Returns whether position a is touching this position by one side.
 func (p Position) IsAdjacent(a Position) bool {
-	// So here deltaX or deltaY must be 1, but not both.
-	return abs8(p.X-a.X) == 1 || abs8(p.Y-a.Y) == 1 && !p.IsCornering(a)
+	// So here deltaX or deltaY must be 1, and the other one 0.
+	return abs8(p.X-a.X)+abs8(p.Y-a.Y) == 1
 }
 
 // Returns whether position a is one of the 8 positions next to this.
 func (p Position) IsNextTo(a Position) bool {
-	return abs8(p.X-a.X) == 1 || abs8(p.Y-a.Y) == 1
+	return p != a && abs8(p.X-a.X) <= 1 && abs8(p.Y-a.Y) <= 1
 }
 
+// Returns whether position a is on the same file or rank as this position.
 func (p Position) IsLinedUpWith(a Position) bool {
 	return p.X == a.X || p.Y == a.Y
 }
 
+// Returns whether position a is on the same diagonal as this position.
 func (p Position) IsOnSameSlope(a Position) bool {
-	return (p.Y-a.Y)/(p.X-a.X) == 1
+	return p != a && abs8(p.X-a.X) == abs8(p.Y-a.Y)
 }
 
+// Returns whether a knight could jump from this position to position a.
 func (p Position) IsKnightAvailable(a Position) bool {
 	slope := math.Abs(float64((p.Y - a.Y)) / float64((p.X - a.X)))
 	diffX := abs8(p.X - a.X)
@@ -107,6 +132,7 @@ func (p Position) IsKnightAvailable(a Position) bool {
 	return (slope == 2 || slope == 0.5) && ((diffX == 1 && diffY == 2) || (diffX == 2 && diffY == 1))
 }
 
+// Returns the positions between a and b, which must be on the same file, rank or diagonal.
 func PositionsBetween(a, b Position) ([]Position, error) {
 	if !a.IsLinedUpWith(b) && !a.IsOnSameSlope(b) || a == b {
 		return nil, fmt.Errorf("the given positions were invalid")
@@ -0,0 +1,81 @@
+package chomp
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPositionRelations(t *testing.T) {
+	tests := []struct {
+		a, b                           string
+		adjacent, nextTo, sameDiagonal bool
+	}{
+		{"e4", "e5", true, true, false},
+		{"e4", "d4", true, true, false},
+		{"e4", "d5", false, true, true},
+		{"e4", "f3", false, true, true},
+		{"e4", "e4", false, false, false},
+		{"e4", "e6", false, false, false},
+		{"e4", "f6", false, false, false},
+		{"e4", "g7", false, false, false},
+		{"e4", "h7", false, false, true},
+		{"e4", "b7", false, false, true},
+		{"e4", "a8", false, false, true},
+		// on the same file, which used to divide by zero
+		{"e4", "e8", false, false, false},
+		{"a1", "h1", false, false, false},
+	}
+
+	for _, test := range tests {
+		a, _ := ParsePosition(test.a)
+		b, _ := ParsePosition(test.b)
+		for _, pair := range [][2]Position{{a, b}, {b, a}} {
+			p, q := pair[0], pair[1]
+			if got := p.IsAdjacent(q); got != test.adjacent {
+				t.Errorf("%s.IsAdjacent(%s) = %t, want %t", p, q, got, test.adjacent)
+			}
+
+			if got := p.IsNextTo(q); got != test.nextTo {
+				t.Errorf("%s.IsNextTo(%s) = %t, want %t", p, q, got, test.nextTo)
+			}
+
+			if got := p.IsOnSameSlope(q); got != test.sameDiagonal {
+				t.Errorf("%s.IsOnSameSlope(%s) = %t, want %t", p, q, got, test.sameDiagonal)
+			}
+		}
+	}
+}
+
+func TestPositionsBetween(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want []string
+	}{
+		{"e1", "e8", []string{"e2", "e3", "e4", "e5", "e6", "e7"}},
+		{"h3", "c3", []string{"d3", "e3", "f3", "g3"}},
+		{"a1", "d4", []string{"b2", "c3"}},
+		{"b7", "e4", []string{"c6", "d5"}},
+		{"e4", "e5", []string{}},
+		{"e4", "f5", []string{}},
+	}
+
+	for _, test := range tests {
+		a, _ := ParsePosition(test.a)
+		b, _ := ParsePosition(test.b)
+		between, err := PositionsBetween(a, b)
+		if err != nil {
+			t.Errorf("%s-%s: %s", test.a, test.b, err.Error())
+			continue
+		}
+
+		if got := sortedNames(between); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s-%s: got %v, want %v", test.a, test.b, got, test.want)
+		}
+	}
+
+	for _, pair := range [][2]Position{{NewPos(4, 3), NewPos(5, 5)}, {NewPos(4, 3), NewPos(4, 3)}} {
+		if _, err := PositionsBetween(pair[0], pair[1]); err == nil {
+			t.Errorf("got the positions between %s and %s", pair[0], pair[1])
+		}
+	}
+}
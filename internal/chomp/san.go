@@ -0,0 +1,246 @@
+package chomp
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The letters used for pieces in Standard Algebraic Notation, indexed by piece type. Pawns have no letter.
+var sanLetters = map[PieceType]byte{
+	PieceTypeRook:   'R',
+	PieceTypeKnight: 'N',
+	PieceTypeBishop: 'B',
+	PieceTypeQueen:  'Q',
+	PieceTypeKing:   'K',
+}
+
+// Returns the piece type with the given letter, or PieceTypeNone if there is none.
+func sanPieceType(letter byte) PieceType {
+	for t, l := range sanLetters {
+		if l == letter {
+			return t
+		}
+	}
+
+	return PieceTypeNone
+}
+
+// Returns whether the move is castling: the king moving two squares, or onto its own rook in Chess960.
+// The move must be legal in the game.
+func (g *Game) isCastling(m Move) bool {
+	piece := g.Board.At(m.From)
+	if piece.Type() != PieceTypeKing {
+		return false
+	}
+
+	return abs8(m.To.X-m.From.X) == 2 || g.Board.At(m.To) == MakePiece(PieceTypeRook, piece.Color())
+}
+
+// Returns whether the move captures a piece. The move must be legal in the game.
+func (g *Game) IsCapture(m Move) bool {
+	if captured := g.Board.At(m.To); captured != PieceNone {
+		// the king moving onto its own rook is castling in Chess960
+		return captured.Color() != g.Board.At(m.From).Color()
+	}
+
+	return g.Board.At(m.From).Type() == PieceTypePawn && m.From.X != m.To.X
+}
+
+// Returns the move in Standard Algebraic Notation, such as Nbd7, exd5, e8=Q, O-O or the drop N@f3.
+// A + or # is added if the move gives check or checkmate.
+func (g *Game) SAN(m Move) (string, error) {
+	legal, ok := g.findLegalMove(m)
+	if !ok {
+		return "", fmt.Errorf("illegal move: %s", m)
+	}
+
+	san := g.sanWithoutSuffix(legal)
+
+	// the game is put back to how it was before returning. Apply would refuse moves in games that are over,
+	// which may still have legal moves, as with insufficient material.
+	if err := g.ApplyPastEnd(legal); err != nil {
+		return "", err
+	}
+
+	if g.Board.Checkmate != ColorNone {
+		san += "#"
+	} else if g.Board.Checked != ColorNone {
+		san += "+"
+	}
+
+	return san, g.Undo()
+}
+
+func (g *Game) sanWithoutSuffix(m Move) string {
+	if m.IsDrop() {
+		return m.UCI()
+	}
+
+	if g.isCastling(m) {
+		if m.To.X > m.From.X {
+			return "O-O"
+		}
+
+		return "O-O-O"
+	}
+
+	piece := g.Board.At(m.From)
+	var sb strings.Builder
+	if piece.Type() == PieceTypePawn {
+		if g.IsCapture(m) {
+			sb.WriteByte(byte('a' + m.From.X))
+		}
+	} else {
+		sb.WriteByte(sanLetters[piece.Type()])
+		sb.WriteString(g.disambiguation(m))
+	}
+
+	if g.IsCapture(m) {
+		sb.WriteByte('x')
+	}
+
+	sb.WriteString(m.To.Name())
+	if m.Promotion != PieceTypeNone {
+		sb.WriteByte('=')
+		sb.WriteByte(sanLetters[m.Promotion])
+	}
+
+	return sb.String()
+}
+
+// Returns what is needed to tell the move apart from moves of other pieces of the same type to the same square:
+// nothing, the file, the rank, or both.
+func (g *Game) disambiguation(m Move) string {
+	piece := g.Board.At(m.From)
+	ambiguous, sameFile, sameRank := false, false, false
+	for _, other := range g.LegalMoves() {
+		if other.To != m.To || other.From == m.From || g.Board.At(other.From) != piece {
+			continue
+		}
+
+		ambiguous = true
+		if other.From.X == m.From.X {
+			sameFile = true
+		}
+
+		if other.From.Y == m.From.Y {
+			sameRank = true
+		}
+	}
+
+	switch {
+	case !ambiguous:
+		return ""
+	case !sameFile:
+		return string(rune('a' + m.From.X))
+	case !sameRank:
+		return string(rune('1' + m.From.Y))
+	}
+
+	return m.From.Name()
+}
+
+// Parses a move in Standard Algebraic Notation and returns the legal move it refers to.
+// Check and checkmate markers and annotations such as ! and ? are ignored.
+// Castling may be written with either letter O or the digit 0, and figurines may be used instead of piece letters.
+func (g *Game) ParseSAN(san string) (Move, error) {
+	s := strings.TrimRight(replaceFigurines(san), "+#!?")
+	switch s {
+	case "O-O", "0-0":
+		return g.findCastling(san, 1)
+	case "O-O-O", "0-0-0":
+		return g.findCastling(san, -1)
+	}
+
+	if strings.IndexByte(s, '@') >= 0 {
+		m, err := parseDrop(s)
+		if err != nil {
+			return Move{}, err
+		}
+
+		legal, ok := g.findLegalMove(m)
+		if !ok {
+			return Move{}, fmt.Errorf("illegal move '%s'", san)
+		}
+
+		return legal, nil
+	}
+
+	pieceType := PieceType(PieceTypePawn)
+	if len(s) > 0 && sanPieceType(s[0]) != PieceTypeNone {
+		pieceType = sanPieceType(s[0])
+		s = s[1:]
+	}
+
+	promotion := PieceType(PieceTypeNone)
+	if pieceType == PieceTypePawn && len(s) > 2 && strings.IndexByte("RNBQK", s[len(s)-1]) >= 0 {
+		// the = sign is optional in some files, like in e8Q. Kings are promoted to in Antichess.
+		promotion = sanPieceType(s[len(s)-1])
+		s = strings.TrimSuffix(s[:len(s)-1], "=")
+	}
+
+	if len(s) < 2 {
+		return Move{}, fmt.Errorf("invalid move '%s'", san)
+	}
+
+	to, err := ParsePosition(s[len(s)-2:])
+	if err != nil {
+		return Move{}, fmt.Errorf("invalid move '%s': %s", san, err.Error())
+	}
+
+	// whatever is left is the disambiguation, possibly followed by a capture marker
+	from := strings.TrimSuffix(s[:len(s)-2], "x")
+	fromX, fromY := int8(-1), int8(-1)
+	for _, c := range from {
+		switch {
+		case c >= 'a' && c <= 'h' && fromX < 0:
+			fromX = int8(c - 'a')
+		case c >= '1' && c <= '8' && fromY < 0:
+			fromY = int8(c - '1')
+		default:
+			return Move{}, fmt.Errorf("invalid move '%s'", san)
+		}
+	}
+
+	if pieceType == PieceTypePawn && fromX < 0 {
+		// pawns not capturing stay on their file
+		fromX = to.X
+	}
+
+	var found []Move
+	for _, m := range g.LegalMoves() {
+		if m.To != to || g.Board.At(m.From).Type() != pieceType || m.Promotion != promotion {
+			continue
+		}
+
+		if (fromX >= 0 && m.From.X != fromX) || (fromY >= 0 && m.From.Y != fromY) {
+			continue
+		}
+
+		if g.isCastling(m) {
+			continue
+		}
+
+		found = append(found, m)
+	}
+
+	switch len(found) {
+	case 0:
+		return Move{}, fmt.Errorf("illegal move '%s'", san)
+	case 1:
+		return found[0], nil
+	}
+
+	return Move{}, fmt.Errorf("ambiguous move '%s'", san)
+}
+
+// Finds the legal castling move towards the given direction on the X axis.
+func (g *Game) findCastling(san string, dir int8) (Move, error) {
+	for _, m := range g.LegalMoves() {
+		if g.isCastling(m) && (m.To.X-m.From.X)*dir > 0 {
+			return m, nil
+		}
+	}
+
+	return Move{}, fmt.Errorf("illegal move '%s'", san)
+}
@@ -0,0 +1,204 @@
+package chomp
+
+import "testing"
+
+func TestSAN(t *testing.T) {
+	tests := []struct {
+		fen, uci, san, lan, figurine string
+	}{
+		{StartFEN, "g1f3", "Nf3", "Ng1-f3", "♘f3"},
+		{"rnbqkbnr/pppp1ppp/8/4p3/6P1/5P2/PPPPP2P/RNBQKBNR b KQkq - 0 2", "d8h4", "Qh4#", "Qd8-h4#", "♛h4#"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "e1c1", "O-O-O", "O-O-O", "O-O-O"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1", "e8g8", "O-O", "O-O", "O-O"},
+		{"4k3/8/8/8/8/8/8/R3K3 w - - 0 1", "a1a8", "Ra8+", "Ra1-a8+", "♖a8+"},
+		{"8/4P3/8/8/8/8/k7/4K3 w - - 0 1", "e7e8q", "e8=Q", "e7-e8=Q", "e8=♕"},
+		{"3rk3/4P3/8/8/8/8/8/4K3 w - - 0 1", "e7d8n", "exd8=N", "e7xd8=N", "exd8=♘"},
+		{"3rk3/4P3/8/8/8/8/8/4K3 w - - 0 1", "e7d8q", "exd8=Q+", "e7xd8=Q+", "exd8=♕+"},
+		{"4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 1", "e5d6", "exd6", "e5xd6", "exd6"},
+		// disambiguation by file, by rank and by both
+		{"rnbqkb1r/ppp1pppp/5n2/3p4/8/8/PPPPPPPP/RNBQKBNR b KQkq - 0 1", "b8d7", "Nbd7", "Nb8-d7", "♞bd7"},
+		{"k7/8/8/8/8/4R3/8/4R1K1 w - - 0 1", "e1e2", "R1e2", "Re1-e2", "♖1e2"},
+		{"4k3/8/8/8/8/Q7/8/Q1Q3K1 w - - 0 1", "a1b2", "Qa1b2", "Qa1-b2", "♕a1b2"},
+		// games over by insufficient material or the seventy-five-move rule still have legal moves
+		{"8/8/8/8/8/8/8/KB5k w - - 0 1", "a1b2", "Kb2", "Ka1-b2", "♔b2"},
+		{"8/8/8/8/8/8/8/KN5k w - - 0 1", "b1d2", "Nd2", "Nb1-d2", "♘d2"},
+		{"4k3/8/8/8/8/8/8/R3K3 w - - 150 100", "a1a8", "Ra8+", "Ra1-a8+", "♖a8+"},
+	}
+
+	for _, test := range tests {
+		g, err := ParseFEN(test.fen)
+		if err != nil {
+			t.Fatalf("%s: %s", test.fen, err.Error())
+		}
+
+		m, err := g.ParseUCI(test.uci)
+		if err != nil {
+			t.Fatalf("%s: %s", test.fen, err.Error())
+		}
+
+		before := g.FEN()
+		san, err := g.SAN(m)
+		if err != nil {
+			t.Errorf("%s %s: %s", test.fen, test.uci, err.Error())
+			continue
+		}
+
+		if san != test.san {
+			t.Errorf("%s %s: got %s, want %s", test.fen, test.uci, san, test.san)
+		}
+
+		if g.FEN() != before {
+			t.Errorf("%s %s: the game was changed to %s", test.fen, test.uci, g.FEN())
+		}
+
+		if lan, err := g.LAN(m); err != nil || lan != test.lan {
+			t.Errorf("%s %s: got the LAN %s with the error %v, want %s", test.fen, test.uci, lan, err, test.lan)
+		}
+
+		if figurine, err := g.FigurineSAN(m); err != nil || figurine != test.figurine {
+			t.Errorf("%s %s: got the figurine SAN %s with the error %v, want %s", test.fen, test.uci, figurine, err, test.figurine)
+		}
+
+		for _, s := range []string{test.uci, test.san, test.lan, test.figurine} {
+			if parsed, err := g.ParseMove(s); err != nil || parsed != m {
+				t.Errorf("%s: %s was parsed as %s with the error %v, want %s", test.fen, s, parsed.UCI(), err, test.uci)
+			}
+		}
+	}
+}
+
+func TestParseSANRoundTrip(t *testing.T) {
+	fens := []string{
+		StartFEN,
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		"r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1",
+	}
+
+	for _, fen := range fens {
+		g, err := ParseFEN(fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, m := range g.LegalMoves() {
+			san, err := g.SAN(m)
+			if err != nil {
+				t.Fatalf("%s %s: %s", fen, m.UCI(), err.Error())
+			}
+
+			if parsed, err := g.ParseSAN(san); err != nil || parsed != m {
+				t.Errorf("%s: %s was parsed as %s with the error %v, want %s", fen, san, parsed.UCI(), err, m.UCI())
+			}
+
+			lan, _ := g.LAN(m)
+			if parsed, err := g.ParseLAN(lan); err != nil || parsed != m {
+				t.Errorf("%s: %s was parsed as %s with the error %v, want %s", fen, lan, parsed.UCI(), err, m.UCI())
+			}
+		}
+	}
+}
+
+func TestParseSANInvalid(t *testing.T) {
+	tests := []struct {
+		fen, san, err string
+	}{
+		{StartFEN, "Nf4", "illegal move 'Nf4'"},
+		{StartFEN, "e5", "illegal move 'e5'"},
+		{StartFEN, "O-O", "illegal move 'O-O'"},
+		{StartFEN, "Zf3", "invalid move 'Zf3'"},
+		{StartFEN, "N", "invalid move 'N'"},
+		{StartFEN, "Nf9", "invalid move 'Nf9': invalid square 'f9'"},
+		{"k7/8/8/8/8/4R3/8/4R1K1 w - - 0 1", "Re2", "ambiguous move 'Re2'"},
+	}
+
+	for _, test := range tests {
+		g, err := ParseFEN(test.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := g.ParseSAN(test.san); err == nil || err.Error() != test.err {
+			t.Errorf("%s: got the error %v, want %s", test.san, err, test.err)
+		}
+	}
+
+	// the = sign of a promotion and the letter O of castling may be left out
+	g, _ := ParseFEN("r3k3/8/8/8/8/8/8/4K3 b q - 0 1")
+	if m, err := g.ParseSAN("0-0-0"); err != nil || m.UCI() != "e8c8" {
+		t.Errorf("0-0-0 was parsed as %s with the error %v", m.UCI(), err)
+	}
+
+	g, _ = ParseFEN("3rk3/4P3/8/8/8/8/8/4K3 w - - 0 1")
+	if m, err := g.ParseSAN("exd8N"); err != nil || m.UCI() != "e7d8n" {
+		t.Errorf("exd8N was parsed as %s with the error %v", m.UCI(), err)
+	}
+}
+
+func TestParseUCI(t *testing.T) {
+	tests := []struct {
+		uci, err string
+	}{
+		{"e2e4", ""},
+		{"e7e8q", ""},
+		{"e2e", "invalid UCI move 'e2e'"},
+		{"e2e4qq", "invalid UCI move 'e2e4qq'"},
+		{"i2e4", "invalid UCI move 'i2e4': invalid square 'i2'"},
+		{"e2e9", "invalid UCI move 'e2e9': invalid square 'e9'"},
+		{"e7e8k", ""},
+		{"e7e8x", "invalid promotion in UCI move 'e7e8x'"},
+	}
+
+	for _, test := range tests {
+		m, err := ParseUCI(test.uci)
+		switch {
+		case test.err == "" && err != nil:
+			t.Errorf("%s: %s", test.uci, err.Error())
+		case test.err == "" && m.UCI() != test.uci:
+			t.Errorf("%s was written back as %s", test.uci, m.UCI())
+		case test.err != "" && (err == nil || err.Error() != test.err):
+			t.Errorf("%s: got the error %v, want %s", test.uci, err, test.err)
+		}
+	}
+
+	// a promotion must name its piece when checked against a game
+	g, _ := ParseFEN("8/4P3/8/8/8/8/k7/4K3 w - - 0 1")
+	if _, err := g.ParseUCI("e7e8"); err == nil {
+		t.Errorf("e7e8 was accepted without a promotion")
+	}
+
+	if _, err := NewGame().ParseUCI("e2e4q"); err == nil {
+		t.Errorf("e2e4q was accepted as a move that isn't a promotion")
+	}
+
+	// only Antichess promotes to a king
+	if _, err := g.ParseUCI("e7e8k"); err == nil || err.Error() != "illegal move 'e7e8k'" {
+		t.Errorf("e7e8k gave the error %v in standard chess", err)
+	}
+
+	g, _ = ParseVariantFEN(Antichess{}, "8/4P3/8/8/8/8/k7/4K3 w - - 0 1")
+	if m, err := g.ParseUCI("e7e8k"); err != nil || m.Promotion != PieceTypeKing {
+		t.Errorf("e7e8k was parsed as %s with the error %v in Antichess", m.UCI(), err)
+	}
+}
+
+func TestPositionNames(t *testing.T) {
+	for x := int8(0); x < 8; x++ {
+		for y := int8(0); y < 8; y++ {
+			p := NewPos(x, y)
+			parsed, err := ParsePosition(p.Name())
+			if err != nil || parsed != p {
+				t.Errorf("%s was parsed as %v with the error %v", p.Name(), parsed, err)
+			}
+		}
+	}
+
+	if NewPos(4, 3).String() != "e4" || ErrorPos.String() != "[x=-1 y=-1]" {
+		t.Errorf("got the names %s and %s", NewPos(4, 3), ErrorPos)
+	}
+
+	for _, s := range []string{"", "e", "e44", "i1", "a0", "A1"} {
+		if _, err := ParsePosition(s); err == nil {
+			t.Errorf("%q was parsed as a square", s)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package chomp
+
+// Represents how a game has ended, if it has.
+type Outcome int8
+
+const (
+	OutcomeOngoing   = 0
+	OutcomeWhiteWins = 1
+	OutcomeBlackWins = 2
+	OutcomeDraw      = 3
+)
+
+var outcomeNames = map[Outcome]string{
+	OutcomeOngoing:   "ongoing",
+	OutcomeWhiteWins: "white wins",
+	OutcomeBlackWins: "black wins",
+	OutcomeDraw:      "draw",
+}
+
+func (o Outcome) String() string {
+	name, ok := outcomeNames[o]
+	if !ok {
+		return "<invalid>"
+	}
+
+	return name
+}
+
+// Returns whether the king of the given color is attacked.
+func (b *Board) InCheck(c Color) bool {
+	king, ok := b.findKing(c)
+	if !ok {
+		return false
+	}
+
+	return b.IsAttacked(king, c.Opposite())
+}
+
+// Recomputes the Checked, Checkmate and Stalemate fields of the board for the color whose turn it is,
+// and whether the game is drawn automatically.
+func (g *Game) updateStatus() {
+	g.Board.Checked = ColorNone
+	g.Board.Checkmate = ColorNone
+	g.Board.Stalemate = ColorNone
+
+	check := g.variant().InCheck(g, g.Turn)
+	if check {
+		g.Board.Checked = g.Turn
+	}
+
+	if len(g.LegalMoves()) == 0 {
+		if check {
+			g.Board.Checkmate = g.Turn
+		} else {
+			g.Board.Stalemate = g.Turn
+		}
+	}
+
+	// games the variant's rules have ended are not drawn
+	g.DrawReason = DrawReasonNone
+	if g.variant().Outcome(g) == OutcomeOngoing {
+		g.DrawReason = g.automaticDraw()
+	}
+}
+
+// Returns the outcome of the game. The rules of the game's variant come first, then checkmate and draws.
+func (g *Game) Outcome() Outcome {
+	if o := g.variant().Outcome(g); o != OutcomeOngoing {
+		return o
+	}
+
+	switch {
+	case g.Board.Checkmate == ColorWhite:
+		return OutcomeBlackWins
+	case g.Board.Checkmate == ColorBlack:
+		return OutcomeWhiteWins
+	case g.DrawReason != DrawReasonNone:
+		return OutcomeDraw
+	}
+
+	return OutcomeOngoing
+}
+
+// Returns whether the game has ended.
+func (g *Game) IsOver() bool {
+	return g.Outcome() != OutcomeOngoing
+}
+
+// Returns the outcome of the game if the player of the given color runs out of time: the other player wins,
+// unless they couldn't possibly checkmate, in which case it's a draw. In variants, running out of time always loses.
+func (g *Game) TimeoutOutcome(flagged Color) Outcome {
+	winner := flagged.Opposite()
+	if _, ok := g.variant().(Standard); ok && !g.Board.HasMatingMaterial(winner) {
+		return OutcomeDraw
+	}
+
+	if winner == ColorWhite {
+		return OutcomeWhiteWins
+	}
+
+	return OutcomeBlackWins
+}
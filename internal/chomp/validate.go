@@ -0,0 +1,206 @@
+package chomp
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Represents a kind of problem that makes a position impossible to reach or to play from.
+type ProblemKind int8
+
+const (
+	// A square holds a value that is not a piece
+	ProblemInvalidPiece = 1
+	// A player has no king or more than one
+	ProblemKingCount = 2
+	// A pawn stands on the first or last rank
+	ProblemPawnOnBackRank = 3
+	// A player has more than eight pawns
+	ProblemTooManyPawns = 4
+	// A player has more pieces than the promotions of their missing pawns can explain
+	ProblemTooManyPromotedPieces = 5
+	// The player who is not to move is in check
+	ProblemOpponentInCheck = 6
+	// A castling right is set without the king and rook on their squares
+	ProblemImpossibleCastling = 7
+	// The en passant position is not one a pawn has just skipped over
+	ProblemInvalidEnPassant = 8
+)
+
+var problemKindNames = map[ProblemKind]string{
+	ProblemInvalidPiece:          "invalid piece",
+	ProblemKingCount:             "wrong number of kings",
+	ProblemPawnOnBackRank:        "pawn on first or last rank",
+	ProblemTooManyPawns:          "too many pawns",
+	ProblemTooManyPromotedPieces: "too many promoted pieces",
+	ProblemOpponentInCheck:       "player not to move in check",
+	ProblemImpossibleCastling:    "impossible castling rights",
+	ProblemInvalidEnPassant:      "invalid en passant position",
+}
+
+func (k ProblemKind) String() string {
+	name, ok := problemKindNames[k]
+	if !ok {
+		return "<invalid>"
+	}
+
+	return name
+}
+
+// A problem found by validating a position.
+type Problem struct {
+	Kind ProblemKind `json:"kind"`
+	// The color of the player the problem is about, or ColorNone if it's not about either
+	Color Color `json:"color"`
+	// The positions of the pieces or squares the problem is about
+	Positions []Position `json:"positions,omitempty"`
+}
+
+// Returns a description of the problem, such as "wrong number of kings: white (e1, g1)".
+func (p Problem) String() string {
+	var sb strings.Builder
+	sb.WriteString(p.Kind.String())
+	if p.Color != ColorNone {
+		sb.WriteString(": ")
+		sb.WriteString(p.Color.String())
+	}
+
+	if len(p.Positions) > 0 {
+		names := make([]string, len(p.Positions))
+		for i, pos := range p.Positions {
+			names[i] = pos.Name()
+		}
+
+		fmt.Fprintf(&sb, " (%s)", strings.Join(names, ", "))
+	}
+
+	return sb.String()
+}
+
+// How many of each piece a player starts with, indexed by piece type. Any more must have been promoted from pawns.
+var startingPieceCounts = [6]int{8, 2, 2, 2, 1, 1}
+
+// Returns the problem of the squares holding values that are not pieces, if there are any,
+// and the positions of the pieces of each color by type.
+func (b *Board) validatePieces() ([]Problem, [2][6][]Position) {
+	problems := []Problem{}
+	invalid := []Position{}
+	var pieces [2][6][]Position
+	for x := int8(0); x < 8; x++ {
+		for y := int8(0); y < 8; y++ {
+			p := NewPos(x, y)
+			piece := b.Grid[x][y]
+			switch {
+			case piece == PieceNone:
+			case piece.Color() == ColorNone:
+				invalid = append(invalid, p)
+			default:
+				ci := colorIndex(piece.Color())
+				pieces[ci][piece.Type()] = append(pieces[ci][piece.Type()], p)
+			}
+		}
+	}
+
+	if len(invalid) > 0 {
+		problems = append(problems, Problem{Kind: ProblemInvalidPiece, Color: ColorNone, Positions: invalid})
+	}
+
+	return problems, pieces
+}
+
+// Returns the problems that make the position on the board impossible: squares holding values that are not pieces,
+// a player without exactly one king, pawns on the first or last rank, and more pawns or promoted pieces than
+// a player can have. Returns an empty list if there are none.
+// Use Game.Validate to also check what depends on the player to move, the castling rights and en passant.
+func (b *Board) Validate() []Problem {
+	problems, pieces := b.validatePieces()
+	for _, c := range []Color{ColorWhite, ColorBlack} {
+		own := pieces[colorIndex(c)]
+		if kings := own[PieceTypeKing]; len(kings) != 1 {
+			problems = append(problems, Problem{Kind: ProblemKingCount, Color: c, Positions: kings})
+		}
+
+		backRankPawns := []Position{}
+		for _, p := range own[PieceTypePawn] {
+			if p.Y == 0 || p.Y == 7 {
+				backRankPawns = append(backRankPawns, p)
+			}
+		}
+
+		if len(backRankPawns) > 0 {
+			problems = append(problems, Problem{Kind: ProblemPawnOnBackRank, Color: c, Positions: backRankPawns})
+		}
+
+		pawns := len(own[PieceTypePawn])
+		if pawns > startingPieceCounts[PieceTypePawn] {
+			problems = append(problems, Problem{Kind: ProblemTooManyPawns, Color: c, Positions: own[PieceTypePawn]})
+			continue
+		}
+
+		// every piece beyond the starting ones was a pawn once
+		promoted := 0
+		extra := []Position{}
+		for _, t := range []PieceType{PieceTypeRook, PieceTypeKnight, PieceTypeBishop, PieceTypeQueen} {
+			if n := len(own[t]) - startingPieceCounts[t]; n > 0 {
+				promoted += n
+				extra = append(extra, own[t]...)
+			}
+		}
+
+		if promoted > startingPieceCounts[PieceTypePawn]-pawns {
+			problems = append(problems, Problem{Kind: ProblemTooManyPromotedPieces, Color: c, Positions: extra})
+		}
+	}
+
+	return problems
+}
+
+// Returns the problems that make the game's position impossible: those of Board.Validate, the player who is
+// not to move being in check, castling rights without the king and rook on their squares, and an en passant position
+// no pawn has just skipped over. Returns an empty list if there are none.
+// Variants change the pieces players can have, so in games of a variant other than standard chess, the pieces are
+// only checked for being pieces at all, and check is decided by the variant.
+func (g *Game) Validate() []Problem {
+	var problems []Problem
+	if _, ok := g.variant().(Standard); ok {
+		problems = g.Board.Validate()
+	} else {
+		problems, _ = g.Board.validatePieces()
+	}
+
+	opponent := g.Turn.Opposite()
+	if g.variant().InCheck(g, opponent) {
+		king, _ := g.Board.findKing(opponent)
+		problems = append(problems, Problem{Kind: ProblemOpponentInCheck, Color: opponent, Positions: []Position{king}})
+	}
+
+	for i, x := range g.CastlingFiles {
+		if !g.Castling.Has(1 << i) {
+			continue
+		}
+
+		c := castlingColor(i)
+		y := backRankOf(c)
+		rook := NewPos(x, y)
+		king, ok := g.Board.findKing(c)
+
+		// the kingside rook is to the right of the king and the queenside one to the left
+		possible := ok && king.Y == y && g.Board.At(rook) == MakePiece(PieceTypeRook, c) &&
+			(king.X < x) == (i%2 == 0) && (g.Chess960 || king.X == 4)
+		if !possible {
+			problems = append(problems, Problem{Kind: ProblemImpossibleCastling, Color: c, Positions: []Position{rook}})
+		}
+	}
+
+	if ep := g.EnPassant; ep != ErrorPos {
+		// the pawn moved two squares from its starting position past the en passant one
+		dir := pawnDirection(opponent)
+		from, to := ep.offset(0, -dir), ep.offset(0, dir)
+		if ep.Y != backRankOf(opponent)+2*dir || g.Board.At(ep) != PieceNone || g.Board.At(from) != PieceNone ||
+			g.Board.At(to) != MakePiece(PieceTypePawn, opponent) {
+			problems = append(problems, Problem{Kind: ProblemInvalidEnPassant, Color: opponent, Positions: []Position{ep}})
+		}
+	}
+
+	return problems
+}
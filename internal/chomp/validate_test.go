@@ -0,0 +1,69 @@
+package chomp
+
+import "testing"
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		variant Variant
+		fen     string
+		kinds   []ProblemKind
+	}{
+		{Standard{}, StartFEN, nil},
+		{Standard{}, "4k3/8/8/8/8/8/8/8 w - - 0 1", []ProblemKind{ProblemKingCount}},
+		{Standard{}, "P3k3/8/8/8/8/8/8/4K3 w - - 0 1", []ProblemKind{ProblemPawnOnBackRank}},
+		{Standard{}, "4k3/pppppppp/p7/8/8/8/8/4K3 w - - 0 1", []ProblemKind{ProblemTooManyPawns}},
+		{Standard{}, "4k3/8/8/8/8/8/PPPPPPPP/QQ2K3 w - - 0 1", []ProblemKind{ProblemTooManyPromotedPieces}},
+		{Standard{}, "4k3/8/8/8/8/8/8/4RK2 w - - 0 1", []ProblemKind{ProblemOpponentInCheck}},
+		{Standard{}, "4k3/8/8/8/8/8/8/4K3 w K - 0 1", []ProblemKind{ProblemImpossibleCastling}},
+		{Standard{}, "4k3/8/8/8/8/8/8/4K3 w - e6 0 1", []ProblemKind{ProblemInvalidEnPassant}},
+		{Standard{}, "rnbqkbnr/ppp1pppp/8/3pP3/8/8/PPPP1PPP/RNBQKBNR w KQkq d6 0 3", nil},
+		// the pieces of variants are not those of standard chess
+		{Horde{}, "", nil},
+		{Antichess{}, "", nil},
+		{Antichess{}, "k7/8/8/8/8/8/8/Q3K2K w - - 0 1", nil},
+		{Crazyhouse{}, "4k3/pppppppp/p7/8/8/8/8/4K3[] w - - 0 1", nil},
+		// kings next to each other can't be in check in Atomic chess
+		{Atomic{}, "8/8/8/8/8/8/3kK3/3R4 w - - 0 1", nil},
+		{Atomic{}, "8/8/8/8/8/3k4/8/3RK3 w - - 0 1", []ProblemKind{ProblemOpponentInCheck}},
+		{Horde{}, "4k3/8/8/8/8/8/8/P7 w K - 0 1", []ProblemKind{ProblemImpossibleCastling}},
+	}
+
+	for _, test := range tests {
+		fen := test.fen
+		if fen == "" {
+			fen = test.variant.StartFEN()
+		}
+
+		g, err := ParseVariantFEN(test.variant, fen)
+		if err != nil {
+			t.Fatalf("%s: %s", fen, err.Error())
+		}
+
+		problems := g.Validate()
+		kinds := []ProblemKind{}
+		for _, p := range problems {
+			kinds = append(kinds, p.Kind)
+		}
+
+		if len(kinds) != len(test.kinds) {
+			t.Errorf("%s %s: got the problems %v, want %v", test.variant.Name(), fen, problems, test.kinds)
+			continue
+		}
+
+		for i := range kinds {
+			if kinds[i] != test.kinds[i] {
+				t.Errorf("%s %s: got the problems %v, want %v", test.variant.Name(), fen, problems, test.kinds)
+				break
+			}
+		}
+	}
+}
+
+func TestValidateInvalidPiece(t *testing.T) {
+	g := NewGame()
+	g.Board.Grid[3][3] = Piece(20)
+	problems := g.Validate()
+	if len(problems) != 1 || problems[0].Kind != ProblemInvalidPiece || problems[0].Positions[0].Name() != "d4" {
+		t.Errorf("got the problems %v", problems)
+	}
+}
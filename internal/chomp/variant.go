@@ -0,0 +1,157 @@
+package chomp
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Represents a variant of chess: the rules deciding how games start, which moves are legal and how games end.
+// Standard chess is a variant too, and the others embed it to keep the rules they don't change.
+type Variant interface {
+	// Returns the name of the variant, such as "atomic"
+	Name() string
+	// Returns the starting position of the variant in Forsyth-Edwards Notation
+	StartFEN() string
+	// Returns all legal moves of the player to move
+	LegalMoves(g *Game) []Move
+	// Changes the game after a move has been made on the board and the turn has passed to the other player,
+	// like the explosions of Atomic chess. capture is whether the move captured a piece.
+	// The game's Zobrist key is recomputed afterwards, so the variant may change the board freely.
+	AfterMove(g *Game, m Move, capture bool)
+	// Returns whether the king of the given color is in check
+	InCheck(g *Game, c Color) bool
+	// Returns the outcome the variant's own rules give the game, or OutcomeOngoing if they don't end it.
+	// Checkmate, stalemate and draws are handled by the game unless the variant's rules end it first.
+	Outcome(g *Game) Outcome
+	// Returns whether the game is drawn because neither player can win with the pieces left
+	InsufficientMaterial(g *Game) bool
+	// Returns the field the variant adds to FEN records after the en passant field, or an empty string if it adds none
+	FENField(g *Game) string
+	// Parses the field written by FENField into the game
+	ParseFENField(g *Game, field string) error
+	// Returns whether players hold captured pieces in pockets to drop them, which FEN records write in brackets
+	// after the piece placement
+	HasPockets() bool
+}
+
+// The variants games can be played in.
+var Variants = []Variant{Standard{}, KingOfTheHill{}, ThreeCheck{}, Antichess{}, Atomic{}, Horde{}, Crazyhouse{}, Bughouse{}}
+
+// Returns the variant with the given name. Case, spaces and hyphens don't matter, so "Three-check" is ThreeCheck.
+func VariantByName(name string) (Variant, error) {
+	simple := strings.ToLower(strings.NewReplacer(" ", "", "-", "").Replace(name))
+	for _, v := range Variants {
+		if v.Name() == simple {
+			return v, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown variant '%s'", name)
+}
+
+// Creates a new game of the variant from its starting position.
+func NewVariantGame(v Variant) (*Game, error) {
+	return ParseVariantFEN(v, v.StartFEN())
+}
+
+// Returns the variant of the game, which is standard chess if none is set.
+func (g *Game) variant() Variant {
+	if g.Variant == nil {
+		return Standard{}
+	}
+
+	return g.Variant
+}
+
+// The rules of standard chess.
+type Standard struct{}
+
+func (Standard) Name() string {
+	return "standard"
+}
+
+func (Standard) StartFEN() string {
+	return StartFEN
+}
+
+func (Standard) LegalMoves(g *Game) []Move {
+	return g.Board.legalMoves(g.Turn, g.castlingState(), g.EnPassant)
+}
+
+func (Standard) AfterMove(g *Game, m Move, capture bool) {}
+
+func (Standard) InCheck(g *Game, c Color) bool {
+	return g.Board.InCheck(c)
+}
+
+func (Standard) Outcome(g *Game) Outcome {
+	return OutcomeOngoing
+}
+
+func (Standard) InsufficientMaterial(g *Game) bool {
+	return g.Board.IsInsufficientMaterial()
+}
+
+func (Standard) FENField(g *Game) string {
+	return ""
+}
+
+func (Standard) ParseFENField(g *Game, field string) error {
+	return fmt.Errorf("invalid FEN: unexpected field '%s'", field)
+}
+
+func (Standard) HasPockets() bool {
+	return false
+}
+
+// Returns the moves of the pieces of the given color, without checking whether they leave the king in check.
+// Pawns promote to the given pieces. Castling is not included.
+func (b *Board) pseudoLegalMoves(c Color, ep Position, promotions []PieceType) []Move {
+	bb := b.Bitboards()
+	us := bb.Colors[colorIndex(c)]
+	them := bb.Colors[colorIndex(c.Opposite())]
+	moves := []Move{}
+	for pieces := us; pieces != 0; {
+		sq := pieces.popLowest()
+		p := positionOf(sq)
+		piece := b.Grid[p.X][p.Y]
+		if piece.Type() != PieceTypePawn {
+			for targets := attacksOf(piece.Type(), sq, bb.Occupied) &^ us; targets != 0; {
+				moves = append(moves, NewMove(p, positionOf(targets.popLowest())))
+			}
+
+			continue
+		}
+
+		targets := Bitboard(0)
+		dir := pawnDirection(c)
+		one := p.offset(0, dir)
+		if one.Locate() != LocationOutOfBounds && !bb.Occupied.Has(one) {
+			targets |= BitboardOf(one)
+			two := p.offset(0, 2*dir)
+			if p.Y == backRankOf(c)+dir && !bb.Occupied.Has(two) {
+				targets |= BitboardOf(two)
+			}
+		}
+
+		attacks := pawnAttacks[colorIndex(c)][sq]
+		targets |= attacks & them
+		if attacks.Has(ep) && b.At(NewPos(ep.X, p.Y)) == MakePiece(PieceTypePawn, c.Opposite()) {
+			targets |= BitboardOf(ep)
+		}
+
+		for targets != 0 {
+			to := positionOf(targets.popLowest())
+			if to.Y != 0 && to.Y != 7 {
+				moves = append(moves, NewMove(p, to))
+				continue
+			}
+
+			for _, t := range promotions {
+				moves = append(moves, Move{From: p, To: to, Promotion: t})
+			}
+		}
+	}
+
+	return moves
+}
@@ -0,0 +1,286 @@
+package chomp
+
+import "fmt"
+
+// Returns the outcome of the given color winning.
+func winOutcome(c Color) Outcome {
+	if c == ColorBlack {
+		return OutcomeBlackWins
+	}
+
+	return OutcomeWhiteWins
+}
+
+// Returns whether the kings are the only pieces left on the board.
+func (b *Board) onlyKings() bool {
+	for x := 0; x < 8; x++ {
+		for y := 0; y < 8; y++ {
+			if t := b.Grid[x][y].Type(); t != PieceTypeNone && t != PieceTypeKing {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// The rules of King of the Hill: a player also wins by bringing their king to one of the four squares in the center.
+type KingOfTheHill struct{ Standard }
+
+func (KingOfTheHill) Name() string {
+	return "kingofthehill"
+}
+
+func (KingOfTheHill) Outcome(g *Game) Outcome {
+	for _, c := range []Color{ColorWhite, ColorBlack} {
+		king, ok := g.Board.findKing(c)
+		if ok && king.X >= 3 && king.X <= 4 && king.Y >= 3 && king.Y <= 4 {
+			return winOutcome(c)
+		}
+	}
+
+	return OutcomeOngoing
+}
+
+// A lone king can still walk to the center, so no material is insufficient.
+func (KingOfTheHill) InsufficientMaterial(g *Game) bool {
+	return false
+}
+
+// The number of checks that wins a game of Three-check.
+const threeCheckChecks = 3
+
+// The rules of Three-check: a player also wins by giving check three times.
+// FEN records of Three-check games have a field after the en passant field with the checks each player has left
+// to give, such as 3+3 at the start.
+type ThreeCheck struct{ Standard }
+
+func (ThreeCheck) Name() string {
+	return "threecheck"
+}
+
+func (ThreeCheck) AfterMove(g *Game, m Move, capture bool) {
+	if g.Board.InCheck(g.Turn) {
+		g.Checks[colorIndex(g.Turn.Opposite())]++
+	}
+}
+
+func (ThreeCheck) Outcome(g *Game) Outcome {
+	for _, c := range []Color{ColorWhite, ColorBlack} {
+		if g.Checks[colorIndex(c)] >= threeCheckChecks {
+			return winOutcome(c)
+		}
+	}
+
+	return OutcomeOngoing
+}
+
+// Any piece besides the king can give check, so only bare kings are a draw.
+func (ThreeCheck) InsufficientMaterial(g *Game) bool {
+	return g.Board.onlyKings()
+}
+
+func (ThreeCheck) FENField(g *Game) string {
+	return fmt.Sprintf("%d+%d", threeCheckChecks-g.Checks[0], threeCheckChecks-g.Checks[1])
+}
+
+func (ThreeCheck) ParseFENField(g *Game, field string) error {
+	var white, black int
+	if _, err := fmt.Sscanf(field, "%d+%d", &white, &black); err != nil ||
+		white < 0 || white > threeCheckChecks || black < 0 || black > threeCheckChecks {
+		return fmt.Errorf("invalid FEN: invalid remaining checks '%s'", field)
+	}
+
+	g.Checks = [2]int{threeCheckChecks - white, threeCheckChecks - black}
+	return nil
+}
+
+// The pieces a pawn can promote to in Antichess, where the king is an ordinary piece.
+var antichessPromotions = append([]PieceType{PieceTypeKing}, promotionTypes...)
+
+// The rules of Antichess: a player wins by losing all their pieces or having no legal moves, and must capture
+// whenever they can. The king is an ordinary piece, so there is no check or castling, and pawns may promote to kings.
+type Antichess struct{ Standard }
+
+func (Antichess) Name() string {
+	return "antichess"
+}
+
+func (Antichess) StartFEN() string {
+	return "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w - - 0 1"
+}
+
+func (Antichess) LegalMoves(g *Game) []Move {
+	moves := g.Board.pseudoLegalMoves(g.Turn, g.EnPassant, antichessPromotions)
+	captures := []Move{}
+	for _, m := range moves {
+		if g.IsCapture(m) {
+			captures = append(captures, m)
+		}
+	}
+
+	if len(captures) > 0 {
+		return captures
+	}
+
+	return moves
+}
+
+func (Antichess) InCheck(g *Game, c Color) bool {
+	return false
+}
+
+func (Antichess) Outcome(g *Game) Outcome {
+	if len(g.LegalMoves()) == 0 {
+		return winOutcome(g.Turn)
+	}
+
+	return OutcomeOngoing
+}
+
+func (Antichess) InsufficientMaterial(g *Game) bool {
+	return false
+}
+
+// The rules of Atomic chess: a capture explodes, removing the capturing piece and every piece other than a pawn
+// next to the captured one. Kings can't capture, and a player wins by blowing up the enemy king, even if their own
+// is in check. Kings next to each other can't be in check, since taking the other would blow up both.
+type Atomic struct{ Standard }
+
+func (Atomic) Name() string {
+	return "atomic"
+}
+
+func (Atomic) LegalMoves(g *Game) []Move {
+	c := g.Turn
+	moves := g.Board.pseudoLegalMoves(c, g.EnPassant, promotionTypes)
+	if king, ok := g.Board.findKing(c); ok {
+		bb := g.Board.Bitboards()
+		moves = g.Board.castlingMoves(&bb, moves, king, c, g.castlingState())
+	}
+
+	legal := []Move{}
+	for _, m := range moves {
+		capture := g.IsCapture(m)
+		if capture && g.Board.At(m.From).Type() == PieceTypeKing {
+			continue
+		}
+
+		after := g.Board
+		after.applyMove(m)
+		if capture {
+			after.explode(m.To)
+		}
+
+		if after.atomicKingSafe(c) {
+			legal = append(legal, m)
+		}
+	}
+
+	return legal
+}
+
+func (Atomic) AfterMove(g *Game, m Move, capture bool) {
+	if capture {
+		g.Board.explode(m.To)
+	}
+}
+
+func (Atomic) InCheck(g *Game, c Color) bool {
+	return !g.Board.kingsTouch() && g.Board.InCheck(c)
+}
+
+func (Atomic) Outcome(g *Game) Outcome {
+	for _, c := range []Color{ColorWhite, ColorBlack} {
+		if _, ok := g.Board.findKing(c); !ok {
+			return winOutcome(c.Opposite())
+		}
+	}
+
+	return OutcomeOngoing
+}
+
+// Kings can't capture, so two bare kings can never blow each other up.
+func (Atomic) InsufficientMaterial(g *Game) bool {
+	return g.Board.onlyKings()
+}
+
+// Blows up the piece that captured on the given position along with every piece other than a pawn next to it.
+func (b *Board) explode(p Position) {
+	b.put(p, PieceNone)
+	for dx := int8(-1); dx <= 1; dx++ {
+		for dy := int8(-1); dy <= 1; dy++ {
+			q := p.offset(dx, dy)
+			if q.Locate() != LocationOutOfBounds && b.At(q).Type() != PieceTypePawn {
+				b.put(q, PieceNone)
+			}
+		}
+	}
+}
+
+// Returns whether both kings are on the board next to each other.
+func (b *Board) kingsTouch() bool {
+	white, ok := b.findKing(ColorWhite)
+	black, ok2 := b.findKing(ColorBlack)
+	return ok && ok2 && abs8(white.X-black.X) <= 1 && abs8(white.Y-black.Y) <= 1
+}
+
+// Returns whether the king of the given color has survived and isn't left in check in Atomic chess.
+// A move blowing up the enemy king wins, whatever happens to the king of the player making it.
+func (b *Board) atomicKingSafe(c Color) bool {
+	king, ok := b.findKing(c)
+	if !ok {
+		return false
+	}
+
+	if _, ok := b.findKing(c.Opposite()); !ok {
+		return true
+	}
+
+	return b.kingsTouch() || !b.IsAttacked(king, c.Opposite())
+}
+
+// The rules of Horde: white has 36 pawns and no king against black's usual pieces, and black wins by capturing them all.
+// White's pawns on the first rank may move two squares, like the ones on the second.
+type Horde struct{ Standard }
+
+func (Horde) Name() string {
+	return "horde"
+}
+
+func (Horde) StartFEN() string {
+	return "rnbqkbnr/pppppppp/8/1PP2PP1/PPPPPPPP/PPPPPPPP/PPPPPPPP/PPPPPPPP w kq - 0 1"
+}
+
+func (Horde) LegalMoves(g *Game) []Move {
+	moves := g.Board.legalMoves(g.Turn, g.castlingState(), g.EnPassant)
+	if g.Turn != ColorWhite {
+		return moves
+	}
+
+	for x := int8(0); x < 8; x++ {
+		from, one, two := NewPos(x, 0), NewPos(x, 1), NewPos(x, 2)
+		if g.Board.At(from) == PieceWhitePawn && g.Board.At(one) == PieceNone && g.Board.At(two) == PieceNone {
+			moves = append(moves, NewMove(from, two))
+		}
+	}
+
+	return moves
+}
+
+func (Horde) Outcome(g *Game) Outcome {
+	for x := 0; x < 8; x++ {
+		for y := 0; y < 8; y++ {
+			if g.Board.Grid[x][y].Color() == ColorWhite {
+				return OutcomeOngoing
+			}
+		}
+	}
+
+	return OutcomeBlackWins
+}
+
+// The horde can't be checkmated, and black's pieces can always capture what is left of it.
+func (Horde) InsufficientMaterial(g *Game) bool {
+	return false
+}
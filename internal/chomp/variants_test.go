@@ -0,0 +1,194 @@
+package chomp
+
+import "testing"
+
+func TestVariantOutcomes(t *testing.T) {
+	tests := []struct {
+		variant Variant
+		fen     string
+		moves   []string
+		outcome Outcome
+	}{
+		// the king reaches the center
+		{KingOfTheHill{}, "4k3/8/8/8/8/4K3/8/8 w - - 0 1", []string{"e3e4"}, OutcomeWhiteWins},
+		{KingOfTheHill{}, "8/8/3k4/8/8/8/8/K7 b - - 0 1", []string{"d6d5"}, OutcomeBlackWins},
+		{Standard{}, "4k3/8/8/8/8/4K3/8/8 w - - 0 1", nil, OutcomeDraw},
+		{KingOfTheHill{}, "4k3/8/8/8/8/4K3/8/8 w - - 0 1", nil, OutcomeOngoing},
+		// the third check wins, even when it isn't mate
+		{ThreeCheck{}, "4k3/8/8/8/8/8/8/R3K3 w - - 1+3 0 1", []string{"a1a8"}, OutcomeWhiteWins},
+		{ThreeCheck{}, "4k3/8/8/8/8/8/8/R3K3 w - - 2+3 0 1", []string{"a1a8"}, OutcomeOngoing},
+		{ThreeCheck{}, "4k3/8/8/8/8/8/8/4K3 w - - 3+3 0 1", nil, OutcomeDraw},
+		// losing every piece wins
+		{Antichess{}, "8/8/8/8/8/8/8/1r5R w - - 0 1", []string{"h1b1"}, OutcomeBlackWins},
+		// so does having no moves
+		{Antichess{}, "8/8/8/8/8/p7/P7/8 w - - 0 1", nil, OutcomeWhiteWins},
+		// the explosion takes the king next to the captured queen, though the capturing queen was pinned
+		{Atomic{}, "4k3/4q3/8/8/8/8/4Q3/4K3 w - - 0 1", []string{"e2e7"}, OutcomeWhiteWins},
+		{Atomic{}, "4k3/8/8/8/8/8/8/4K3 w - - 0 1", nil, OutcomeDraw},
+		// black captures the last of the horde
+		{Horde{}, "4k3/8/8/8/8/8/8/Pr6 b - - 0 1", []string{"b1a1"}, OutcomeBlackWins},
+		{Horde{}, "", nil, OutcomeOngoing},
+	}
+
+	for _, test := range tests {
+		fen := test.fen
+		if fen == "" {
+			fen = test.variant.StartFEN()
+		}
+
+		g, err := ParseVariantFEN(test.variant, fen)
+		if err != nil {
+			t.Fatalf("%s %s: %s", test.variant.Name(), fen, err.Error())
+		}
+
+		for _, s := range test.moves {
+			m, err := g.ParseUCI(s)
+			if err != nil {
+				t.Fatalf("%s %s: %s", test.variant.Name(), fen, err.Error())
+			}
+
+			if err := g.Apply(m); err != nil {
+				t.Fatalf("%s %s: %s: %s", test.variant.Name(), fen, s, err.Error())
+			}
+		}
+
+		if g.Outcome() != test.outcome {
+			t.Errorf("%s %s %v: got the outcome %s, want %s", test.variant.Name(), fen, test.moves, g.Outcome(), test.outcome)
+		}
+	}
+}
+
+// Returns the set of the moves in UCI notation.
+func uciMoves(moves []Move) map[string]bool {
+	set := make(map[string]bool)
+	for _, m := range moves {
+		set[m.UCI()] = true
+	}
+
+	return set
+}
+
+func TestVariantMoves(t *testing.T) {
+	// captures are forced in Antichess
+	g, err := ParseVariantFEN(Antichess{}, "rnbqkbnr/p1pppppp/8/1p6/8/4P3/PPPP1PPP/RNBQKBNR w - - 0 2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if moves := uciMoves(g.LegalMoves()); len(moves) != 1 || !moves["f1b5"] {
+		t.Errorf("Antichess allowed %v when a capture was possible", moves)
+	}
+
+	// pawns can promote to kings
+	g, err = ParseVariantFEN(Antichess{}, "8/P7/8/8/8/8/8/7k w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !uciMoves(g.LegalMoves())["a7a8k"] {
+		t.Errorf("Antichess didn't allow promoting to a king")
+	}
+
+	// kings can't capture in Atomic chess
+	g, err = ParseVariantFEN(Atomic{}, "8/8/8/8/8/8/3p4/3K3k w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if uciMoves(g.LegalMoves())["d1d2"] {
+		t.Errorf("an Atomic king captured")
+	}
+
+	// pawns on the first rank may move two squares in Horde
+	g, err = ParseVariantFEN(Horde{}, "4k3/8/8/8/8/8/8/P7 w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if moves := uciMoves(g.LegalMoves()); !moves["a1a2"] || !moves["a1a3"] {
+		t.Errorf("the Horde pawn on a1 can make the moves %v", moves)
+	}
+
+	g, err = NewVariantGame(Horde{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, want := range []uint64{8, 128, 1274, 23310} {
+		if got := g.Perft(i + 1); got != want {
+			t.Errorf("Horde starting position: depth %d: got %d nodes, want %d", i+1, got, want)
+		}
+	}
+}
+
+func TestThreeCheckFEN(t *testing.T) {
+	g, err := ParseVariantFEN(ThreeCheck{}, "4k3/8/8/8/8/8/8/R3K3 w - - 3+3 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, _ := g.ParseUCI("a1a8")
+	if err := g.Apply(m); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "R3k3/8/8/8/8/8/8/4K3 b - - 2+3 1 1"; g.FEN() != want {
+		t.Errorf("got %s, want %s", g.FEN(), want)
+	}
+
+	if err := g.Undo(); err != nil {
+		t.Fatal(err)
+	}
+
+	if g.Checks != [2]int{} {
+		t.Errorf("undoing the check left the checks at %v", g.Checks)
+	}
+
+	if _, err := ParseVariantFEN(ThreeCheck{}, "4k3/8/8/8/8/8/8/R3K3 w - - 4+3 0 1"); err == nil {
+		t.Errorf("more than three checks left were accepted")
+	}
+}
+
+func TestCrazyhouseDrops(t *testing.T) {
+	g, err := ParseVariantFEN(Crazyhouse{}, "4k3/8/8/3p4/4P3/8/8/4K3[] w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, uci := range []string{"e4d5", "e8d7"} {
+		m, err := g.ParseUCI(uci)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := g.Apply(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the captured pawn can be dropped, but not on the last rank
+	if _, err := g.ParseUCI("P@d8"); err == nil {
+		t.Errorf("a pawn was dropped on the last rank")
+	}
+
+	m, err := g.ParseUCI("P@c6")
+	if err != nil || m != NewDrop(PieceTypePawn, NewPos(2, 5)) {
+		t.Fatalf("P@c6 was parsed as %s with the error %v", m.UCI(), err)
+	}
+
+	if san, err := g.SAN(m); err != nil || san != "P@c6+" {
+		t.Errorf("P@c6 was written as %s with the error %v", san, err)
+	}
+
+	if err := g.Apply(m); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "8/3k4/2P5/3P4/8/8/8/4K3[] b - - 0 2"; g.FEN() != want {
+		t.Errorf("got %s, want %s", g.FEN(), want)
+	}
+
+	if _, err := g.ParseUCI("P@e4"); err == nil {
+		t.Errorf("black dropped a pawn it doesn't have")
+	}
+}
@@ -0,0 +1,101 @@
+package chomp
+
+// The Polyglot index of the first random number for each kind of piece, indexed by the piece constants.
+var polyglotPieceOffsets = [12]int{
+	64 * 1, 64 * 7, 64 * 3, 64 * 5, 64 * 9, 64 * 11,
+	64 * 0, 64 * 6, 64 * 2, 64 * 4, 64 * 8, 64 * 10,
+}
+
+const (
+	polyglotCastlingOffset  = 768
+	polyglotEnPassantOffset = 772
+	polyglotTurnOffset      = 780
+)
+
+// Returns the Zobrist key of a piece standing on a position.
+func pieceKey(piece Piece, p Position) uint64 {
+	if piece < 0 || int(piece) >= len(polyglotPieceOffsets) {
+		return 0
+	}
+
+	return polyglotRandom[polyglotPieceOffsets[piece]+int(p.Y)*8+int(p.X)]
+}
+
+// Returns the part of the Zobrist key made up by the castling rights.
+func castlingKey(r CastlingRights) uint64 {
+	var key uint64
+	for i, right := range []CastlingRights{
+		CastlingWhiteKingside, CastlingWhiteQueenside, CastlingBlackKingside, CastlingBlackQueenside,
+	} {
+		if r.Has(right) {
+			key ^= polyglotRandom[polyglotCastlingOffset+i]
+		}
+	}
+
+	return key
+}
+
+// Returns the part of the Zobrist key made up by the en passant position.
+// Like in Polyglot, it only counts if a pawn of the player to move stands next to the pawn that can be
+// captured, whether or not the capture is legal.
+func (g *Game) enPassantKey() uint64 {
+	if g.EnPassant == ErrorPos {
+		return 0
+	}
+
+	pawn := MakePiece(PieceTypePawn, g.Turn)
+	for _, dx := range []int8{-1, 1} {
+		if g.Board.At(g.EnPassant.offset(dx, -pawnDirection(g.Turn))) == pawn {
+			return polyglotRandom[polyglotEnPassantOffset+int(g.EnPassant.X)]
+		}
+	}
+
+	return 0
+}
+
+// Returns the part of the Zobrist key made up by the player to move.
+func turnKey(c Color) uint64 {
+	if c == ColorWhite {
+		return polyglotRandom[polyglotTurnOffset]
+	}
+
+	return 0
+}
+
+// Returns the part of the Zobrist key made up by the pieces in the pockets of Crazyhouse and Bughouse.
+// Polyglot has no numbers for pockets, so they are made up by mixing the color, piece type and count
+// the way SplitMix64 does. Empty pockets add nothing, which keeps the keys of other games the same.
+func pocketsKey(pockets [2]Pocket) uint64 {
+	var key uint64
+	for ci, pocket := range pockets {
+		for t, count := range pocket {
+			for n := 1; n <= count; n++ {
+				z := uint64((ci*len(pocket)+t)*64+n) * 0x9e3779b97f4a7c15
+				z = (z ^ z>>30) * 0xbf58476d1ce4e5b9
+				z = (z ^ z>>27) * 0x94d049bb133111eb
+				key ^= z ^ z>>31
+			}
+		}
+	}
+
+	return key
+}
+
+// Calculates the Zobrist key of the position from scratch.
+func (g *Game) computeHash() uint64 {
+	var key uint64
+	for x := int8(0); x < 8; x++ {
+		for y := int8(0); y < 8; y++ {
+			key ^= pieceKey(g.Board.Grid[x][y], NewPos(x, y))
+		}
+	}
+
+	return key ^ castlingKey(g.Castling) ^ g.enPassantKey() ^ turnKey(g.Turn) ^ pocketsKey(g.Board.Pockets)
+}
+
+// Returns the Zobrist key of the current position. The key is the same one Polyglot opening books use,
+// so it can be used to look positions up in them. It covers the pieces, the player to move,
+// the castling rights and the en passant file, and the pockets in Crazyhouse and Bughouse.
+func (g *Game) Hash() uint64 {
+	return g.hash
+}
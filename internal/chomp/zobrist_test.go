@@ -0,0 +1,103 @@
+package chomp
+
+import (
+	"strings"
+	"testing"
+)
+
+// Applies moves given in UCI notation, separated by spaces.
+func playUCI(t *testing.T, g *Game, moves string) {
+	t.Helper()
+	for _, uci := range strings.Fields(moves) {
+		m, err := g.ParseUCI(uci)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := g.Apply(m); err != nil {
+			t.Fatalf("%s: %s", uci, err.Error())
+		}
+	}
+}
+
+// The reference keys from the Polyglot book format specification.
+var polyglotKeys = []struct {
+	moves string
+	key   uint64
+}{
+	{"", 0x463b96181691fc9c},
+	{"e2e4", 0x823c9b50fd114196},
+	{"e2e4 d7d5", 0x0756b94461c50fb0},
+	{"e2e4 d7d5 e4e5", 0x662fafb965db29d4},
+	{"e2e4 d7d5 e4e5 f7f5", 0x22a48b5a8e47ff78},
+	{"e2e4 d7d5 e4e5 f7f5 e1e2", 0x652a607ca3f242c1},
+	{"e2e4 d7d5 e4e5 f7f5 e1e2 e8f7", 0x00fdd303c946bdd9},
+	{"a2a4 b7b5 h2h4 b5b4 c2c4", 0x3c8123ea7b067637},
+	{"a2a4 b7b5 h2h4 b5b4 c2c4 b4c3 a1a3", 0x5c3f9b829b279560},
+}
+
+func TestPolyglotKeys(t *testing.T) {
+	for _, test := range polyglotKeys {
+		g := NewGame()
+		playUCI(t, g, test.moves)
+		if g.Hash() != test.key {
+			t.Errorf("%q: got the key %016x, want %016x", test.moves, g.Hash(), test.key)
+		}
+
+		if g.computeHash() != g.Hash() {
+			t.Errorf("%q: the key was updated to %016x, but is %016x from scratch", test.moves, g.Hash(), g.computeHash())
+		}
+
+		// the same position read from FEN has the same key
+		if parsed := mustParseFEN(t, g.FEN()); parsed.Hash() != test.key {
+			t.Errorf("%q: %s has the key %016x", test.moves, g.FEN(), parsed.Hash())
+		}
+	}
+}
+
+func TestHashUndo(t *testing.T) {
+	g := mustParseFEN(t, "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+	before := g.Hash()
+	for _, m := range g.LegalMoves() {
+		if err := g.Apply(m); err != nil {
+			t.Fatal(err)
+		}
+
+		if g.Hash() != g.computeHash() {
+			t.Errorf("%s: the key was updated to %016x, but is %016x from scratch", m.UCI(), g.Hash(), g.computeHash())
+		}
+
+		if err := g.Undo(); err != nil {
+			t.Fatal(err)
+		}
+
+		if g.Hash() != before {
+			t.Errorf("%s: undoing the move left the key at %016x, want %016x", m.UCI(), g.Hash(), before)
+		}
+	}
+}
+
+func TestHashTranspositions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		same bool
+	}{
+		{"g1f3 g8f6 b1c3", "b1c3 g8f6 g1f3", true},
+		{"e2e4 e7e5 g1f3", "g1f3 e7e5 e2e4", true},
+		// the en passant file only counts when a pawn could take
+		{"e2e4 g8f6 e4e5 d7d5", "e2e4 g8f6 e4e5 f6g8 g1f3 d7d5 f3g1 g8f6", false},
+		{"e2e4 d7d5", "e2e3 d7d6 e3e4 d6d5", true},
+		// castling rights lost by moving the king back and forth
+		{"e2e4 e7e5 e1e2 e8e7 e2e1 e7e8", "e2e4 e7e5", false},
+		{"g1f3 g8f6 f3g1 f6g8", "", true},
+	}
+
+	for _, test := range tests {
+		a, b := NewGame(), NewGame()
+		playUCI(t, a, test.a)
+		playUCI(t, b, test.b)
+		if (a.Hash() == b.Hash()) != test.same {
+			t.Errorf("%q and %q: got the keys %016x and %016x", test.a, test.b, a.Hash(), b.Hash())
+		}
+	}
+}
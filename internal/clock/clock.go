@@ -0,0 +1,243 @@
+// Package clock keeps the time of chess games played with a time control.
+package clock
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/apachejuice/chomp/internal/chomp"
+)
+
+// A source of time that never goes backwards, which tests can replace with one they control.
+type Source interface {
+	// Returns how much time has passed since some point in the past that doesn't change.
+	Now() time.Duration
+}
+
+type systemSource struct {
+	start time.Time
+}
+
+// Readings of time.Now carry the monotonic clock of the system, which is what time.Since measures with.
+func (s systemSource) Now() time.Duration {
+	return time.Since(s.start)
+}
+
+// The monotonic clock of the system.
+var System Source = systemSource{start: time.Now()}
+
+// A source of time that only moves when it's told to. It is safe for concurrent use.
+type ManualSource struct {
+	now time.Duration
+	mu  sync.Mutex
+}
+
+func (s *ManualSource) Now() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.now
+}
+
+// Moves the time forward by d.
+func (s *ManualSource) Advance(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if d > 0 {
+		s.now += d
+	}
+}
+
+var (
+	ErrFlagFell   = fmt.Errorf("the flag fell: the player ran out of time")
+	errNotRunning = fmt.Errorf("it is not the player's clock that is running")
+)
+
+// The clocks of both players of a game. Only the clock of the player to move runs, and a move stops it
+// and starts the other one. A clock is not safe for concurrent use.
+type Clock struct {
+	// The time control the game is played with
+	Control Control
+
+	source Source
+	// The time each player had left when their clock last stopped, indexed by the color index
+	left [2]time.Duration
+	// The stage of the time control each player is in, and how many moves they have made in it
+	stage, moves [2]int
+	// The color whose clock is running, or ColorNone if neither is
+	running chomp.Color
+	// When the running clock started
+	since time.Duration
+	// The color that ran out of time, or ColorNone
+	flagged chomp.Color
+}
+
+// Returns the index of a color in the arrays of a clock.
+func side(c chomp.Color) int {
+	if c == chomp.ColorBlack {
+		return 1
+	}
+
+	return 0
+}
+
+// Creates the clocks of a game with the given time control, which tell time with the given source,
+// or the System one if it is nil. Both players start with the time of the first stage, and neither clock runs.
+func New(control Control, source Source) (*Clock, error) {
+	if len(control) == 0 {
+		return nil, errEmptyControl
+	}
+
+	if source == nil {
+		source = System
+	}
+
+	c := &Clock{Control: control, source: source, running: chomp.ColorNone, flagged: chomp.ColorNone}
+	c.left[0], c.left[1] = control[0].Time, control[0].Time
+	return c, nil
+}
+
+// Returns how much time the player has left at the given time, which is never less than 0.
+func (c *Clock) leftAt(color chomp.Color, now time.Duration) time.Duration {
+	left := c.left[side(color)]
+	if color == c.running {
+		left -= c.charged(color, now-c.since)
+	}
+
+	if left < 0 {
+		return 0
+	}
+
+	return left
+}
+
+// Returns how much of the time the player has spent on a move comes off their clock.
+// Under a simple delay, the clock doesn't count down until the delay has passed.
+func (c *Clock) charged(color chomp.Color, spent time.Duration) time.Duration {
+	stage := c.Control[c.stage[side(color)]]
+	if stage.Mode == ModeDelay {
+		spent -= stage.Bonus
+		if spent < 0 {
+			return 0
+		}
+	}
+
+	return spent
+}
+
+// Returns how much time the player has left.
+func (c *Clock) Remaining(color chomp.Color) time.Duration {
+	return c.leftAt(color, c.source.Now())
+}
+
+// Returns the color whose clock is running, or ColorNone if neither is.
+func (c *Clock) Running() chomp.Color {
+	return c.running
+}
+
+// Returns the color of the player who ran out of time, or ColorNone if neither has.
+func (c *Clock) Flagged() chomp.Color {
+	if c.flagged == chomp.ColorNone && c.running != chomp.ColorNone && c.Remaining(c.running) == 0 {
+		return c.running
+	}
+
+	return c.flagged
+}
+
+// Returns the stage of the time control the player is in.
+func (c *Clock) Stage(color chomp.Color) Stage {
+	return c.Control[c.stage[side(color)]]
+}
+
+// Returns how many moves the player has to make to reach the next stage of the time control,
+// or 0 if they are in the last stage and it lasts for the rest of the game.
+func (c *Clock) MovesToGo(color chomp.Color) int {
+	i := side(color)
+	if c.Control[c.stage[i]].Moves == 0 {
+		return 0
+	}
+
+	return c.Control[c.stage[i]].Moves - c.moves[i]
+}
+
+// Starts the clock of the player, if neither clock is running and no flag has fallen.
+func (c *Clock) Start(color chomp.Color) {
+	if c.running != chomp.ColorNone || c.flagged != chomp.ColorNone {
+		return
+	}
+
+	c.running = color
+	c.since = c.source.Now()
+}
+
+// Stops the running clock, taking the time spent since it started off it without giving any bonus.
+func (c *Clock) Stop() {
+	if c.running == chomp.ColorNone {
+		return
+	}
+
+	c.stopAt(c.source.Now())
+}
+
+// Stops the running clock at the given time.
+func (c *Clock) stopAt(now time.Duration) {
+	c.left[side(c.running)] = c.leftAt(c.running, now)
+	if c.left[side(c.running)] == 0 {
+		c.flagged = c.running
+	}
+
+	c.running = chomp.ColorNone
+}
+
+// Makes a move for the player whose clock is running: calls apply, which makes the move on the board,
+// then stops the player's clock and starts the other one. The time is read once, so the move is made at the same
+// moment its time is charged. If the player's time has run out, the move is not made and ErrFlagFell is returned.
+// If apply returns an error, the clocks are left as they were. Apply may be nil.
+func (c *Clock) Move(color chomp.Color, apply func() error) error {
+	now := c.source.Now()
+	if c.flagged == color {
+		return ErrFlagFell
+	}
+
+	if c.running != color {
+		return errNotRunning
+	}
+
+	if c.leftAt(color, now) == 0 {
+		c.stopAt(now)
+		return ErrFlagFell
+	}
+
+	if apply != nil {
+		if err := apply(); err != nil {
+			return err
+		}
+	}
+
+	i := side(color)
+	spent := now - c.since
+	stage := c.Control[c.stage[i]]
+	c.left[i] = c.leftAt(color, now)
+	switch stage.Mode {
+	case ModeIncrement:
+		c.left[i] += stage.Bonus
+	case ModeBronstein:
+		if spent < stage.Bonus {
+			c.left[i] += spent
+		} else {
+			c.left[i] += stage.Bonus
+		}
+	}
+
+	c.moves[i]++
+	if next, reached := c.Control.after(c.stage[i], c.moves[i]); reached {
+		c.stage[i], c.moves[i] = next, 0
+		c.left[i] += c.Control[next].Time
+	}
+
+	c.running = color.Opposite()
+	c.since = now
+	return nil
+}
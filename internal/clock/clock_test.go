@@ -0,0 +1,211 @@
+package clock
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apachejuice/chomp/internal/chomp"
+)
+
+// Creates the clocks of a game with the time control in the notation of ParseControl, with white's clock running.
+func startClock(t *testing.T, control string) (*Clock, *ManualSource) {
+	t.Helper()
+
+	c, err := ParseControl(control)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	source := &ManualSource{}
+	clock, err := New(c, source)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clock.Start(chomp.ColorWhite)
+	return clock, source
+}
+
+// Makes a move for the player after they have thought for the given time.
+func move(t *testing.T, c *Clock, source *ManualSource, color chomp.Color, spent time.Duration) {
+	t.Helper()
+
+	source.Advance(spent)
+	if err := c.Move(color, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIncrement(t *testing.T) {
+	c, source := startClock(t, "5+3")
+	move(t, c, source, chomp.ColorWhite, 10*time.Second)
+	if want := 5*time.Minute - 7*time.Second; c.Remaining(chomp.ColorWhite) != want {
+		t.Errorf("white has %s left, want %s", c.Remaining(chomp.ColorWhite), want)
+	}
+
+	// the increment is added even to a move made at once
+	move(t, c, source, chomp.ColorBlack, 0)
+	if want := 5*time.Minute + 3*time.Second; c.Remaining(chomp.ColorBlack) != want {
+		t.Errorf("black has %s left, want %s", c.Remaining(chomp.ColorBlack), want)
+	}
+
+	if c.Running() != chomp.ColorWhite {
+		t.Errorf("the clock of %s is running after black moved", c.Running())
+	}
+}
+
+func TestBronsteinDelay(t *testing.T) {
+	c, source := startClock(t, "5b3")
+
+	// the time spent is given back, but never more than the delay
+	move(t, c, source, chomp.ColorWhite, 2*time.Second)
+	if want := 5 * time.Minute; c.Remaining(chomp.ColorWhite) != want {
+		t.Errorf("white has %s left after a quick move, want %s", c.Remaining(chomp.ColorWhite), want)
+	}
+
+	move(t, c, source, chomp.ColorBlack, 10*time.Second)
+	if want := 5*time.Minute - 7*time.Second; c.Remaining(chomp.ColorBlack) != want {
+		t.Errorf("black has %s left after a slow move, want %s", c.Remaining(chomp.ColorBlack), want)
+	}
+}
+
+func TestSimpleDelay(t *testing.T) {
+	c, source := startClock(t, "5d3")
+
+	// the clock waits for the delay before counting down
+	source.Advance(2 * time.Second)
+	if want := 5 * time.Minute; c.Remaining(chomp.ColorWhite) != want {
+		t.Errorf("white has %s left during the delay, want %s", c.Remaining(chomp.ColorWhite), want)
+	}
+
+	source.Advance(3 * time.Second)
+	if want := 5*time.Minute - 2*time.Second; c.Remaining(chomp.ColorWhite) != want {
+		t.Errorf("white has %s left after the delay, want %s", c.Remaining(chomp.ColorWhite), want)
+	}
+
+	// nothing is added after the move
+	move(t, c, source, chomp.ColorWhite, 0)
+	if want := 5*time.Minute - 2*time.Second; c.Remaining(chomp.ColorWhite) != want {
+		t.Errorf("white has %s left after moving, want %s", c.Remaining(chomp.ColorWhite), want)
+	}
+}
+
+func TestStages(t *testing.T) {
+	c, source := startClock(t, "40/90+30:30+30")
+	for i := 0; i < 39; i++ {
+		move(t, c, source, chomp.ColorWhite, 10*time.Second)
+		move(t, c, source, chomp.ColorBlack, time.Minute)
+	}
+
+	if c.MovesToGo(chomp.ColorWhite) != 1 || c.Stage(chomp.ColorWhite) != c.Control[0] {
+		t.Errorf("white has %d moves to go in the stage %s, want 1 in the first one", c.MovesToGo(chomp.ColorWhite), c.Stage(chomp.ColorWhite))
+	}
+
+	// the 40th move reaches the second stage, which adds its time
+	move(t, c, source, chomp.ColorWhite, 10*time.Second)
+	if want := 90*time.Minute - 40*10*time.Second + 40*30*time.Second + 30*time.Minute; c.Remaining(chomp.ColorWhite) != want {
+		t.Errorf("white has %s left in the second stage, want %s", c.Remaining(chomp.ColorWhite), want)
+	}
+
+	if c.MovesToGo(chomp.ColorWhite) != 0 || c.Stage(chomp.ColorWhite) != c.Control[1] {
+		t.Errorf("white has %d moves to go in the stage %s, want the last one", c.MovesToGo(chomp.ColorWhite), c.Stage(chomp.ColorWhite))
+	}
+
+	// black is still in the first stage
+	if c.MovesToGo(chomp.ColorBlack) != 1 || c.Stage(chomp.ColorBlack) != c.Control[0] {
+		t.Errorf("black has %d moves to go in the stage %s, want 1 in the first one", c.MovesToGo(chomp.ColorBlack), c.Stage(chomp.ColorBlack))
+	}
+}
+
+func TestFlagFell(t *testing.T) {
+	c, source := startClock(t, "1")
+	source.Advance(61 * time.Second)
+	if c.Flagged() != chomp.ColorWhite {
+		t.Errorf("%s has run out of time, want white", c.Flagged())
+	}
+
+	applied := false
+	err := c.Move(chomp.ColorWhite, func() error {
+		applied = true
+		return nil
+	})
+
+	if err != ErrFlagFell || applied {
+		t.Errorf("moving after the flag fell gave the error %v, and the move was made: %t", err, applied)
+	}
+
+	if c.Remaining(chomp.ColorWhite) != 0 || c.Running() != chomp.ColorNone {
+		t.Errorf("white has %s left and the clock of %s is running", c.Remaining(chomp.ColorWhite), c.Running())
+	}
+
+	if err := c.Move(chomp.ColorWhite, nil); err != ErrFlagFell {
+		t.Errorf("moving again gave the error %v, want %v", err, ErrFlagFell)
+	}
+}
+
+func TestMoveNotRunning(t *testing.T) {
+	c, source := startClock(t, "5")
+	source.Advance(time.Second)
+	if err := c.Move(chomp.ColorBlack, nil); err != errNotRunning {
+		t.Errorf("black moved on white's time with the error %v", err)
+	}
+
+	// a move the board refuses leaves the clocks alone
+	refused := fmt.Errorf("illegal move")
+	if err := c.Move(chomp.ColorWhite, func() error { return refused }); err != refused {
+		t.Errorf("got the error %v, want %v", err, refused)
+	}
+
+	if c.Running() != chomp.ColorWhite || c.Remaining(chomp.ColorWhite) != 5*time.Minute-time.Second {
+		t.Errorf("the refused move changed the clocks")
+	}
+}
+
+func TestParseControl(t *testing.T) {
+	c, err := ParseControl("40/90+30:30+30")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := Control{
+		{Moves: 40, Time: 90 * time.Minute, Bonus: 30 * time.Second, Mode: ModeIncrement},
+		{Time: 30 * time.Minute, Bonus: 30 * time.Second, Mode: ModeIncrement},
+	}
+
+	if len(c) != len(want) || c[0] != want[0] || c[1] != want[1] {
+		t.Errorf("got %+v, want %+v", c, want)
+	}
+
+	if c.String() != "40/90+30:30+30" {
+		t.Errorf("the control is written as %s", c.String())
+	}
+
+	if c, err := ParseControl("0.5d2.5"); err != nil || c[0] != (Stage{Time: 30 * time.Second, Bonus: 2500 * time.Millisecond, Mode: ModeDelay}) {
+		t.Errorf("got %+v, %v", c, err)
+	}
+
+	tests := []struct {
+		control string
+		// a part of the error
+		err string
+	}{
+		{"", "at least one stage"},
+		{"90:30", "only the last stage"},
+		{"40/90:30:30", "only the last stage"},
+		{"40/0", "the stage gives no time"},
+		{"40/90:20/0+0", "the stage gives no time"},
+		{"0+3", "the first stage"},
+		{"0/5", "the number of moves"},
+		{"5+", "the bonus"},
+		{"5x3", "the time"},
+		{"-5", "the time"},
+	}
+
+	for _, test := range tests {
+		if _, err := ParseControl(test.control); err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("the time control '%s' gave the error %v, want one about %s", test.control, err, test.err)
+		}
+	}
+}
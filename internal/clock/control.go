@@ -0,0 +1,190 @@
+package clock
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// How the bonus time of a stage of a time control is given.
+type Mode int8
+
+const (
+	// The bonus is added to the clock after every move (Fischer increment)
+	ModeIncrement = 0
+	// The time a move took is given back after it, up to the bonus (Bronstein delay)
+	ModeBronstein = 1
+	// The clock only starts counting down once the bonus has passed on every move (simple or US delay)
+	ModeDelay = 2
+)
+
+// The letters that separate the time of a stage from its bonus in the notation of time controls, by mode.
+var modeLetters = map[Mode]byte{
+	ModeIncrement: '+',
+	ModeBronstein: 'b',
+	ModeDelay:     'd',
+}
+
+var modeNames = map[Mode]string{
+	ModeIncrement: "increment",
+	ModeBronstein: "Bronstein delay",
+	ModeDelay:     "delay",
+}
+
+func (m Mode) String() string {
+	name, ok := modeNames[m]
+	if !ok {
+		return "<invalid>"
+	}
+
+	return name
+}
+
+// A stage of a time control: a number of moves to be made in a time.
+type Stage struct {
+	// How many moves each player must make in the stage, or 0 if it lasts for the rest of the game
+	Moves int
+	// The time added to each player's clock when they start the stage
+	Time time.Duration
+	// The bonus time each player gets on every move of the stage, given as the mode says
+	Bonus time.Duration
+	// How the bonus is given
+	Mode Mode
+}
+
+// Returns the stage in the notation of ParseControl.
+func (s Stage) String() string {
+	str := formatDuration(s.Time, time.Minute)
+	if s.Moves > 0 {
+		str = strconv.Itoa(s.Moves) + "/" + str
+	}
+
+	if s.Bonus > 0 {
+		str += string(modeLetters[s.Mode]) + formatDuration(s.Bonus, time.Second)
+	}
+
+	return str
+}
+
+// Returns the duration as a number of the given units, with as many decimals as it needs.
+func formatDuration(d, unit time.Duration) string {
+	return strconv.FormatFloat(float64(d)/float64(unit), 'f', -1, 64)
+}
+
+// A time control: the stages of a game, in the order they are played. Once a player has made all moves
+// of a stage, they move on to the next one. If the last stage has a number of moves, it is played again and again.
+type Control []Stage
+
+// Returns the time control in the notation of ParseControl.
+func (c Control) String() string {
+	stages := make([]string, len(c))
+	for i, s := range c {
+		stages[i] = s.String()
+	}
+
+	return strings.Join(stages, ":")
+}
+
+// Returns the stage a player is in after making the given number of moves of the stage they were in,
+// which is the stage with the given index, and whether they have reached a new stage by doing so.
+func (c Control) after(stage, moves int) (int, bool) {
+	if c[stage].Moves == 0 || moves < c[stage].Moves {
+		return stage, false
+	}
+
+	if stage+1 < len(c) {
+		return stage + 1, true
+	}
+
+	return stage, true
+}
+
+var errEmptyControl = fmt.Errorf("a time control needs at least one stage")
+
+// Parses a time control written as its stages separated by colons, such as 40/90+30:30+30.
+// Each stage is written as the number of moves in it followed by a slash, which is left out for the last stage
+// if it lasts for the rest of the game, then the time for it in minutes, and then optionally the bonus time
+// in seconds preceded by + for an increment, b for a Bronstein delay or d for a simple delay.
+// So 40/90+30:30+30 gives 90 minutes for the first 40 moves and 30 more for the rest of the game,
+// with 30 seconds added on every move, and 5d3 gives 5 minutes for the game with a delay of 3 seconds.
+func ParseControl(s string) (Control, error) {
+	if s == "" {
+		return nil, errEmptyControl
+	}
+
+	c := Control{}
+	for _, str := range strings.Split(s, ":") {
+		stage, err := parseStage(str)
+		if err != nil {
+			return nil, fmt.Errorf("invalid time control stage '%s': %s", str, err.Error())
+		}
+
+		c = append(c, stage)
+	}
+
+	if c[0].Time == 0 {
+		return nil, fmt.Errorf("the first stage of a time control must give some time")
+	}
+
+	for _, stage := range c[:len(c)-1] {
+		if stage.Moves == 0 {
+			return nil, fmt.Errorf("only the last stage of a time control can last for the rest of the game")
+		}
+	}
+
+	return c, nil
+}
+
+// Parses a stage of a time control, written as described by ParseControl.
+func parseStage(s string) (Stage, error) {
+	stage := Stage{}
+	if i := strings.IndexByte(s, '/'); i >= 0 {
+		moves, err := strconv.Atoi(s[:i])
+		if err != nil || moves < 1 {
+			return stage, fmt.Errorf("the number of moves must be a positive number")
+		}
+
+		stage.Moves = moves
+		s = s[i+1:]
+	}
+
+	if i := strings.IndexAny(s, "+bd"); i >= 0 {
+		for mode, letter := range modeLetters {
+			if s[i] == letter {
+				stage.Mode = mode
+			}
+		}
+
+		bonus, err := parseDuration(s[i+1:], time.Second)
+		if err != nil {
+			return stage, fmt.Errorf("the bonus must be a number of seconds")
+		}
+
+		stage.Bonus = bonus
+		s = s[:i]
+	}
+
+	t, err := parseDuration(s, time.Minute)
+	if err != nil {
+		return stage, fmt.Errorf("the time must be a number of minutes")
+	}
+
+	stage.Time = t
+	if stage.Time == 0 && stage.Bonus == 0 {
+		return stage, fmt.Errorf("the stage gives no time")
+	}
+
+	return stage, nil
+}
+
+// Parses a number of the given units, which may have decimals but can't be negative.
+func parseDuration(s string, unit time.Duration) (time.Duration, error) {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || !(f >= 0 && f <= float64(math.MaxInt64)/float64(unit)) {
+		return 0, fmt.Errorf("invalid duration '%s'", s)
+	}
+
+	return time.Duration(f * float64(unit)), nil
+}
@@ -0,0 +1,195 @@
+// Package engine implements a chess engine that plays on top of the chomp package.
+// It searches with alpha-beta and quiescence search, deepening iteratively until it runs out of time,
+// and can be weakened to one of several strength levels.
+package engine
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/apachejuice/chomp/internal/book"
+	"github.com/apachejuice/chomp/internal/chomp"
+	"github.com/apachejuice/chomp/internal/syzygy"
+)
+
+// Represents how strong the engine plays.
+type Level struct {
+	// The deepest the engine searches, or 0 for no limit
+	Depth int `json:"depth"`
+	// The most positions the engine searches per move, or 0 for no limit
+	Nodes uint64 `json:"nodes"`
+	// How many centipawns the engine may misjudge a move by. Before picking a move, a random amount
+	// between 0 and this is added to the score of each one, so weaker levels play worse moves now and then.
+	Error int `json:"error"`
+}
+
+// The strength levels of the engine, from the weakest to full strength. Level 1 is the first one.
+var Levels = []Level{
+	{Depth: 1, Nodes: 500, Error: 400},
+	{Depth: 2, Nodes: 2000, Error: 250},
+	{Depth: 3, Nodes: 10000, Error: 150},
+	{Depth: 4, Nodes: 40000, Error: 80},
+	{Depth: 5, Nodes: 150000, Error: 40},
+	{Depth: 6, Nodes: 500000, Error: 20},
+	{Depth: 8, Nodes: 2000000, Error: 0},
+	{Depth: 0, Nodes: 0, Error: 0},
+}
+
+// Returns the strength level with the given number, starting from 1.
+func GetLevel(n int) (Level, error) {
+	if n < 1 || n > len(Levels) {
+		return Level{}, fmt.Errorf("strength level must be between 1 and %d, got %d", len(Levels), n)
+	}
+
+	return Levels[n-1], nil
+}
+
+// Limits how long a single search may take. Zero fields mean no limit.
+// If no limits are given at all, the search runs until Stop is called.
+type Limits struct {
+	// The deepest to search
+	Depth int
+	// The most positions to search
+	Nodes uint64
+	// Exactly how long to search
+	MoveTime time.Duration
+	// The time white and black have left on their clocks
+	WhiteTime, BlackTime time.Duration
+	// How much time white and black get back after each move
+	WhiteIncrement, BlackIncrement time.Duration
+	// How many moves are left until the next time control, or 0 if the rest of the game must be played in the time left
+	MovesToGo int
+	// Only these moves are considered, if any are given
+	Moves []chomp.Move
+}
+
+// Represents the score of a position in centipawns from the point of view of the player to move.
+// Scores close to ScoreMate mean that a player can force checkmate.
+type Score int
+
+const (
+	// The score of checkmating right now. Mating in more moves scores a bit less.
+	ScoreMate = Score(30000)
+	// Higher than any score a position can have
+	scoreInfinite = ScoreMate + 1
+	// Scores at least this high are forced mates
+	scoreMateBound = ScoreMate - maxPly
+	// The score of positions the tablebase says are won, higher than any evaluation but lower than mates
+	scoreTablebaseWin = scoreMateBound - 1
+)
+
+// Returns whether the score means a player can force checkmate.
+func (s Score) IsMate() bool {
+	return s >= scoreMateBound || s <= -scoreMateBound
+}
+
+// Returns how many moves it takes to checkmate, counting only the moves of the winning player.
+// The number is negative if the player to move is getting mated. Returns 0 if the score is not a mate.
+func (s Score) MateIn() int {
+	switch {
+	case s >= scoreMateBound:
+		return int(ScoreMate-s+1) / 2
+	case s <= -scoreMateBound:
+		return -int(ScoreMate+s) / 2
+	}
+
+	return 0
+}
+
+func (s Score) String() string {
+	if s.IsMate() {
+		return fmt.Sprintf("mate %d", s.MateIn())
+	}
+
+	return fmt.Sprintf("cp %d", int(s))
+}
+
+// Describes the progress of a search after each depth has been searched completely.
+type Info struct {
+	// How deep the search went
+	Depth int `json:"depth"`
+	// The score of the best move
+	Score Score `json:"score"`
+	// How many positions have been searched in total
+	Nodes uint64 `json:"nodes"`
+	// How long the search has been running
+	Time time.Duration `json:"time"`
+	// The principal variation: the best move and the moves both players are expected to answer with
+	PV []chomp.Move `json:"pv"`
+}
+
+// An engine that searches for the best move in a game. One engine can only search one position at a time,
+// but it keeps what it has learned between searches, so it's best to use the same one for a whole game.
+type Engine struct {
+	// Called after each depth of a search, if set
+	OnInfo func(Info)
+	// The opening book to play from, if set. While it has moves for the position, the engine plays them without searching.
+	Book *book.Book
+	// The endgame tablebase to play from, if set. At full strength, the engine plays the tablebase's best move
+	// in positions it has without searching.
+	Tablebase *syzygy.Tablebase
+	// The weights to evaluate positions with, or nil for DefaultWeights
+	Weights *Weights
+
+	level   Level
+	rand    *rand.Rand
+	tt      transpositionTable
+	stopped int32
+	mu      sync.Mutex
+
+	// state of the search that is running
+	game      *chomp.Game
+	limits    Limits
+	start     time.Time
+	soft      time.Duration
+	hard      time.Duration
+	deadline  time.Time
+	depth     int
+	nodes     uint64
+	killers   [maxPly][2]chomp.Move
+	history   [12][64]int
+	pv        [maxPly][maxPly]chomp.Move
+	pvLength  [maxPly]int
+	rootScore Score
+	scores    map[chomp.Move]Score
+}
+
+// Creates a new engine that plays at the given strength level.
+func New(level Level) *Engine {
+	return &Engine{
+		level: level,
+		rand:  rand.New(rand.NewSource(time.Now().UnixNano())),
+		tt:    newTranspositionTable(),
+	}
+}
+
+// Returns the strength level of the engine.
+func (e *Engine) Level() Level {
+	return e.level
+}
+
+// Returns the weights the engine evaluates positions with.
+func (e *Engine) weights() *Weights {
+	if e.Weights == nil {
+		return &DefaultWeights
+	}
+
+	return e.Weights
+}
+
+// Stops the search that is running as soon as possible. The search still returns the best move it has found.
+func (e *Engine) Stop() {
+	atomic.StoreInt32(&e.stopped, 1)
+}
+
+// Forgets everything learned in earlier searches. Call this before searching a position from a different game.
+func (e *Engine) Clear() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.tt.clear()
+	e.history = [12][64]int{}
+}
@@ -0,0 +1,423 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/apachejuice/chomp/internal/chomp"
+)
+
+// The value of each type of piece in centipawns, indexed by the piece type constants.
+// The king is priceless, so it counts for nothing.
+var pieceValues = [6]int{100, 500, 320, 330, 900, 0}
+
+// Piece-square tables giving a bonus or penalty to each type of piece on each square,
+// indexed by the piece type constants. The tables are from white's point of view and written
+// the way a board is drawn, so the first row is the 8th rank.
+var pieceSquareTables = [6][8][8]int{
+	// pawn
+	{
+		{0, 0, 0, 0, 0, 0, 0, 0},
+		{50, 50, 50, 50, 50, 50, 50, 50},
+		{10, 10, 20, 30, 30, 20, 10, 10},
+		{5, 5, 10, 25, 25, 10, 5, 5},
+		{0, 0, 0, 20, 20, 0, 0, 0},
+		{5, -5, -10, 0, 0, -10, -5, 5},
+		{5, 10, 10, -20, -20, 10, 10, 5},
+		{0, 0, 0, 0, 0, 0, 0, 0},
+	},
+	// rook
+	{
+		{0, 0, 0, 0, 0, 0, 0, 0},
+		{5, 10, 10, 10, 10, 10, 10, 5},
+		{-5, 0, 0, 0, 0, 0, 0, -5},
+		{-5, 0, 0, 0, 0, 0, 0, -5},
+		{-5, 0, 0, 0, 0, 0, 0, -5},
+		{-5, 0, 0, 0, 0, 0, 0, -5},
+		{-5, 0, 0, 0, 0, 0, 0, -5},
+		{0, 0, 0, 5, 5, 0, 0, 0},
+	},
+	// knight
+	{
+		{-50, -40, -30, -30, -30, -30, -40, -50},
+		{-40, -20, 0, 0, 0, 0, -20, -40},
+		{-30, 0, 10, 15, 15, 10, 0, -30},
+		{-30, 5, 15, 20, 20, 15, 5, -30},
+		{-30, 0, 15, 20, 20, 15, 0, -30},
+		{-30, 5, 10, 15, 15, 10, 5, -30},
+		{-40, -20, 0, 5, 5, 0, -20, -40},
+		{-50, -40, -30, -30, -30, -30, -40, -50},
+	},
+	// bishop
+	{
+		{-20, -10, -10, -10, -10, -10, -10, -20},
+		{-10, 0, 0, 0, 0, 0, 0, -10},
+		{-10, 0, 5, 10, 10, 5, 0, -10},
+		{-10, 5, 5, 10, 10, 5, 5, -10},
+		{-10, 0, 10, 10, 10, 10, 0, -10},
+		{-10, 10, 10, 10, 10, 10, 10, -10},
+		{-10, 5, 0, 0, 0, 0, 5, -10},
+		{-20, -10, -10, -10, -10, -10, -10, -20},
+	},
+	// queen
+	{
+		{-20, -10, -10, -5, -5, -10, -10, -20},
+		{-10, 0, 0, 0, 0, 0, 0, -10},
+		{-10, 0, 5, 5, 5, 5, 0, -10},
+		{-5, 0, 5, 5, 5, 5, 0, -5},
+		{0, 0, 5, 5, 5, 5, 0, -5},
+		{-10, 5, 5, 5, 5, 5, 0, -10},
+		{-10, 0, 5, 0, 0, 0, 0, -10},
+		{-20, -10, -10, -5, -5, -10, -10, -20},
+	},
+	// king
+	{
+		{-30, -40, -40, -50, -50, -40, -40, -30},
+		{-30, -40, -40, -50, -50, -40, -40, -30},
+		{-30, -40, -40, -50, -50, -40, -40, -30},
+		{-30, -40, -40, -50, -50, -40, -40, -30},
+		{-20, -30, -30, -40, -40, -30, -30, -20},
+		{-10, -20, -20, -20, -20, -20, -20, -10},
+		{20, 20, 0, 0, 0, 0, 20, 20},
+		{20, 30, 10, 0, 0, 10, 30, 20},
+	},
+}
+
+// The weights of the evaluation, in centipawns. Tuned weights can be loaded from a JSON file with LoadWeights.
+type Weights struct {
+	// The value of each type of piece, indexed by the piece type constants
+	PieceValues [6]int `json:"pieceValues"`
+	// The bonus of each type of piece on each square, indexed by the piece type constants. The tables are
+	// from white's point of view and written the way a board is drawn, so the first row is the 8th rank.
+	PieceSquareTables [6][8][8]int `json:"pieceSquareTables"`
+	// The bonus of each type of piece for every square it attacks that isn't taken by a piece of its own color,
+	// indexed by the piece type constants
+	Mobility [6]int `json:"mobility"`
+	// The bonus for each pawn on one of the three files around the king, one or two ranks in front of it
+	KingShield int `json:"kingShield"`
+	// The penalty for each file around the king that has no pawn of the king's color on it
+	KingOpenFile int `json:"kingOpenFile"`
+	// The penalty for each attack of the opponent on the king or the squares next to it
+	KingAttack int `json:"kingAttack"`
+	// The penalty for each pawn on a file after the first one
+	DoubledPawn int `json:"doubledPawn"`
+	// The penalty for each pawn without pawns of its own color on the files next to it
+	IsolatedPawn int `json:"isolatedPawn"`
+	// The bonus for a pawn no enemy pawn can stop, indexed by how many ranks it has moved forward
+	PassedPawn [8]int `json:"passedPawn"`
+}
+
+// The weights the engine evaluates with unless it is given others.
+var DefaultWeights = Weights{
+	PieceValues:       pieceValues,
+	PieceSquareTables: pieceSquareTables,
+	Mobility:          [6]int{0, 2, 4, 4, 1, 0},
+	KingShield:        10,
+	KingOpenFile:      15,
+	KingAttack:        6,
+	DoubledPawn:       15,
+	IsolatedPawn:      12,
+	PassedPawn:        [8]int{0, 5, 10, 20, 35, 60, 100, 0},
+}
+
+// Loads evaluation weights from a JSON file. Weights missing from the file keep their default values, but the
+// ones in it must be given in full, so an array needs all of its values. None of the weights except the
+// piece-square tables can be negative: bonuses are added and penalties taken away by the evaluation itself.
+func LoadWeights(path string) (*Weights, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	w := DefaultWeights
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.DisallowUnknownFields()
+	if err := d.Decode(&w); err != nil {
+		return nil, fmt.Errorf("invalid weights file %s: %s", path, err.Error())
+	}
+
+	if err := checkWeights(data, &w); err != nil {
+		return nil, fmt.Errorf("invalid weights file %s: %s", path, err.Error())
+	}
+
+	return &w, nil
+}
+
+// Checks the lengths of the arrays in the JSON the weights were decoded from, since decoding fills the rest
+// of an array that is too short with zeroes, and the signs of the weights.
+func checkWeights(data []byte, w *Weights) error {
+	var arrays struct {
+		PieceValues       []json.RawMessage     `json:"pieceValues"`
+		PieceSquareTables [][][]json.RawMessage `json:"pieceSquareTables"`
+		Mobility          []json.RawMessage     `json:"mobility"`
+		PassedPawn        []json.RawMessage     `json:"passedPawn"`
+	}
+
+	if err := json.Unmarshal(data, &arrays); err != nil {
+		return err
+	}
+
+	// arrays missing from the file are nil, and keep the default values
+	type array struct {
+		name   string
+		values []json.RawMessage
+		length int
+	}
+
+	checked := []array{
+		{"pieceValues", arrays.PieceValues, len(w.PieceValues)},
+		{"mobility", arrays.Mobility, len(w.Mobility)},
+		{"passedPawn", arrays.PassedPawn, len(w.PassedPawn)},
+	}
+
+	if arrays.PieceSquareTables != nil && len(arrays.PieceSquareTables) != len(w.PieceSquareTables) {
+		return fmt.Errorf("pieceSquareTables has %d values, want %d", len(arrays.PieceSquareTables), len(w.PieceSquareTables))
+	}
+
+	for i, table := range arrays.PieceSquareTables {
+		if len(table) != 8 {
+			return fmt.Errorf("pieceSquareTables[%d] has %d values, want 8", i, len(table))
+		}
+
+		for j, row := range table {
+			checked = append(checked, array{fmt.Sprintf("pieceSquareTables[%d][%d]", i, j), row, 8})
+		}
+	}
+
+	for _, a := range checked {
+		if a.values != nil && len(a.values) != a.length {
+			return fmt.Errorf("%s has %d values, want %d", a.name, len(a.values), a.length)
+		}
+	}
+
+	scalars := []struct {
+		name  string
+		value int
+	}{
+		{"kingShield", w.KingShield},
+		{"kingOpenFile", w.KingOpenFile},
+		{"kingAttack", w.KingAttack},
+		{"doubledPawn", w.DoubledPawn},
+		{"isolatedPawn", w.IsolatedPawn},
+	}
+
+	for _, scalar := range scalars {
+		if scalar.value < 0 {
+			return fmt.Errorf("%s is negative", scalar.name)
+		}
+	}
+
+	bonuses := []struct {
+		name   string
+		values []int
+	}{
+		{"pieceValues", w.PieceValues[:]},
+		{"mobility", w.Mobility[:]},
+		{"passedPawn", w.PassedPawn[:]},
+	}
+
+	for _, b := range bonuses {
+		for i, value := range b.values {
+			if value < 0 {
+				return fmt.Errorf("%s[%d] is negative", b.name, i)
+			}
+		}
+	}
+
+	return nil
+}
+
+// A part of the evaluation: how much it is worth to each player, in centipawns.
+type Term struct {
+	White int `json:"white"`
+	Black int `json:"black"`
+	// White's value minus black's, so positive totals are good for white
+	Total int `json:"total"`
+}
+
+// Adds the value to the term for the given color.
+func (t *Term) add(c chomp.Color, value int) {
+	if c == chomp.ColorWhite {
+		t.White += value
+	} else {
+		t.Black += value
+	}
+
+	t.Total = t.White - t.Black
+}
+
+// The evaluation of a position broken down into the terms it is made of.
+type Evaluation struct {
+	// The value of the pieces
+	Material Term `json:"material"`
+	// The bonuses of the pieces for the squares they are on
+	PieceSquares Term `json:"pieceSquares"`
+	// The bonuses of the pieces for how many squares they attack
+	Mobility Term `json:"mobility"`
+	// The pawns sheltering the king, less the open files and enemy attacks around it
+	KingSafety Term `json:"kingSafety"`
+	// The passed pawns, less the doubled and isolated pawns
+	PawnStructure Term `json:"pawnStructure"`
+	// The sum of the terms in centipawns. Positive totals are good for white.
+	Total int `json:"total"`
+}
+
+// Evaluates the board with the default weights.
+func Evaluate(b *chomp.Board) Evaluation {
+	return DefaultWeights.Evaluate(b)
+}
+
+// Evaluates the board with the weights. Whose turn it is doesn't matter.
+func (w *Weights) Evaluate(b *chomp.Board) Evaluation {
+	var e Evaluation
+	bb := b.Bitboards()
+
+	// the squares around each king, indexed by the color index
+	var zones [2]chomp.Bitboard
+	for i, c := range []chomp.Color{chomp.ColorWhite, chomp.ColorBlack} {
+		for _, king := range bb.Pieces[chomp.MakePiece(chomp.PieceTypeKing, c)].Positions() {
+			zones[i] |= chomp.BitboardOf(king) | bb.Attacks(king)
+		}
+	}
+
+	for x := int8(0); x < 8; x++ {
+		for y := int8(0); y < 8; y++ {
+			piece := b.Grid[x][y]
+			c := piece.Color()
+			if c == chomp.ColorNone {
+				continue
+			}
+
+			t := piece.Type()
+			row := 7 - y
+			if c == chomp.ColorBlack {
+				row = y
+			}
+
+			e.Material.add(c, w.PieceValues[t])
+			e.PieceSquares.add(c, w.PieceSquareTables[t][row][x])
+
+			attacks := bb.Attacks(chomp.NewPos(x, y))
+			e.Mobility.add(c, w.Mobility[t]*(attacks&^bb.Colors[c/2]).Count())
+			// every attack counts, so a square attacked twice is twice as dangerous
+			e.KingSafety.add(c.Opposite(), -w.KingAttack*(attacks&zones[1-c/2]).Count())
+		}
+	}
+
+	for _, c := range []chomp.Color{chomp.ColorWhite, chomp.ColorBlack} {
+		e.KingSafety.add(c, w.kingShelter(&bb, c))
+		e.PawnStructure.add(c, w.pawnStructure(&bb, c))
+	}
+
+	e.Total = e.Material.Total + e.PieceSquares.Total + e.Mobility.Total + e.KingSafety.Total + e.PawnStructure.Total
+	return e
+}
+
+// Returns the positions of the pawns of the given color on each file, counted from the a file.
+func pawnFiles(bb *chomp.Bitboards, c chomp.Color) [8]chomp.Bitboard {
+	var files [8]chomp.Bitboard
+	pawns := bb.Pieces[chomp.MakePiece(chomp.PieceTypePawn, c)]
+	for x := 0; x < 8; x++ {
+		files[x] = pawns & (0x0101010101010101 << x)
+	}
+
+	return files
+}
+
+// Returns how well the pawns of the given color shelter their king.
+func (w *Weights) kingShelter(bb *chomp.Bitboards, c chomp.Color) int {
+	kings := bb.Pieces[chomp.MakePiece(chomp.PieceTypeKing, c)].Positions()
+	if len(kings) != 1 {
+		return 0
+	}
+
+	king := kings[0]
+	forward := int8(1)
+	if c == chomp.ColorBlack {
+		forward = -1
+	}
+
+	score := 0
+	files := pawnFiles(bb, c)
+	for x := king.X - 1; x <= king.X+1; x++ {
+		if x < 0 || x > 7 {
+			continue
+		}
+
+		if files[x] == 0 {
+			score -= w.KingOpenFile
+		}
+
+		for dy := int8(1); dy <= 2; dy++ {
+			p := chomp.NewPos(x, king.Y+forward*dy)
+			if files[x].Has(p) {
+				score += w.KingShield
+			}
+		}
+	}
+
+	return score
+}
+
+// Returns how good the pawns of the given color are.
+func (w *Weights) pawnStructure(bb *chomp.Bitboards, c chomp.Color) int {
+	score := 0
+	files := pawnFiles(bb, c)
+	enemyFiles := pawnFiles(bb, c.Opposite())
+	for x := 0; x < 8; x++ {
+		count := files[x].Count()
+		if count > 1 {
+			score -= w.DoubledPawn * (count - 1)
+		}
+
+		isolated := (x == 0 || files[x-1] == 0) && (x == 7 || files[x+1] == 0)
+		if isolated {
+			score -= w.IsolatedPawn * count
+		}
+
+		for _, p := range files[x].Positions() {
+			if !isPassed(p, c, enemyFiles) {
+				continue
+			}
+
+			advanced := int(p.Y)
+			if c == chomp.ColorBlack {
+				advanced = 7 - advanced
+			}
+
+			score += w.PassedPawn[advanced]
+		}
+	}
+
+	return score
+}
+
+// Returns whether no enemy pawn is in front of the pawn on its file or the files next to it.
+func isPassed(p chomp.Position, c chomp.Color, enemyFiles [8]chomp.Bitboard) bool {
+	for x := p.X - 1; x <= p.X+1; x++ {
+		if x < 0 || x > 7 {
+			continue
+		}
+
+		for _, enemy := range enemyFiles[x].Positions() {
+			if (c == chomp.ColorWhite && enemy.Y > p.Y) || (c == chomp.ColorBlack && enemy.Y < p.Y) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// Evaluates the board in centipawns from the point of view of the given color.
+// Positive scores are good for that color.
+func (w *Weights) evaluate(b *chomp.Board, c chomp.Color) int {
+	score := w.Evaluate(b).Total
+	if c == chomp.ColorBlack {
+		return -score
+	}
+
+	return score
+}
@@ -0,0 +1,126 @@
+package engine
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/apachejuice/chomp/internal/chomp"
+)
+
+func TestEvaluateSymmetric(t *testing.T) {
+	e := Evaluate(&chomp.NewGame().Board)
+	terms := []Term{e.Material, e.PieceSquares, e.Mobility, e.KingSafety, e.PawnStructure}
+	for _, term := range terms {
+		if term.White != term.Black || term.Total != 0 {
+			t.Errorf("the starting position has the term %+v", term)
+		}
+	}
+
+	if e.Total != 0 || e.Material.White != 8*100+2*500+2*320+2*330+900 {
+		t.Errorf("the starting position is evaluated as %+v", e)
+	}
+}
+
+func TestEvaluateTermsAddUp(t *testing.T) {
+	// white is a knight up, and has a doubled, isolated but passed pawn on the e file
+	g, err := chomp.ParseFEN("4k3/8/8/8/4P3/4P3/8/1N2K3 w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := Evaluate(&g.Board)
+	if e.Material.Total != 2*100+320 {
+		t.Errorf("the material is %+v", e.Material)
+	}
+
+	w := DefaultWeights
+	if want := w.PassedPawn[2] + w.PassedPawn[3] - w.DoubledPawn - 2*w.IsolatedPawn; e.PawnStructure.White != want || e.PawnStructure.Black != 0 {
+		t.Errorf("the pawn structure is %+v", e.PawnStructure)
+	}
+
+	sum := e.Material.Total + e.PieceSquares.Total + e.Mobility.Total + e.KingSafety.Total + e.PawnStructure.Total
+	if e.Total != sum {
+		t.Errorf("the total is %d, but the terms add up to %d", e.Total, sum)
+	}
+}
+
+func TestLoadWeights(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "weights.json")
+	if err := os.WriteFile(path, []byte(`{"pieceValues": [100, 500, 300, 300, 1000, 0], "kingShield": 20}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := LoadWeights(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if w.PieceValues[chomp.PieceTypeQueen] != 1000 || w.KingShield != 20 {
+		t.Errorf("the weights from the file were not loaded: %+v", w)
+	}
+
+	if w.PassedPawn != DefaultWeights.PassedPawn || w.PieceSquareTables != DefaultWeights.PieceSquareTables {
+		t.Errorf("the weights missing from the file lost their defaults")
+	}
+
+	if DefaultWeights.PieceValues[chomp.PieceTypeQueen] != 900 {
+		t.Errorf("loading weights changed the default ones")
+	}
+
+	tests := []struct {
+		json, err string
+	}{
+		{`{"kingShield": "a lot"}`, "cannot unmarshal string"},
+		{`{"kingShield": 10, "queenSafety": 5}`, `unknown field "queenSafety"`},
+		// arrays that are too short would have the rest of their values set to zero
+		{`{"pieceValues": [100, 500, 300]}`, "pieceValues has 3 values, want 6"},
+		{`{"pieceValues": []}`, "pieceValues has 0 values, want 6"},
+		{`{"passedPawn": [0, 5, 10, 20, 35, 60, 100, 0, 0]}`, "passedPawn has 9 values, want 8"},
+		{`{"pieceSquareTables": [[[0, 0, 0, 0, 0, 0, 0, 0]]]}`, "pieceSquareTables has 1 values, want 6"},
+		{`{"pieceValues": [100, 500, 300, 300, -1000, 0]}`, "pieceValues[4] is negative"},
+		{`{"doubledPawn": -15}`, "doubledPawn is negative"},
+	}
+
+	for _, test := range tests {
+		if err := os.WriteFile(path, []byte(test.json), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := LoadWeights(path); err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: got the error %v, want one about %s", test.json, err, test.err)
+		}
+	}
+
+	// a row of a piece-square table has to be full too
+	tables := make([][][]int, 6)
+	for i := range tables {
+		tables[i] = make([][]int, 8)
+		for j := range tables[i] {
+			tables[i][j] = make([]int, 8)
+		}
+	}
+
+	tables[2][7] = tables[2][7][:7]
+	data, _ := json.Marshal(map[string]any{"pieceSquareTables": tables})
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadWeights(path); err == nil || !strings.Contains(err.Error(), "pieceSquareTables[2][7] has 7 values, want 8") {
+		t.Errorf("a short row of a piece-square table gave the error %v", err)
+	}
+
+	// the piece-square tables may be negative
+	tables[2][7] = []int{-50, -40, -30, -30, -30, -30, -40, -50}
+	data, _ = json.Marshal(map[string]any{"pieceSquareTables": tables})
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if w, err := LoadWeights(path); err != nil || w.PieceSquareTables[2][7][0] != -50 {
+		t.Errorf("got the error %v loading negative piece-square tables", err)
+	}
+}
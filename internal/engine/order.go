@@ -0,0 +1,88 @@
+package engine
+
+import (
+	"sort"
+
+	"github.com/apachejuice/chomp/internal/chomp"
+)
+
+// Scores of the kinds of moves for ordering, the best ones first. Alpha-beta prunes the most
+// when the best move is searched first.
+const (
+	orderTTMove   = 1 << 30
+	orderPromoted = 1 << 21
+	orderCapture  = 1 << 20
+	orderKiller   = 1 << 19
+)
+
+// Sorts the moves so that the most promising ones come first: the best move found for the position earlier,
+// then promotions and captures of valuable pieces with cheap ones, then moves that caused cutoffs before.
+func (e *Engine) orderMoves(moves []chomp.Move, ttMove chomp.Move, ply int) {
+	scores := make([]int, len(moves))
+	for i, m := range moves {
+		scores[i] = e.moveOrder(m, ttMove, ply)
+	}
+
+	sort.Sort(byOrder{moves: moves, scores: scores})
+}
+
+// Returns how promising the move is for ordering.
+func (e *Engine) moveOrder(m, ttMove chomp.Move, ply int) int {
+	if m == ttMove {
+		return orderTTMove
+	}
+
+	b := &e.game.Board
+	piece := b.At(m.From)
+	score := 0
+	if m.Promotion != chomp.PieceTypeNone {
+		score += orderPromoted + pieceValues[m.Promotion]
+	}
+
+	if e.game.IsCapture(m) {
+		// most valuable victim, least valuable attacker
+		victim := pieceValues[chomp.PieceTypePawn]
+		if captured := b.At(m.To); captured != chomp.PieceNone {
+			victim = pieceValues[captured.Type()]
+		}
+
+		return score + orderCapture + victim*10 - pieceValues[piece.Type()]/10
+	}
+
+	if score > 0 {
+		return score
+	}
+
+	if m == e.killers[ply][0] || m == e.killers[ply][1] {
+		return orderKiller
+	}
+
+	// the history of a move can grow large in a long search, but it never beats a killer move
+	history := e.history[piece][m.To.Y*8+m.To.X]
+	if history >= orderKiller {
+		return orderKiller - 1
+	}
+
+	return history
+}
+
+// Remembers a quiet move that caused a cutoff, since it probably does so in other positions at the same ply too.
+func (e *Engine) addKiller(ply int, m chomp.Move) {
+	if e.killers[ply][0] != m {
+		e.killers[ply][1] = e.killers[ply][0]
+		e.killers[ply][0] = m
+	}
+}
+
+// Sorts moves by their ordering scores, highest first.
+type byOrder struct {
+	moves  []chomp.Move
+	scores []int
+}
+
+func (o byOrder) Len() int           { return len(o.moves) }
+func (o byOrder) Less(i, j int) bool { return o.scores[i] > o.scores[j] }
+func (o byOrder) Swap(i, j int) {
+	o.moves[i], o.moves[j] = o.moves[j], o.moves[i]
+	o.scores[i], o.scores[j] = o.scores[j], o.scores[i]
+}
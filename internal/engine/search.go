@@ -0,0 +1,487 @@
+package engine
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/apachejuice/chomp/internal/chomp"
+	"github.com/apachejuice/chomp/internal/syzygy"
+)
+
+// The deepest the search can go, counting the extensions and quiescence search.
+const maxPly = 64
+
+// Represents the result of a search.
+type Result struct {
+	// The move the engine chose
+	Move chomp.Move `json:"move"`
+	// The score of the move the engine thinks is best. With a weakened engine, this may not be the chosen move.
+	Score Score `json:"score"`
+	// How deep the last complete iteration of the search went
+	Depth int `json:"depth"`
+	// How many positions were searched
+	Nodes uint64 `json:"nodes"`
+	// How long the search took
+	Time time.Duration `json:"time"`
+	// The principal variation of the best move
+	PV []chomp.Move `json:"pv"`
+}
+
+// Searches for the best move for the player to move in the game, within the given limits and
+// those of the engine's strength level. The game is not changed.
+// Returns an error if the game is over, since there is nothing to search.
+func (e *Engine) Search(g *chomp.Game, limits Limits) (Result, error) {
+	if g.IsOver() {
+		return Result{}, errNoMoves
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.prepare(g, limits)
+	return e.run(), nil
+}
+
+// Like Search, but searches in the background and sends the result on the returned channel when done.
+// A call to Stop after Start has returned always stops this search, even if it hasn't really begun yet.
+func (e *Engine) Start(g *chomp.Game, limits Limits) (<-chan Result, error) {
+	if g.IsOver() {
+		return nil, errNoMoves
+	}
+
+	e.mu.Lock()
+	e.prepare(g, limits)
+
+	results := make(chan Result, 1)
+	go func() {
+		defer e.mu.Unlock()
+		results <- e.run()
+	}()
+
+	return results, nil
+}
+
+// Returns the score of a position with the given tablebase result. Cursed wins and blessed losses
+// are draws under the fifty-move rule.
+func tablebaseScore(wdl syzygy.WDL) Score {
+	switch wdl {
+	case syzygy.WDLWin:
+		return scoreTablebaseWin
+	case syzygy.WDLLoss:
+		return -scoreTablebaseWin
+	}
+
+	return 0
+}
+
+// Sets up the state of a search. The engine must be locked.
+func (e *Engine) prepare(g *chomp.Game, limits Limits) {
+	e.game = g.Clone()
+	e.limits = e.applyLevel(limits)
+	e.start = time.Now()
+	e.nodes = 0
+	e.killers = [maxPly][2]chomp.Move{}
+	atomic.StoreInt32(&e.stopped, 0)
+
+	e.soft, e.hard = e.limits.budget(g.Turn)
+	e.deadline = time.Time{}
+	if e.hard > 0 {
+		e.deadline = e.start.Add(e.hard)
+	}
+
+	// a weakened engine needs the exact score of every move to pick one of the good ones
+	e.scores = nil
+	if e.level.Error > 0 {
+		e.scores = make(map[chomp.Move]Score)
+	}
+}
+
+// Searches deeper and deeper until the limits are reached, and returns the best move found.
+// The engine must be locked and prepared.
+func (e *Engine) run() Result {
+	// a book move is only played if the caller didn't ask for certain moves to be searched
+	if e.Book != nil && len(e.limits.Moves) == 0 {
+		if m, ok := e.Book.Pick(e.game); ok {
+			return Result{Move: m, Time: time.Since(e.start), PV: []chomp.Move{m}}
+		}
+	}
+
+	if e.Tablebase != nil && e.level.Error == 0 && len(e.limits.Moves) == 0 && e.Tablebase.Covers(e.game) {
+		if r, err := e.Tablebase.Probe(e.game); err == nil {
+			if m, ok := r.BestMove(); ok {
+				return Result{Move: m, Score: tablebaseScore(r.WDL), Time: time.Since(e.start), PV: []chomp.Move{m}}
+			}
+		}
+	}
+
+	moves := e.rootMoves()
+	result := Result{Move: moves[0], PV: []chomp.Move{moves[0]}}
+	var scores map[chomp.Move]Score
+	for depth := 1; depth < maxPly; depth++ {
+		if e.limits.Depth > 0 && depth > e.limits.Depth {
+			break
+		}
+
+		e.depth = depth
+		score := e.negamax(depth, 0, -scoreInfinite, scoreInfinite)
+		if e.isStopped() && depth > 1 {
+			// the last iteration didn't finish, but a move it has searched completely and found to be
+			// better than the others so far is still better than the result of the previous iteration
+			if e.pvLength[0] > 0 && e.scores == nil {
+				result.Score = e.rootScore
+				result.PV = append([]chomp.Move{}, e.pv[0][:e.pvLength[0]]...)
+				result.Move = result.PV[0]
+			}
+
+			break
+		}
+
+		result.Depth = depth
+		result.Score = score
+		result.PV = append([]chomp.Move{}, e.pv[0][:e.pvLength[0]]...)
+		if len(result.PV) > 0 {
+			result.Move = result.PV[0]
+		}
+
+		if e.scores != nil {
+			scores = e.scores
+			e.scores = make(map[chomp.Move]Score)
+		}
+
+		if e.OnInfo != nil {
+			e.OnInfo(Info{Depth: depth, Score: score, Nodes: e.nodes, Time: time.Since(e.start), PV: result.PV})
+		}
+
+		// there's no point searching deeper if there's only one move or a forced mate was found,
+		// or starting another iteration that is unlikely to finish in time
+		if e.isStopped() || len(moves) == 1 || score.IsMate() {
+			break
+		}
+
+		if e.soft > 0 && time.Since(e.start) > e.soft/2 {
+			break
+		}
+	}
+
+	if scores != nil {
+		result.Move = e.pickWeakened(scores, result.Move)
+	}
+
+	result.Nodes = e.nodes
+	result.Time = time.Since(e.start)
+	e.game = nil
+	return result
+}
+
+// Returns the legal moves to search in the position being searched, which may be limited to only some of them.
+func (e *Engine) rootMoves() []chomp.Move {
+	moves := e.game.LegalMoves()
+	if len(e.limits.Moves) == 0 {
+		return moves
+	}
+
+	allowed := []chomp.Move{}
+	for _, m := range moves {
+		for _, a := range e.limits.Moves {
+			promotion := a.Promotion
+			if promotion == chomp.PieceTypePawn && !a.IsDrop() {
+				// a move written without a promotion may have the zero piece type, which nothing is promoted to,
+				// while a pawn drop names the pawn
+				promotion = chomp.PieceTypeNone
+			}
+
+			if m.From == a.From && m.To == a.To && m.Promotion == promotion {
+				allowed = append(allowed, m)
+				break
+			}
+		}
+	}
+
+	// limiting the moves to none that are legal would leave nothing to play
+	if len(allowed) == 0 {
+		return moves
+	}
+
+	return allowed
+}
+
+// Combines the limits with those of the engine's strength level, keeping the stricter of each.
+func (e *Engine) applyLevel(limits Limits) Limits {
+	if e.level.Depth > 0 && (limits.Depth == 0 || e.level.Depth < limits.Depth) {
+		limits.Depth = e.level.Depth
+	}
+
+	if e.level.Nodes > 0 && (limits.Nodes == 0 || e.level.Nodes < limits.Nodes) {
+		limits.Nodes = e.level.Nodes
+	}
+
+	return limits
+}
+
+// Picks the move with the best score after adding a random error to each one.
+func (e *Engine) pickWeakened(scores map[chomp.Move]Score, best chomp.Move) chomp.Move {
+	bestScore := -scoreInfinite
+	for m, score := range scores {
+		if !score.IsMate() {
+			score += Score(e.rand.Intn(e.level.Error + 1))
+		}
+
+		if score > bestScore {
+			best, bestScore = m, score
+		}
+	}
+
+	return best
+}
+
+// Returns whether the search has to stop, checking the limits every now and then.
+// The first iteration always finishes so that there is a move to play.
+func (e *Engine) isStopped() bool {
+	if e.depth == 1 {
+		return false
+	}
+
+	if atomic.LoadInt32(&e.stopped) != 0 {
+		return true
+	}
+
+	if e.limits.Nodes > 0 && e.nodes >= e.limits.Nodes {
+		e.Stop()
+		return true
+	}
+
+	// looking at the time is slow, so it's only done every 1024 positions
+	if !e.deadline.IsZero() && e.nodes%1024 == 0 && time.Now().After(e.deadline) {
+		e.Stop()
+		return true
+	}
+
+	return false
+}
+
+// Returns whether the position is drawn no matter what, ignoring stalemate which is found by the search itself.
+func (e *Engine) isDraw() bool {
+	return e.game.HalfmoveClock >= 100 || e.game.Repetitions() >= 2 || e.game.Board.IsInsufficientMaterial()
+}
+
+// Searches the position to the given depth with alpha-beta pruning and returns its score.
+// The principal variation of the position is left in the pv table at the given ply.
+func (e *Engine) negamax(depth, ply int, alpha, beta Score) Score {
+	e.pvLength[ply] = 0
+	if ply > 0 && (e.isStopped() || e.isDraw()) {
+		return 0
+	}
+
+	g := e.game
+	check := g.Board.InCheck(g.Turn)
+	if check {
+		// checks are searched a move deeper so forced sequences of them aren't cut short
+		depth++
+	}
+
+	if depth <= 0 || ply >= maxPly-1 {
+		return e.quiesce(ply, alpha, beta)
+	}
+
+	e.nodes++
+	key := g.Hash()
+	entry, found := e.tt.probe(key)
+	ttMove := chomp.Move{From: chomp.ErrorPos}
+	if found {
+		ttMove = entry.move
+		// exact scores aren't taken from the table in the principal variation, since it would be cut short there
+		if ply > 0 && int(entry.depth) >= depth {
+			score := scoreFromTT(entry.score, ply)
+			switch {
+			case entry.bound == boundExact && beta-alpha == 1,
+				entry.bound == boundLower && score >= beta,
+				entry.bound == boundUpper && score <= alpha:
+				return score
+			}
+		}
+	}
+
+	moves := g.LegalMoves()
+	if ply == 0 {
+		moves = e.rootMoves()
+	}
+
+	if len(moves) == 0 {
+		if check {
+			return -ScoreMate + Score(ply)
+		}
+
+		return 0
+	}
+
+	e.orderMoves(moves, ttMove, ply)
+	best := -scoreInfinite
+	bestMove := moves[0]
+	bound := boundUpper
+	for i, m := range moves {
+		capture := g.IsCapture(m)
+		piece := g.Board.At(m.From)
+		g.MakeMove(m)
+
+		var score Score
+		switch {
+		case ply == 0 && e.scores != nil:
+			// the weakened engine chooses between the moves that are at most its error worse than the best one,
+			// so those need exact scores, and the others only need to be shown to be worse than that
+			floor := alpha - Score(e.level.Error)
+			if floor < -scoreInfinite {
+				floor = -scoreInfinite
+			}
+
+			score = -e.negamax(depth-1, ply+1, -beta, -floor)
+		case i == 0:
+			score = -e.negamax(depth-1, ply+1, -beta, -alpha)
+		default:
+			// the first move is probably the best, so the others are only checked for being worse,
+			// which is cheaper, and searched again properly if they're not
+			score = -e.negamax(depth-1, ply+1, -alpha-1, -alpha)
+			if score > alpha && score < beta {
+				score = -e.negamax(depth-1, ply+1, -beta, -alpha)
+			}
+		}
+
+		g.Undo()
+		if e.isStopped() {
+			return 0
+		}
+
+		if e.scores != nil && ply == 0 {
+			e.scores[m] = score
+		}
+
+		if score <= best {
+			continue
+		}
+
+		best = score
+		bestMove = m
+		if score <= alpha {
+			continue
+		}
+
+		alpha = score
+		bound = boundExact
+		e.updatePV(ply, m)
+		if ply == 0 {
+			e.rootScore = score
+		}
+
+		if alpha >= beta {
+			bound = boundLower
+			if !capture && m.Promotion == chomp.PieceTypeNone {
+				e.addKiller(ply, m)
+				e.history[piece][m.To.Y*8+m.To.X] += depth * depth
+			}
+
+			break
+		}
+	}
+
+	e.tt.store(key, bestMove, scoreToTT(best, ply), depth, bound)
+	return best
+}
+
+// Searches only captures and promotions until the position is quiet, so that the evaluation isn't fooled
+// by a piece that is about to be taken. When in check, every move is searched instead.
+func (e *Engine) quiesce(ply int, alpha, beta Score) Score {
+	e.pvLength[ply] = 0
+	if e.isStopped() {
+		return 0
+	}
+
+	e.nodes++
+	g := e.game
+	if ply >= maxPly-1 {
+		return Score(e.weights().evaluate(&g.Board, g.Turn))
+	}
+
+	check := g.Board.InCheck(g.Turn)
+	best := -scoreInfinite
+	if !check {
+		// the player to move can usually do at least as well as standing still
+		best = Score(e.weights().evaluate(&g.Board, g.Turn))
+		if best >= beta {
+			return best
+		}
+
+		if best > alpha {
+			alpha = best
+		}
+	}
+
+	moves := g.LegalMoves()
+	if len(moves) == 0 && check {
+		return -ScoreMate + Score(ply)
+	}
+
+	if !check {
+		moves = e.noisyMoves(moves)
+	}
+
+	e.orderMoves(moves, chomp.Move{From: chomp.ErrorPos}, ply)
+	for _, m := range moves {
+		if !check && e.losesMaterial(m) {
+			continue
+		}
+
+		g.MakeMove(m)
+		score := -e.quiesce(ply+1, -beta, -alpha)
+		g.Undo()
+
+		if score <= best {
+			continue
+		}
+
+		best = score
+		if score > alpha {
+			alpha = score
+			e.updatePV(ply, m)
+			if alpha >= beta {
+				break
+			}
+		}
+	}
+
+	return best
+}
+
+// Returns whether the move is a capture that is likely to lose material: one of a defended piece
+// with a more valuable one. Searching these rarely changes the score, so the quiescence search skips them.
+func (e *Engine) losesMaterial(m chomp.Move) bool {
+	b := &e.game.Board
+	captured := b.At(m.To)
+	if captured == chomp.PieceNone || m.Promotion != chomp.PieceTypeNone {
+		return false
+	}
+
+	piece := b.At(m.From)
+	if pieceValues[piece.Type()] <= pieceValues[captured.Type()] {
+		return false
+	}
+
+	return b.IsAttacked(m.To, captured.Color())
+}
+
+// Returns the captures and promotions among the moves.
+func (e *Engine) noisyMoves(moves []chomp.Move) []chomp.Move {
+	noisy := moves[:0]
+	for _, m := range moves {
+		if e.game.IsCapture(m) || m.Promotion != chomp.PieceTypeNone {
+			noisy = append(noisy, m)
+		}
+	}
+
+	return noisy
+}
+
+// Makes the move followed by the principal variation of the next ply the principal variation of this one.
+func (e *Engine) updatePV(ply int, m chomp.Move) {
+	e.pv[ply][0] = m
+	n := copy(e.pv[ply][1:], e.pv[ply+1][:e.pvLength[ply+1]])
+	e.pvLength[ply] = n + 1
+}
@@ -0,0 +1,186 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/apachejuice/chomp/internal/chomp"
+)
+
+func TestSearchFindsMate(t *testing.T) {
+	tests := []struct {
+		fen, move string
+	}{
+		{"6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1", "a1a8"},
+		{"rnbqkbnr/pppp1ppp/8/4p3/6P1/5P2/PPPPP2P/RNBQKBNR b KQkq - 0 2", "d8h4"},
+	}
+
+	for _, test := range tests {
+		g, err := chomp.ParseFEN(test.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		result, err := New(Levels[len(Levels)-1]).Search(g, Limits{Depth: 3})
+		if err != nil {
+			t.Fatalf("%s: %s", test.fen, err.Error())
+		}
+
+		if result.Move.UCI() != test.move || result.Score.MateIn() != 1 {
+			t.Errorf("%s: got %s with the score %s, want %s with mate in 1", test.fen, result.Move.UCI(), result.Score, test.move)
+		}
+
+		if g.FEN() != test.fen {
+			t.Errorf("%s: the search changed the game to %s", test.fen, g.FEN())
+		}
+	}
+}
+
+func TestSearchGameOver(t *testing.T) {
+	g, err := chomp.ParseFEN("rnb1kbnr/pppp1ppp/8/4p3/6Pq/5P2/PPPPP2P/RNBQKBNR w KQkq - 1 3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := New(Levels[0]).Search(g, Limits{Depth: 1}); err == nil {
+		t.Errorf("a position that is checkmate was searched")
+	}
+}
+
+func TestSearchLimits(t *testing.T) {
+	full := New(Levels[len(Levels)-1])
+	result, err := full.Search(chomp.NewGame(), Limits{Depth: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Depth != 2 || !chomp.NewGame().IsLegal(result.Move) {
+		t.Errorf("got the move %s at depth %d, want a legal move at depth 2", result.Move.UCI(), result.Depth)
+	}
+
+	// the first iteration always finishes, so only the deeper ones are cut short by the node limit
+	first, _ := full.Search(chomp.NewGame(), Limits{Depth: 1})
+	result, err = full.Search(chomp.NewGame(), Limits{Nodes: first.Nodes + 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Nodes > first.Nodes+100 || !chomp.NewGame().IsLegal(result.Move) {
+		t.Errorf("searched %d positions with a limit of %d, and got the move %s", result.Nodes, first.Nodes+100, result.Move.UCI())
+	}
+}
+
+func TestLevels(t *testing.T) {
+	for _, n := range []int{0, len(Levels) + 1} {
+		if _, err := GetLevel(n); err == nil {
+			t.Errorf("the level %d was accepted", n)
+		}
+	}
+
+	for n := 1; n <= len(Levels); n++ {
+		level, err := GetLevel(n)
+		if err != nil || level != Levels[n-1] {
+			t.Errorf("got the level %+v with the error %v, want %+v", level, err, Levels[n-1])
+		}
+	}
+
+	// a level limits the search further than the limits it's given, but not less
+	e := New(Levels[0])
+	if limits := e.applyLevel(Limits{Depth: 5}); limits.Depth != Levels[0].Depth || limits.Nodes != Levels[0].Nodes {
+		t.Errorf("got the limits %+v at level 1", limits)
+	}
+
+	if limits := e.applyLevel(Limits{Nodes: 10}); limits.Nodes != 10 {
+		t.Errorf("got the node limit %d, want 10", limits.Nodes)
+	}
+
+	result, err := e.Search(chomp.NewGame(), Limits{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Depth > Levels[0].Depth || !chomp.NewGame().IsLegal(result.Move) {
+		t.Errorf("got the move %s at depth %d at level 1", result.Move.UCI(), result.Depth)
+	}
+}
+
+func TestBudget(t *testing.T) {
+	tests := []struct {
+		name       string
+		limits     Limits
+		turn       chomp.Color
+		soft, hard time.Duration
+	}{
+		{"no limit", Limits{}, chomp.ColorWhite, 0, 0},
+		{"move time", Limits{MoveTime: time.Second}, chomp.ColorWhite, time.Second, time.Second},
+		{"sudden death", Limits{WhiteTime: 30*time.Second + moveOverhead}, chomp.ColorWhite, time.Second, 2 * time.Second},
+		{"black's clock", Limits{WhiteTime: time.Second, BlackTime: 30*time.Second + moveOverhead}, chomp.ColorBlack, time.Second, 2 * time.Second},
+		// all of the time left may be used on the last move before the time control
+		{"one move to go", Limits{WhiteTime: 10*time.Second + moveOverhead, MovesToGo: 1}, chomp.ColorWhite, 10 * time.Second, 10 * time.Second},
+		{"two moves to go", Limits{WhiteTime: 10*time.Second + moveOverhead, MovesToGo: 2}, chomp.ColorWhite, 5 * time.Second, 5 * time.Second},
+		// the increment counts, but never past the time left
+		{"increment", Limits{WhiteTime: 30*time.Second + moveOverhead, WhiteIncrement: 4 * time.Second}, chomp.ColorWhite, 4 * time.Second, 8 * time.Second},
+		{"increment bigger than the time left", Limits{WhiteTime: 2*time.Second + moveOverhead, WhiteIncrement: 10 * time.Second}, chomp.ColorWhite, 2 * time.Second, 2 * time.Second},
+		{"out of time", Limits{WhiteTime: moveOverhead / 2}, chomp.ColorWhite, time.Millisecond / 30, time.Millisecond / 30 * 2},
+	}
+
+	for _, test := range tests {
+		soft, hard := test.limits.budget(test.turn)
+		if soft != test.soft || hard != test.hard {
+			t.Errorf("%s: got %s and %s, want %s and %s", test.name, soft, hard, test.soft, test.hard)
+		}
+	}
+}
+
+func TestSearchMoves(t *testing.T) {
+	tests := []struct {
+		fen   string
+		moves []chomp.Move
+		want  string
+	}{
+		{chomp.StartFEN, []chomp.Move{chomp.NewMove(chomp.NewPos(0, 1), chomp.NewPos(0, 2))}, "a2a3"},
+		// a move written without a promotion may have the zero piece type
+		{chomp.StartFEN, []chomp.Move{{From: chomp.NewPos(7, 1), To: chomp.NewPos(7, 2)}}, "h2h3"},
+		{"4k3/P7/8/8/8/8/8/4K3 w - - 0 1", []chomp.Move{{From: chomp.NewPos(0, 6), To: chomp.NewPos(0, 7), Promotion: chomp.PieceTypeKnight}}, "a7a8n"},
+		// a promotion has to name its piece to be searched
+		{"4k3/P7/8/8/8/8/8/4K3 w - - 0 1", []chomp.Move{{From: chomp.NewPos(0, 6), To: chomp.NewPos(0, 7)}}, ""},
+		// with no legal moves to search, all of them are
+		{chomp.StartFEN, []chomp.Move{chomp.NewMove(chomp.NewPos(0, 1), chomp.NewPos(0, 4))}, ""},
+	}
+
+	for _, test := range tests {
+		g, err := chomp.ParseFEN(test.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		result, err := New(Levels[len(Levels)-1]).Search(g, Limits{Depth: 2, Moves: test.moves})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !g.IsLegal(result.Move) {
+			t.Errorf("%s: got the illegal move %s", test.fen, result.Move.UCI())
+		}
+
+		if test.want != "" && result.Move.UCI() != test.want {
+			t.Errorf("%s: got the move %s, want %s", test.fen, result.Move.UCI(), test.want)
+		}
+	}
+
+	// the piece of a drop is kept, even a pawn
+	g, err := chomp.ParseVariantFEN(chomp.Crazyhouse{}, "4k3/8/8/8/8/8/8/4K3[Pp] w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	drop := chomp.NewDrop(chomp.PieceTypePawn, chomp.NewPos(0, 2))
+	result, err := New(Levels[len(Levels)-1]).Search(g, Limits{Depth: 2, Moves: []chomp.Move{drop}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Move != drop {
+		t.Errorf("got the move %s, want %s", result.Move.UCI(), drop.UCI())
+	}
+}
@@ -0,0 +1,76 @@
+package engine
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/apachejuice/chomp/internal/chomp"
+)
+
+var errNoMoves = fmt.Errorf("the game is over, there are no moves to search")
+
+const (
+	// How many moves the time left is spread over when there's no time control to reach
+	defaultMovesToGo = 30
+	// Time kept back on the clock for the delay between the engine choosing a move and the clock stopping
+	moveOverhead = 50 * time.Millisecond
+)
+
+// Returns how long the search should take for the given player to move.
+// The soft limit is how long the search should usually take, and no new iteration is started after half of it
+// has passed. The hard limit is when the search stops no matter what. Both are zero if there is no time limit.
+func (l Limits) budget(turn chomp.Color) (soft, hard time.Duration) {
+	if l.MoveTime > 0 {
+		return l.MoveTime, l.MoveTime
+	}
+
+	left, inc := l.WhiteTime, l.WhiteIncrement
+	if turn == chomp.ColorBlack {
+		left, inc = l.BlackTime, l.BlackIncrement
+	}
+
+	if left <= 0 {
+		return 0, 0
+	}
+
+	movesToGo := l.MovesToGo
+	if movesToGo <= 0 {
+		movesToGo = defaultMovesToGo
+	}
+
+	left -= moveOverhead
+	if left < time.Millisecond {
+		left = time.Millisecond
+	}
+
+	// the hard limit may use a bigger share of the time left the fewer moves there are to the time control,
+	// up to all of it on the last move, and most of the increment since it comes back after the move
+	share := movesToGo
+	if share > 4 {
+		share = 4
+	}
+
+	limit := left/time.Duration(share) + inc*3/4
+	if limit > left {
+		limit = left
+	}
+
+	soft = left/time.Duration(movesToGo) + inc*3/4
+	hard = soft * 2
+	if hard > limit {
+		hard = limit
+	}
+
+	if soft > hard {
+		soft = hard
+	}
+
+	return soft, hard
+}
+
+// Returns how long a search with these limits should usually take for the given player to move,
+// or 0 if there is no time limit.
+func (l Limits) TimeFor(turn chomp.Color) time.Duration {
+	soft, _ := l.budget(turn)
+	return soft
+}
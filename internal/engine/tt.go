@@ -0,0 +1,85 @@
+package engine
+
+import "github.com/apachejuice/chomp/internal/chomp"
+
+// How many positions the transposition table holds. Must be a power of two.
+const ttSize = 1 << 18
+
+// What a score stored in the transposition table says about the real score of the position.
+type bound uint8
+
+const (
+	// The score is exact
+	boundExact bound = iota
+	// The real score is at least the stored score
+	boundLower
+	// The real score is at most the stored score
+	boundUpper
+)
+
+// A position stored in the transposition table.
+type ttEntry struct {
+	key   uint64
+	move  chomp.Move
+	score Score
+	depth int8
+	bound bound
+}
+
+// Remembers the results of positions that have been searched, since the same position is often
+// reached by different orders of moves. Positions are found by their Zobrist key.
+type transpositionTable struct {
+	entries []ttEntry
+}
+
+func newTranspositionTable() transpositionTable {
+	return transpositionTable{entries: make([]ttEntry, ttSize)}
+}
+
+// Returns the entry of the position with the given key, if it is in the table.
+func (t *transpositionTable) probe(key uint64) (ttEntry, bool) {
+	entry := t.entries[key&(ttSize-1)]
+	return entry, entry.key == key && entry.depth > 0
+}
+
+// Stores the result of searching a position. Deeper results are kept over shallower ones of the same position,
+// but a different position always replaces the old one.
+func (t *transpositionTable) store(key uint64, move chomp.Move, score Score, depth int, b bound) {
+	entry := &t.entries[key&(ttSize-1)]
+	if entry.key == key && int(entry.depth) > depth {
+		return
+	}
+
+	*entry = ttEntry{key: key, move: move, score: score, depth: int8(depth), bound: b}
+}
+
+func (t *transpositionTable) clear() {
+	for i := range t.entries {
+		t.entries[i] = ttEntry{}
+	}
+}
+
+// Mate scores count the plies from the root of the search, but in the table they have to count them
+// from the stored position, which can be reached at any ply.
+func scoreToTT(s Score, ply int) Score {
+	switch {
+	case s >= scoreMateBound:
+		return s + Score(ply)
+	case s <= -scoreMateBound:
+		return s - Score(ply)
+	}
+
+	return s
+}
+
+// Turns a mate score from the transposition table back into one counted from the root of the search.
+func scoreFromTT(s Score, ply int) Score {
+	switch {
+	case s >= scoreMateBound:
+		return s - Score(ply)
+	case s <= -scoreMateBound:
+		return s + Score(ply)
+	}
+
+	return s
+}
@@ -0,0 +1,205 @@
+// Package pgn reads and writes chess games in Portable Game Notation.
+package pgn
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/apachejuice/chomp/internal/chomp"
+)
+
+// The tags of the Seven Tag Roster, in the order they are written in.
+var SevenTagRoster = []string{"Event", "Site", "Date", "Round", "White", "Black", "Result"}
+
+// The values the tags of the Seven Tag Roster have when they are unknown.
+var sevenTagDefaults = map[string]string{
+	"Event":  "?",
+	"Site":   "?",
+	"Date":   "????.??.??",
+	"Round":  "?",
+	"White":  "?",
+	"Black":  "?",
+	"Result": ResultUnknown,
+}
+
+// The possible game results.
+const (
+	ResultWhiteWins = "1-0"
+	ResultBlackWins = "0-1"
+	ResultDraw      = "1/2-1/2"
+	ResultUnknown   = "*"
+)
+
+// Represents a tag pair, such as [Event "Casual game"].
+type Tag struct {
+	Name  string
+	Value string
+}
+
+// Represents a game read from or written to PGN.
+type Game struct {
+	// The tags of the game in the order they appeared in
+	Tags []Tag
+	// The node for the starting position. It has no move; its children are the first moves of the game.
+	Root *Node
+	// The result written after the moves. One of the Result constants.
+	Result string
+}
+
+// Represents a move in the game tree, along with its annotations.
+type Node struct {
+	// The node of the move before this one, or nil for the root node
+	Parent *Node
+	// The moves played after this one. The first one continues the main line and the rest are variations.
+	Children []*Node
+	// The move played
+	Move chomp.Move
+	// The move in Standard Algebraic Notation as it was read
+	SAN string
+	// A comment written before the move, which can only happen at the start of a variation
+	CommentBefore string
+	// A comment written after the move
+	Comment string
+	// Numeric Annotation Glyphs such as $1 for a good move
+	NAGs []int
+}
+
+// Creates a new game with the Seven Tag Roster filled with unknown values.
+func NewGame() *Game {
+	g := &Game{Root: &Node{}, Result: ResultUnknown}
+	for _, name := range SevenTagRoster {
+		g.SetTag(name, sevenTagDefaults[name])
+	}
+
+	return g
+}
+
+// Returns the value of the tag with the given name, or an empty string if the game doesn't have it.
+func (g *Game) Tag(name string) string {
+	for _, t := range g.Tags {
+		if t.Name == name {
+			return t.Value
+		}
+	}
+
+	return ""
+}
+
+// Sets the value of a tag, adding it if the game doesn't have it yet.
+func (g *Game) SetTag(name, value string) {
+	for i, t := range g.Tags {
+		if t.Name == name {
+			g.Tags[i].Value = value
+			return
+		}
+	}
+
+	g.Tags = append(g.Tags, Tag{Name: name, Value: value})
+}
+
+// Returns the position the game starts from, which is set by the FEN tag if the game has one.
+// The game is Chess960 or played in another variant if the Variant tag says so, and a variant that isn't known
+// is an error.
+func (g *Game) StartPosition() (*chomp.Game, error) {
+	variant := chomp.Variant(chomp.Standard{})
+	chess960 := false
+	switch name := strings.ToLower(strings.ReplaceAll(g.Tag("Variant"), " ", "")); name {
+	case "chess960", "fischerandom", "fischerrandom", "960":
+		chess960 = true
+	case "":
+	default:
+		v, err := chomp.VariantByName(g.Tag("Variant"))
+		if err != nil {
+			return nil, err
+		}
+
+		variant = v
+	}
+
+	fen := g.Tag("FEN")
+	if fen == "" {
+		fen = variant.StartFEN()
+	}
+
+	pos, err := chomp.ParseVariantFEN(variant, fen)
+	if err != nil {
+		return nil, err
+	}
+
+	if chess960 {
+		pos.Chess960 = true
+	}
+
+	return pos, nil
+}
+
+// Returns the nodes of the main line, not including the root.
+func (g *Game) MainLine() []*Node {
+	nodes := []*Node{}
+	for n := g.Root; len(n.Children) > 0; n = n.Children[0] {
+		nodes = append(nodes, n.Children[0])
+	}
+
+	return nodes
+}
+
+// Adds a move after the given node, making it a variation if the node already has a move after it.
+// The move is checked against the rules. The new node is returned.
+func (g *Game) AddMove(after *Node, m chomp.Move) (*Node, error) {
+	pos, err := g.PositionAfter(after)
+	if err != nil {
+		return nil, err
+	}
+
+	san, err := pos.SAN(m)
+	if err != nil {
+		return nil, err
+	}
+
+	n := &Node{Parent: after, Move: m, SAN: san}
+	after.Children = append(after.Children, n)
+	return n, nil
+}
+
+// Returns the position after the move of the given node has been played.
+func (g *Game) PositionAfter(n *Node) (*chomp.Game, error) {
+	path := []*Node{}
+	for ; n != nil && n != g.Root; n = n.Parent {
+		path = append(path, n)
+	}
+
+	if n == nil {
+		return nil, fmt.Errorf("node is not part of the game")
+	}
+
+	pos, err := g.StartPosition()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := len(path) - 1; i >= 0; i-- {
+		if err := pos.ApplyPastEnd(path[i].Move); err != nil {
+			return nil, err
+		}
+	}
+
+	return pos, nil
+}
+
+// Returns the result matching the outcome of a chomp game.
+func ResultOf(o chomp.Outcome) string {
+	switch o {
+	case chomp.OutcomeWhiteWins:
+		return ResultWhiteWins
+	case chomp.OutcomeBlackWins:
+		return ResultBlackWins
+	case chomp.OutcomeDraw:
+		return ResultDraw
+	}
+
+	return ResultUnknown
+}
+
+func isResult(s string) bool {
+	return s == ResultWhiteWins || s == ResultBlackWins || s == ResultDraw || s == ResultUnknown
+}
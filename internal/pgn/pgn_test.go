@@ -0,0 +1,379 @@
+package pgn
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/apachejuice/chomp/internal/chomp"
+)
+
+const immortal = `[Event "Casual game"]
+[Site "London"]
+[Date "1851.06.21"]
+[Round "?"]
+[White "Anderssen, Adolf"]
+[Black "Kieseritzky, Lionel"]
+[Result "1-0"]
+[Annotator "The \"Immortal\" \\ game"]
+
+{Played during a break} 1. e4 e5 2. f4 $1 exf4 (2... d5 {the Falkbeer} 3. exd5
+(3. Nf3 $5) 3... e4) 3. Bc4 Qh4+ $2 4. Kf1 1-0
+
+`
+
+const queensGambit = `[Event "?"]
+[Site "?"]
+[Date "????.??.??"]
+[Round "?"]
+[White "?"]
+[Black "?"]
+[Result "*"]
+
+1. d4 d5 2. c4 {Queen's Gambit} *
+
+`
+
+// Returns the SAN of the moves of the line starting at n.
+func lineSAN(n *Node) []string {
+	sans := []string{}
+	for ; n != nil; n = firstChild(n) {
+		sans = append(sans, n.SAN)
+	}
+
+	return sans
+}
+
+func firstChild(n *Node) *Node {
+	if len(n.Children) == 0 {
+		return nil
+	}
+
+	return n.Children[0]
+}
+
+func TestReadMultipleGames(t *testing.T) {
+	games, err := ReadAll(strings.NewReader(immortal + queensGambit))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(games) != 2 {
+		t.Fatalf("read %d games, want 2", len(games))
+	}
+
+	g := games[0]
+	if g.Tag("White") != "Anderssen, Adolf" || g.Tag("Annotator") != `The "Immortal" \ game` || g.Tag("Opening") != "" {
+		t.Errorf("got the tags %+v", g.Tags)
+	}
+
+	if g.Result != ResultWhiteWins || games[1].Result != ResultUnknown {
+		t.Errorf("got the results %s and %s", g.Result, games[1].Result)
+	}
+
+	if g.Root.Comment != "Played during a break" {
+		t.Errorf("got the comment before the moves %q", g.Root.Comment)
+	}
+
+	main := g.MainLine()
+	if got, want := lineSAN(main[0]), []string{"e4", "e5", "f4", "exf4", "Bc4", "Qh4+", "Kf1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got the main line %v, want %v", got, want)
+	}
+
+	if !reflect.DeepEqual(main[2].NAGs, []int{1}) || !reflect.DeepEqual(main[5].NAGs, []int{2}) {
+		t.Errorf("got the NAGs %v and %v", main[2].NAGs, main[5].NAGs)
+	}
+
+	// the variation replaces exf4 and has a variation of its own replacing exd5
+	if len(main[2].Children) != 2 {
+		t.Fatalf("f4 is followed by %d moves, want 2", len(main[2].Children))
+	}
+
+	falkbeer := main[2].Children[1]
+	if got, want := lineSAN(falkbeer), []string{"d5", "exd5", "e4"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got the variation %v, want %v", got, want)
+	}
+
+	if falkbeer.Comment != "the Falkbeer" {
+		t.Errorf("got the comment %q in the variation", falkbeer.Comment)
+	}
+
+	nested := falkbeer.Children[0]
+	if len(nested.Children) != 1 || len(falkbeer.Children) != 2 {
+		t.Fatalf("the nested variation is in the wrong place")
+	}
+
+	if n := falkbeer.Children[1]; n.SAN != "Nf3" || !reflect.DeepEqual(n.NAGs, []int{5}) || len(n.Children) != 0 {
+		t.Errorf("got the nested variation %s %v", n.SAN, n.NAGs)
+	}
+
+	if main := lineSAN(games[1].MainLine()[0]); !reflect.DeepEqual(main, []string{"d4", "d5", "c4"}) {
+		t.Errorf("got the main line %v of the second game", main)
+	}
+
+	if c := games[1].MainLine()[2].Comment; c != "Queen's Gambit" {
+		t.Errorf("got the comment %q in the second game", c)
+	}
+}
+
+func TestWriteRoundTrip(t *testing.T) {
+	games, err := ReadAll(strings.NewReader(immortal + queensGambit))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, games...); err != nil {
+		t.Fatal(err)
+	}
+
+	if buf.String() != immortal+queensGambit {
+		t.Errorf("got\n%s\nwant\n%s", buf.String(), immortal+queensGambit)
+	}
+}
+
+func TestWriteNewGame(t *testing.T) {
+	g := NewGame()
+	g.SetTag("Event", "Club championship")
+	g.SetTag("ECO", "C20")
+	n, err := g.AddMove(g.Root, chomp.NewMove(chomp.NewPos(4, 1), chomp.NewPos(4, 3)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := g.AddMove(g.Root, chomp.NewMove(chomp.NewPos(3, 1), chomp.NewPos(3, 3))); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := g.AddMove(n, chomp.NewMove(chomp.NewPos(4, 1), chomp.NewPos(4, 3))); err == nil {
+		t.Errorf("an illegal move was added")
+	}
+
+	n.Comment = "best by test"
+	g.Result = ResultDraw
+	text, err := g.Format()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `[Event "Club championship"]
+[Site "?"]
+[Date "????.??.??"]
+[Round "?"]
+[White "?"]
+[Black "?"]
+[Result "1/2-1/2"]
+[ECO "C20"]
+
+1. e4 {best by test} (1. d4) 1/2-1/2
+
+`
+	if text != want {
+		t.Errorf("got\n%s\nwant\n%s", text, want)
+	}
+}
+
+func TestWriteWrapsLines(t *testing.T) {
+	g := NewGame()
+	g.Root.Comment = strings.Repeat("a long comment that has to be wrapped ", 5)
+	after := g.Root
+	// the knights go back and forth, on past the fivefold repetition, which records may do
+	moves := [][2]chomp.Position{
+		{chomp.NewPos(6, 0), chomp.NewPos(5, 2)},
+		{chomp.NewPos(6, 7), chomp.NewPos(5, 5)},
+		{chomp.NewPos(5, 2), chomp.NewPos(6, 0)},
+		{chomp.NewPos(5, 5), chomp.NewPos(6, 7)},
+	}
+
+	for i := 0; i < 16; i++ {
+		m := moves[i%len(moves)]
+		n, err := g.AddMove(after, chomp.NewMove(m[0], m[1]))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		after = n
+	}
+
+	text, err := g.Format()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	movetext := text[strings.Index(text, "\n\n")+2:]
+	lines := strings.Split(strings.TrimSpace(movetext), "\n")
+	if len(lines) < 3 {
+		t.Errorf("the movetext was written on %d lines:\n%s", len(lines), movetext)
+	}
+
+	for _, line := range lines {
+		if len(line) > maxLineLength {
+			t.Errorf("the line %q is longer than %d", line, maxLineLength)
+		}
+	}
+
+	read, err := ReadAll(strings.NewReader(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the comment is read back with the line breaks it was wrapped at
+	comment := strings.Join(strings.Fields(read[0].Root.Comment), " ")
+	if len(read[0].MainLine()) != 16 || comment != strings.TrimSpace(g.Root.Comment) {
+		t.Errorf("the wrapped game was read back with %d moves and the comment %q", len(read[0].MainLine()), comment)
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	tests := []struct {
+		pgn string
+		err string
+	}{
+		{"1. e4 e5 2. Ke3 *", "pgn: line 1: illegal move 'Ke3'"},
+		{"1. e4 (1. d4 d5 *", "pgn: line 1: result inside a variation"},
+		{"1. e4 (1. d4 d5", "pgn: line 1: unterminated variation"},
+		{"1. e4 e5)\n*", "pgn: line 1: unexpected ')'"},
+		{"$1 1. e4 *", "pgn: line 1: annotation without a move"},
+		{"(1. e4) *", "pgn: line 1: variation without a move"},
+		{"[Event \"?\"\n1. e4 *", "pgn: line 2: expected ']'"},
+		{"[FEN \"8/8/8\"]\n\n*", "pgn: line 3: invalid FEN: expected 4 or 6 fields, got 1"},
+		{"[Variant \"Shogi\"]\n\n1. e4 *", "pgn: line 3: unknown variant 'Shogi'"},
+	}
+
+	for _, test := range tests {
+		_, err := ReadAll(strings.NewReader(test.pgn))
+		if err == nil || err.Error() != test.err {
+			t.Errorf("%q: got the error %v, want %s", test.pgn, err, test.err)
+		}
+	}
+}
+
+func TestReadFEN(t *testing.T) {
+	text := "[FEN \"4k3/8/8/8/8/8/8/R3K3 b - - 0 40\"]\n\n40... Kd7 41. Ra7+ *\n"
+	r := NewReader(strings.NewReader(text))
+	g, err := r.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := r.Read(); err != io.EOF {
+		t.Errorf("got the error %v after the last game, want EOF", err)
+	}
+
+	pos, err := g.PositionAfter(g.MainLine()[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if pos.FEN() != "8/R2k4/8/8/8/8/8/4K3 b - - 2 41" {
+		t.Errorf("got the position %s", pos.FEN())
+	}
+
+	formatted, err := g.Format()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasSuffix(formatted, "40... Kd7 41. Ra7+ *\n\n") {
+		t.Errorf("got the movetext of\n%s", formatted)
+	}
+}
+
+func TestReadChess960(t *testing.T) {
+	text := "[Variant \"Chess960\"]\n[FEN \"1r1k3r/8/8/8/8/8/8/1R1K3R w BHbh - 0 1\"]\n\n1. O-O-O *\n"
+	games, err := ReadAll(strings.NewReader(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pos, err := games[0].PositionAfter(games[0].MainLine()[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !pos.Chess960 || pos.FEN() != "1r1k3r/8/8/8/8/8/8/2KR3R b kq - 1 1" {
+		t.Errorf("got the Chess960 game %t in the position %s", pos.Chess960, pos.FEN())
+	}
+}
+
+func TestReadVariant(t *testing.T) {
+	games, err := ReadAll(strings.NewReader("[Variant \"King of the Hill\"]\n\n1. e4 e5 2. Ke2 *\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pos, err := games[0].PositionAfter(games[0].MainLine()[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if pos.Variant != (chomp.KingOfTheHill{}) {
+		t.Errorf("got the variant %v", pos.Variant)
+	}
+}
+
+func TestDropsRoundTrip(t *testing.T) {
+	g := NewGame()
+	g.SetTag("Variant", "Crazyhouse")
+	pos, err := g.StartPosition()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n := g.Root
+	sans := []string{"e4", "d5", "exd5", "Qxd5", "Nc3", "Qa5", "P@d4", "P@e4", "Nxe4"}
+	for _, san := range sans {
+		m, err := pos.ParseSAN(san)
+		if err != nil {
+			t.Fatalf("%s: %s", san, err.Error())
+		}
+
+		if n, err = g.AddMove(n, m); err != nil {
+			t.Fatalf("%s: %s", san, err.Error())
+		}
+
+		if err := pos.Apply(m); err != nil {
+			t.Fatalf("%s: %s", san, err.Error())
+		}
+	}
+
+	text, err := g.Format()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(text, "4. P@d4 P@e4 5. Nxe4") {
+		t.Errorf("the drops were written as:\n%s", text)
+	}
+
+	games, err := ReadAll(strings.NewReader(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(games) != 1 {
+		t.Fatalf("read %d games, want 1", len(games))
+	}
+
+	read := games[0].MainLine()
+	if len(read) != len(sans) {
+		t.Fatalf("read %d moves, want %d", len(read), len(sans))
+	}
+
+	for i, node := range read {
+		if node.SAN != sans[i] || node.Move != g.MainLine()[i].Move {
+			t.Errorf("move %d was read as %s, want %s", i+1, node.SAN, sans[i])
+		}
+	}
+
+	last, err := games[0].PositionAfter(read[len(read)-1])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if last.FEN() != pos.FEN() {
+		t.Errorf("the game read back ends at %s, want %s", last.FEN(), pos.FEN())
+	}
+}
@@ -0,0 +1,416 @@
+package pgn
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/apachejuice/chomp/internal/chomp"
+)
+
+type tokenKind int8
+
+const (
+	tokenEOF          = 0
+	tokenTagOpen      = 1
+	tokenTagClose     = 2
+	tokenString       = 3
+	tokenSymbol       = 4
+	tokenPeriod       = 5
+	tokenNAG          = 6
+	tokenComment      = 7
+	tokenVariationIn  = 8
+	tokenVariationOut = 9
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	line int
+}
+
+// The suffix annotations and the Numeric Annotation Glyphs they stand for.
+var suffixNAGs = map[string]int{
+	"!":  1,
+	"?":  2,
+	"!!": 3,
+	"??": 4,
+	"!?": 5,
+	"?!": 6,
+}
+
+type lexer struct {
+	r    *bufio.Reader
+	line int
+	// whether the next rune is the first one on its line, for % escapes
+	lineStart bool
+}
+
+func (l *lexer) read() (rune, bool) {
+	c, _, err := l.r.ReadRune()
+	if err != nil {
+		return 0, false
+	}
+
+	l.lineStart = c == '\n'
+	if c == '\n' {
+		l.line++
+	}
+
+	return c, true
+}
+
+func (l *lexer) unread(c rune) {
+	l.r.UnreadRune()
+	if c == '\n' {
+		l.line--
+	}
+}
+
+func isSymbolRune(c rune) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || strings.ContainsRune("_+#=:-/@", c)
+}
+
+// Reads the next token. Escaped lines are skipped along with whitespace.
+func (l *lexer) next() (token, error) {
+	for {
+		start := l.lineStart
+		c, ok := l.read()
+		if !ok {
+			return token{kind: tokenEOF, line: l.line}, nil
+		}
+
+		line := l.line
+		switch {
+		case c == '%' && start:
+			l.skipLine()
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			continue
+		case c == '[':
+			return token{kind: tokenTagOpen, line: line}, nil
+		case c == ']':
+			return token{kind: tokenTagClose, line: line}, nil
+		case c == '(':
+			return token{kind: tokenVariationIn, line: line}, nil
+		case c == ')':
+			return token{kind: tokenVariationOut, line: line}, nil
+		case c == '.':
+			return token{kind: tokenPeriod, line: line}, nil
+		case c == '*':
+			return token{kind: tokenSymbol, text: ResultUnknown, line: line}, nil
+		case c == '"':
+			return l.readString(line)
+		case c == '{':
+			return l.readComment(line)
+		case c == ';':
+			text := l.skipLine()
+			return token{kind: tokenComment, text: strings.TrimSpace(text), line: line}, nil
+		case c == '$':
+			digits := l.readWhile(func(c rune) bool { return c >= '0' && c <= '9' })
+			if digits == "" {
+				return token{}, fmt.Errorf("pgn: line %d: expected a number after '$'", line)
+			}
+
+			return token{kind: tokenNAG, text: digits, line: line}, nil
+		case c == '!' || c == '?':
+			text := string(c) + l.readWhile(func(c rune) bool { return c == '!' || c == '?' })
+			nag, ok := suffixNAGs[text]
+			if !ok {
+				return token{}, fmt.Errorf("pgn: line %d: invalid annotation '%s'", line, text)
+			}
+
+			return token{kind: tokenNAG, text: strconv.Itoa(nag), line: line}, nil
+		case isSymbolRune(c):
+			text := string(c) + l.readWhile(isSymbolRune)
+			return token{kind: tokenSymbol, text: text, line: line}, nil
+		default:
+			return token{}, fmt.Errorf("pgn: line %d: unexpected character '%c'", line, c)
+		}
+	}
+}
+
+func (l *lexer) readWhile(f func(rune) bool) string {
+	var sb strings.Builder
+	for {
+		c, ok := l.read()
+		if !ok {
+			break
+		}
+
+		if !f(c) {
+			l.unread(c)
+			break
+		}
+
+		sb.WriteRune(c)
+	}
+
+	return sb.String()
+}
+
+// Skips the rest of the line and returns it.
+func (l *lexer) skipLine() string {
+	text := l.readWhile(func(c rune) bool { return c != '\n' })
+	l.read()
+	return text
+}
+
+func (l *lexer) readString(line int) (token, error) {
+	var sb strings.Builder
+	for {
+		c, ok := l.read()
+		if !ok || c == '\n' {
+			return token{}, fmt.Errorf("pgn: line %d: unterminated string", line)
+		}
+
+		switch c {
+		case '"':
+			return token{kind: tokenString, text: sb.String(), line: line}, nil
+		case '\\':
+			c, ok = l.read()
+			if !ok {
+				return token{}, fmt.Errorf("pgn: line %d: unterminated string", line)
+			}
+		}
+
+		sb.WriteRune(c)
+	}
+}
+
+func (l *lexer) readComment(line int) (token, error) {
+	text := l.readWhile(func(c rune) bool { return c != '}' })
+	if _, ok := l.read(); !ok {
+		return token{}, fmt.Errorf("pgn: line %d: unterminated comment", line)
+	}
+
+	return token{kind: tokenComment, text: strings.TrimSpace(text), line: line}, nil
+}
+
+// Reads games one by one from PGN text.
+type Reader struct {
+	lex *lexer
+	tok token
+	err error
+}
+
+// Creates a reader that reads games from r.
+func NewReader(r io.Reader) *Reader {
+	rd := &Reader{lex: &lexer{r: bufio.NewReader(r), line: 1, lineStart: true}}
+	rd.advance()
+	return rd
+}
+
+func (r *Reader) advance() {
+	if r.err != nil {
+		return
+	}
+
+	r.tok, r.err = r.lex.next()
+}
+
+// Reads the next game. Returns io.EOF when there are no more games.
+// Every move is checked against the rules, and an error is returned on the first illegal one.
+func (r *Reader) Read() (*Game, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	if r.tok.kind == tokenEOF {
+		return nil, io.EOF
+	}
+
+	g := &Game{Root: &Node{}, Result: ResultUnknown}
+	if err := r.readTags(g); err != nil {
+		return nil, err
+	}
+
+	pos, err := g.StartPosition()
+	if err != nil {
+		return nil, fmt.Errorf("pgn: line %d: %s", r.tok.line, err.Error())
+	}
+
+	if err := r.readLine(g, g.Root, pos, 0); err != nil {
+		return nil, err
+	}
+
+	if g.Result == ResultUnknown && isResult(g.Tag("Result")) {
+		g.Result = g.Tag("Result")
+	}
+
+	return g, r.err
+}
+
+// Reads every game in r.
+func ReadAll(r io.Reader) ([]*Game, error) {
+	rd := NewReader(r)
+	games := []*Game{}
+	for {
+		g, err := rd.Read()
+		if err == io.EOF {
+			return games, nil
+		}
+
+		if err != nil {
+			return games, err
+		}
+
+		games = append(games, g)
+	}
+}
+
+func (r *Reader) expect(kind tokenKind, what string) (token, error) {
+	if r.err != nil {
+		return token{}, r.err
+	}
+
+	t := r.tok
+	if t.kind != kind {
+		return t, fmt.Errorf("pgn: line %d: expected %s", t.line, what)
+	}
+
+	r.advance()
+	return t, nil
+}
+
+func (r *Reader) readTags(g *Game) error {
+	for r.err == nil && r.tok.kind == tokenTagOpen {
+		r.advance()
+		name, err := r.expect(tokenSymbol, "a tag name")
+		if err != nil {
+			return err
+		}
+
+		value, err := r.expect(tokenString, "a tag value")
+		if err != nil {
+			return err
+		}
+
+		if _, err := r.expect(tokenTagClose, "']'"); err != nil {
+			return err
+		}
+
+		g.SetTag(name.text, value.text)
+	}
+
+	return r.err
+}
+
+// Reads a line of moves starting after the given node, with pos being the position after it.
+// Variations are read recursively, with depth telling how deep in them the line is.
+func (r *Reader) readLine(g *Game, parent *Node, pos *chomp.Game, depth int) error {
+	var last *Node
+	commentBefore := ""
+	for r.err == nil {
+		t := r.tok
+		switch t.kind {
+		case tokenEOF, tokenTagOpen:
+			if depth > 0 {
+				return fmt.Errorf("pgn: line %d: unterminated variation", t.line)
+			}
+
+			return nil
+		case tokenVariationOut:
+			if depth == 0 {
+				return fmt.Errorf("pgn: line %d: unexpected ')'", t.line)
+			}
+
+			return nil
+		case tokenPeriod:
+			r.advance()
+		case tokenComment:
+			switch {
+			case last != nil:
+				last.Comment = joinComments(last.Comment, t.text)
+			case depth == 0 && parent == g.Root:
+				g.Root.Comment = joinComments(g.Root.Comment, t.text)
+			default:
+				commentBefore = joinComments(commentBefore, t.text)
+			}
+
+			r.advance()
+		case tokenNAG:
+			if last == nil {
+				return fmt.Errorf("pgn: line %d: annotation without a move", t.line)
+			}
+
+			nag, _ := strconv.Atoi(t.text)
+			last.NAGs = append(last.NAGs, nag)
+			r.advance()
+		case tokenVariationIn:
+			if last == nil {
+				return fmt.Errorf("pgn: line %d: variation without a move", t.line)
+			}
+
+			// the variation replaces the last move, so it starts from the position before it
+			before := pos.Clone()
+			before.Undo()
+			r.advance()
+			if err := r.readLine(g, last.Parent, before, depth+1); err != nil {
+				return err
+			}
+
+			if _, err := r.expect(tokenVariationOut, "')'"); err != nil {
+				return err
+			}
+		case tokenSymbol:
+			if isResult(t.text) {
+				if depth > 0 {
+					return fmt.Errorf("pgn: line %d: result inside a variation", t.line)
+				}
+
+				g.Result = t.text
+				r.advance()
+				return nil
+			}
+
+			r.advance()
+			if isMoveNumber(t.text) {
+				continue
+			}
+
+			m, err := pos.ParseSAN(t.text)
+			if err != nil {
+				return fmt.Errorf("pgn: line %d: %s", t.line, err.Error())
+			}
+
+			if err := pos.ApplyPastEnd(m); err != nil {
+				return fmt.Errorf("pgn: line %d: %s", t.line, err.Error())
+			}
+
+			n := &Node{Move: m, SAN: t.text, CommentBefore: commentBefore}
+			commentBefore = ""
+			if last == nil {
+				n.Parent = parent
+			} else {
+				n.Parent = last
+			}
+
+			n.Parent.Children = append(n.Parent.Children, n)
+			last = n
+		default:
+			return fmt.Errorf("pgn: line %d: unexpected token", t.line)
+		}
+	}
+
+	return r.err
+}
+
+func isMoveNumber(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+func joinComments(a, b string) string {
+	if a == "" {
+		return b
+	}
+
+	return a + " " + b
+}
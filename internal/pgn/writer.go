@@ -0,0 +1,179 @@
+package pgn
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/apachejuice/chomp/internal/chomp"
+)
+
+// The longest a line of movetext may be, as recommended by the PGN standard.
+const maxLineLength = 79
+
+// Collects movetext tokens and wraps them into lines.
+type movetextWriter struct {
+	sb    strings.Builder
+	width int
+	// whether the next token is written without a space before it, like after an opening parenthesis
+	glue bool
+}
+
+func (w *movetextWriter) token(s string) {
+	switch {
+	case w.width == 0:
+	case w.width+1+len(s) > maxLineLength:
+		w.sb.WriteByte('\n')
+		w.width = 0
+	case !w.glue:
+		w.sb.WriteByte(' ')
+		w.width++
+	}
+
+	w.glue = false
+	w.sb.WriteString(s)
+	w.width += len(s)
+}
+
+func (w *movetextWriter) comment(text string) {
+	// comments are wrapped by their words so that long ones don't break the line length
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		w.token("{}")
+		return
+	}
+
+	words[0] = "{" + words[0]
+	words[len(words)-1] += "}"
+	for _, word := range words {
+		w.token(word)
+	}
+}
+
+// Writes the moves starting from node n, with pos being the position before it.
+// A move number is written before the first move even if it's black's.
+func (w *movetextWriter) line(n *Node, pos *chomp.Game) error {
+	forceNumber := true
+	for n != nil {
+		if n.CommentBefore != "" {
+			w.comment(n.CommentBefore)
+			forceNumber = true
+		}
+
+		if pos.Turn == chomp.ColorWhite {
+			w.token(strconv.Itoa(pos.FullmoveNumber) + ".")
+		} else if forceNumber {
+			w.token(strconv.Itoa(pos.FullmoveNumber) + "...")
+		}
+
+		san, err := pos.SAN(n.Move)
+		if err != nil {
+			return err
+		}
+
+		w.token(san)
+		forceNumber = false
+		for _, nag := range n.NAGs {
+			w.token("$" + strconv.Itoa(nag))
+		}
+
+		if n.Comment != "" {
+			w.comment(n.Comment)
+			forceNumber = true
+		}
+
+		// variations of this move come right after it
+		if n.Parent != nil && len(n.Parent.Children) > 1 && n.Parent.Children[0] == n {
+			for _, v := range n.Parent.Children[1:] {
+				w.token("(")
+				w.glue = true
+				if err := w.line(v, pos.Clone()); err != nil {
+					return err
+				}
+
+				w.glue = true
+				w.token(")")
+				forceNumber = true
+			}
+		}
+
+		if err := pos.ApplyPastEnd(n.Move); err != nil {
+			return err
+		}
+
+		if len(n.Children) == 0 {
+			break
+		}
+
+		n = n.Children[0]
+	}
+
+	return nil
+}
+
+func writeTag(sb *strings.Builder, name, value string) {
+	value = strings.ReplaceAll(value, "\\", "\\\\")
+	value = strings.ReplaceAll(value, "\"", "\\\"")
+	fmt.Fprintf(sb, "[%s \"%s\"]\n", name, value)
+}
+
+// Returns the game in PGN export format. The Seven Tag Roster is always written first,
+// and the moves are checked against the rules while writing them.
+func (g *Game) Format() (string, error) {
+	var sb strings.Builder
+	for _, name := range SevenTagRoster {
+		value := g.Tag(name)
+		if name == "Result" {
+			value = g.Result
+		} else if value == "" {
+			value = sevenTagDefaults[name]
+		}
+
+		writeTag(&sb, name, value)
+	}
+
+	for _, t := range g.Tags {
+		if sevenTagDefaults[t.Name] == "" {
+			writeTag(&sb, t.Name, t.Value)
+		}
+	}
+
+	sb.WriteByte('\n')
+	pos, err := g.StartPosition()
+	if err != nil {
+		return "", err
+	}
+
+	mw := &movetextWriter{}
+	if g.Root.Comment != "" {
+		mw.comment(g.Root.Comment)
+	}
+
+	if len(g.Root.Children) > 0 {
+		if err := mw.line(g.Root.Children[0], pos); err != nil {
+			return "", err
+		}
+	}
+
+	mw.token(g.Result)
+	sb.WriteString(mw.sb.String())
+	sb.WriteString("\n\n")
+	return sb.String(), nil
+}
+
+// Writes the games to w in PGN export format.
+func Write(w io.Writer, games ...*Game) error {
+	for _, g := range games {
+		text, err := g.Format()
+		if err != nil {
+			return err
+		}
+
+		if _, err := io.WriteString(w, text); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
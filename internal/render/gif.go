@@ -0,0 +1,150 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"image/gif"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/apachejuice/chomp/internal/chomp"
+)
+
+// How long each position of an animated game is shown if no delay is given.
+const DefaultFrameDelay = time.Second
+
+// How many times longer than the others the last position of an animated game is shown, before it starts over.
+const lastFrameHold = 3
+
+// A frame of an animated game: the part of the image of a position that changed since the one before it.
+type frame struct {
+	img   *image.RGBA
+	delay time.Duration
+}
+
+// Returns the smallest rectangle holding every pixel that differs between the two images, which have the same bounds.
+func changedPixels(before, after *image.RGBA) image.Rectangle {
+	r := image.Rectangle{}
+	b := after.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if before.RGBAAt(x, y) != after.RGBAAt(x, y) {
+				r = r.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+
+	return r
+}
+
+// Returns the colors of the frames the most pixels have, at most 256 of them.
+func framePalette(frames []frame) color.Palette {
+	counts := map[color.RGBA]int{}
+	for _, f := range frames {
+		b := f.img.Bounds()
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				counts[f.img.RGBAAt(x, y)]++
+			}
+		}
+	}
+
+	colors := make([]color.RGBA, 0, len(counts))
+	for c := range counts {
+		colors = append(colors, c)
+	}
+
+	sort.Slice(colors, func(i, j int) bool {
+		if counts[colors[i]] != counts[colors[j]] {
+			return counts[colors[i]] > counts[colors[j]]
+		}
+
+		// so the palette of the same game is always the same
+		return rgbaValue(colors[i]) < rgbaValue(colors[j])
+	})
+
+	if len(colors) > 256 {
+		colors = colors[:256]
+	}
+
+	palette := make(color.Palette, len(colors))
+	for i, c := range colors {
+		palette[i] = c
+	}
+
+	return palette
+}
+
+// Returns the color as a number, for ordering colors.
+func rgbaValue(c color.RGBA) uint32 {
+	return uint32(c.R)<<24 | uint32(c.G)<<16 | uint32(c.B)<<8 | uint32(c.A)
+}
+
+// Returns the image with each of its colors replaced by the closest one of the palette.
+// Indexes remembers the index of the closest color of the colors seen so far.
+func toPaletted(img *image.RGBA, palette color.Palette, indexes map[color.RGBA]uint8) *image.Paletted {
+	b := img.Bounds()
+	p := image.NewPaletted(b, palette)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := img.RGBAAt(x, y)
+			i, ok := indexes[c]
+			if !ok {
+				i = uint8(palette.Index(c))
+				indexes[c] = i
+			}
+
+			p.SetColorIndex(x, y, i)
+		}
+	}
+
+	return p
+}
+
+// Writes an animated GIF of the game, showing each of its positions for the given delay, or DefaultFrameDelay
+// if it is 0, and the last one for longer. The squares of the move that led to each position are highlighted
+// in place of the last move of the options; the rest of the options are the same for every position.
+func GIF(w io.Writer, g *chomp.Game, o Options, delay time.Duration) error {
+	if delay <= 0 {
+		delay = DefaultFrameDelay
+	}
+
+	boards := g.Boards()
+	moves := g.Moves()
+	frames := make([]frame, 0, len(boards))
+	var previous *image.RGBA
+	for i := range boards {
+		o.LastMove = nil
+		if i > 0 {
+			o.LastMove = &moves[i-1]
+		}
+
+		img := Image(&boards[i], o)
+		// only the part that changed is kept of every position after the first, and drawn over the one before it
+		r := img.Bounds()
+		if previous != nil {
+			r = changedPixels(previous, img)
+			if r.Empty() {
+				r = image.Rect(0, 0, 1, 1)
+			}
+		}
+
+		frames = append(frames, frame{img: img.SubImage(r).(*image.RGBA), delay: delay})
+		previous = img
+	}
+
+	frames[len(frames)-1].delay *= lastFrameHold
+
+	palette := framePalette(frames)
+	size := o.imageSize()
+	anim := &gif.GIF{Config: image.Config{ColorModel: palette, Width: size, Height: size}}
+	indexes := map[color.RGBA]uint8{}
+	for _, f := range frames {
+		anim.Image = append(anim.Image, toPaletted(f.img, palette, indexes))
+		anim.Delay = append(anim.Delay, int(f.delay/(10*time.Millisecond)))
+		anim.Disposal = append(anim.Disposal, gif.DisposalNone)
+	}
+
+	return gif.EncodeAll(w, anim)
+}
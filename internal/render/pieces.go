@@ -0,0 +1,122 @@
+package render
+
+import (
+	"image/color"
+
+	"github.com/apachejuice/chomp/internal/chomp"
+)
+
+// The size of the square the pieces are drawn in, in the units of their outlines.
+const pieceUnits = 45
+
+// The width of the outlines of the pieces, in the same units.
+const pieceStroke = 1.5
+
+var (
+	outlineColor    = color.RGBA{0x00, 0x00, 0x00, 0xff}
+	whitePieceColor = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	blackPieceColor = color.RGBA{0x00, 0x00, 0x00, 0xff}
+)
+
+type point struct {
+	x, y float64
+}
+
+type circle struct {
+	center point
+	radius float64
+}
+
+// A part of a piece: a polygon, or a circle if the polygon is nil.
+type part struct {
+	polygon []point
+	circle  circle
+}
+
+// The outline of a piece, drawn in a square of pieceUnits from the top left corner.
+type pieceShape struct {
+	// The parts filled in the color of the piece and outlined, in the order they are drawn
+	body []part
+	// Small parts drawn in the outline color on white pieces, and in white on black pieces
+	details []part
+}
+
+func poly(points ...float64) part {
+	p := part{}
+	for i := 0; i+1 < len(points); i += 2 {
+		p.polygon = append(p.polygon, point{points[i], points[i+1]})
+	}
+
+	return p
+}
+
+func disc(x, y, r float64) part {
+	return part{circle: circle{center: point{x, y}, radius: r}}
+}
+
+// The shapes of the pieces, indexed by piece type.
+var pieceShapes = [6]pieceShape{
+	chomp.PieceTypePawn: {
+		body: []part{
+			disc(22.5, 15, 5),
+			poly(18, 20, 27, 20, 25, 24, 29, 32, 16, 32, 20, 24),
+			poly(12, 32, 33, 32, 33, 38, 12, 38),
+		},
+	},
+	chomp.PieceTypeRook: {
+		body: []part{
+			poly(15, 17, 30, 17, 31, 33, 14, 33),
+			poly(11, 9, 15, 9, 15, 12, 20, 12, 20, 9, 25, 9, 25, 12, 30, 12, 30, 9, 34, 9, 34, 14, 31, 17, 14, 17, 11, 14),
+			poly(11, 33, 34, 33, 34, 38, 11, 38),
+		},
+	},
+	chomp.PieceTypeKnight: {
+		body: []part{
+			poly(14, 38, 34, 38, 34, 27, 32, 17, 27, 10, 22, 9, 21, 6, 18, 10, 13, 14, 9, 21, 9, 24, 12, 26, 16, 24,
+				20, 22, 21, 24, 17, 29, 14, 33),
+		},
+		details: []part{
+			disc(16.5, 15.5, 1.3),
+			disc(10.8, 22.5, 0.8),
+		},
+	},
+	chomp.PieceTypeBishop: {
+		body: []part{
+			disc(22.5, 8, 2.5),
+			poly(22.5, 10, 29, 18, 28, 26, 25, 30, 30, 34, 15, 34, 20, 30, 17, 26, 16, 18),
+			poly(12, 34, 33, 34, 33, 38, 12, 38),
+		},
+		details: []part{
+			poly(21.8, 15, 23.2, 15, 23.2, 24, 21.8, 24),
+			poly(19, 18.8, 26, 18.8, 26, 20.2, 19, 20.2),
+		},
+	},
+	chomp.PieceTypeQueen: {
+		body: []part{
+			poly(9, 13, 14, 27, 15.5, 11, 19, 26, 22.5, 9, 26, 26, 29.5, 11, 31, 27, 36, 13, 32, 33, 13, 33),
+			disc(9, 13, 2.3),
+			disc(15.5, 10.5, 2.3),
+			disc(22.5, 9, 2.3),
+			disc(29.5, 10.5, 2.3),
+			disc(36, 13, 2.3),
+			poly(12, 33, 33, 33, 32, 38, 13, 38),
+		},
+	},
+	chomp.PieceTypeKing: {
+		body: []part{
+			poly(21, 5, 24, 5, 24, 8, 27, 8, 27, 11, 24, 11, 24, 15, 21, 15, 21, 11, 18, 11, 18, 8, 21, 8),
+			poly(18, 17, 22.5, 14, 27, 17, 28, 21, 17, 21),
+			poly(10, 20, 35, 20, 31, 33, 14, 33),
+			poly(12, 33, 33, 33, 32, 38, 13, 38),
+		},
+	},
+}
+
+// Returns the color the body of a piece is filled with, and the color of its details.
+func pieceColors(piece chomp.Piece) (fill, detail color.RGBA) {
+	if piece.Color() == chomp.ColorBlack {
+		return blackPieceColor, whitePieceColor
+	}
+
+	return whitePieceColor, outlineColor
+}
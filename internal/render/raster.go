@@ -0,0 +1,259 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"math"
+	"sync"
+
+	"github.com/apachejuice/chomp/internal/chomp"
+)
+
+// The number of samples taken across each pixel, in both directions, to find how much of it a shape covers.
+const supersamples = 4
+
+// Returns the coverage mask of a shape over the rectangle, where covers says whether a point is in the shape.
+func coverage(r image.Rectangle, covers func(q point) bool) *image.Alpha {
+	mask := image.NewAlpha(r)
+	step := 1.0 / supersamples
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			n := 0
+			for sy := 0; sy < supersamples; sy++ {
+				for sx := 0; sx < supersamples; sx++ {
+					if covers(point{float64(x) + (float64(sx)+0.5)*step, float64(y) + (float64(sy)+0.5)*step}) {
+						n++
+					}
+				}
+			}
+
+			mask.SetAlpha(x, y, color.Alpha{uint8(n * 0xff / (supersamples * supersamples))})
+		}
+	}
+
+	return mask
+}
+
+// Paints the color over the image where the mask covers it.
+func paint(img draw.Image, mask *image.Alpha, c color.Color) {
+	draw.DrawMask(img, mask.Rect, image.NewUniform(c), image.Point{}, mask, mask.Rect.Min, draw.Over)
+}
+
+// Returns the color with the given opacity, for painting it over something.
+func translucent(c color.RGBA, opacity float64) color.NRGBA {
+	return color.NRGBA{c.R, c.G, c.B, uint8(opacity * 0xff)}
+}
+
+// Returns whether the point is inside the polygon, by the even-odd rule.
+func insidePolygon(polygon []point, q point) bool {
+	inside := false
+	for i, j := 0, len(polygon)-1; i < len(polygon); j, i = i, i+1 {
+		a, b := polygon[i], polygon[j]
+		if (a.y > q.y) != (b.y > q.y) && q.x < (b.x-a.x)*(q.y-a.y)/(b.y-a.y)+a.x {
+			inside = !inside
+		}
+	}
+
+	return inside
+}
+
+// Returns the distance from the point to the closest point of the line segment from a to b.
+func segmentDistance(q, a, b point) float64 {
+	dx, dy := b.x-a.x, b.y-a.y
+	t := 0.0
+	if l := dx*dx + dy*dy; l > 0 {
+		t = math.Max(0, math.Min(1, ((q.x-a.x)*dx+(q.y-a.y)*dy)/l))
+	}
+
+	return math.Hypot(q.x-(a.x+t*dx), q.y-(a.y+t*dy))
+}
+
+// Returns whether the point is inside the part.
+func (p part) contains(q point) bool {
+	if p.polygon == nil {
+		return math.Hypot(q.x-p.circle.center.x, q.y-p.circle.center.y) <= p.circle.radius
+	}
+
+	return insidePolygon(p.polygon, q)
+}
+
+// Returns the distance from the point to the outline of the part.
+func (p part) distance(q point) float64 {
+	if p.polygon == nil {
+		return math.Abs(math.Hypot(q.x-p.circle.center.x, q.y-p.circle.center.y) - p.circle.radius)
+	}
+
+	d := math.Inf(1)
+	for i, j := 0, len(p.polygon)-1; i < len(p.polygon); j, i = i, i+1 {
+		d = math.Min(d, segmentDistance(q, p.polygon[j], p.polygon[i]))
+	}
+
+	return d
+}
+
+// Returns the top left and bottom right corners of the smallest rectangle the part fits in.
+func (p part) bounds() (point, point) {
+	if p.polygon == nil {
+		c, r := p.circle.center, p.circle.radius
+		return point{c.x - r, c.y - r}, point{c.x + r, c.y + r}
+	}
+
+	topLeft, bottomRight := p.polygon[0], p.polygon[0]
+	for _, q := range p.polygon[1:] {
+		topLeft = point{math.Min(topLeft.x, q.x), math.Min(topLeft.y, q.y)}
+		bottomRight = point{math.Max(bottomRight.x, q.x), math.Max(bottomRight.y, q.y)}
+	}
+
+	return topLeft, bottomRight
+}
+
+// Returns the pixels of an image of the given size the part can be drawn on, at the given scale from its units
+// and with the given width of its outline, which is also in its units.
+func (p part) pixels(size int, scale, stroke float64) image.Rectangle {
+	topLeft, bottomRight := p.bounds()
+	r := image.Rect(int(math.Floor((topLeft.x-stroke)*scale)), int(math.Floor((topLeft.y-stroke)*scale)),
+		int(math.Ceil((bottomRight.x+stroke)*scale)), int(math.Ceil((bottomRight.y+stroke)*scale)))
+	return r.Intersect(image.Rect(0, 0, size, size))
+}
+
+type sprite struct {
+	piece chomp.Piece
+	size  int
+}
+
+// The images of the pieces drawn so far, by piece and size, as *image.RGBA.
+var sprites sync.Map
+
+// Returns the image of a piece drawn in a square of the given size, on a transparent background.
+func pieceImage(piece chomp.Piece, size int) *image.RGBA {
+	key := sprite{piece, size}
+	if img, ok := sprites.Load(key); ok {
+		return img.(*image.RGBA)
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	scale := float64(size) / pieceUnits
+	// samples are taken in pixels, and the outlines are in the units of the pieces
+	units := func(q point) point {
+		return point{q.x / scale, q.y / scale}
+	}
+
+	shape := pieceShapes[piece.Type()]
+	fill, detail := pieceColors(piece)
+	for _, p := range shape.body {
+		r := p.pixels(size, scale, pieceStroke)
+		paint(img, coverage(r, func(q point) bool { return p.contains(units(q)) }), fill)
+		paint(img, coverage(r, func(q point) bool { return p.distance(units(q)) <= pieceStroke/2 }), outlineColor)
+	}
+
+	for _, p := range shape.details {
+		paint(img, coverage(p.pixels(size, scale, 0), func(q point) bool { return p.contains(units(q)) }), detail)
+	}
+
+	sprites.Store(key, img)
+	return img
+}
+
+// The letters of the coordinates, drawn on a grid of 5 by 7 pixels.
+var glyphs = map[rune][7]string{
+	'a': {".....", ".....", ".###.", "....#", ".####", "#...#", ".####"},
+	'b': {"#....", "#....", "####.", "#...#", "#...#", "#...#", "####."},
+	'c': {".....", ".....", ".###.", "#....", "#....", "#...#", ".###."},
+	'd': {"....#", "....#", ".####", "#...#", "#...#", "#...#", ".####"},
+	'e': {".....", ".....", ".###.", "#...#", "#####", "#....", ".###."},
+	'f': {"..##.", ".#..#", ".#...", "###..", ".#...", ".#...", ".#..."},
+	'g': {".....", ".####", "#...#", "#...#", ".####", "....#", ".###."},
+	'h': {"#....", "#....", "#.##.", "##..#", "#...#", "#...#", "#...#"},
+	'1': {"..#..", ".##..", "..#..", "..#..", "..#..", "..#..", ".###."},
+	'2': {".###.", "#...#", "....#", "...#.", "..#..", ".#...", "#####"},
+	'3': {"#####", "...#.", "..#..", "...#.", "....#", "#...#", ".###."},
+	'4': {"...#.", "..##.", ".#.#.", "#..#.", "#####", "...#.", "...#."},
+	'5': {"#####", "#....", "####.", "....#", "....#", "#...#", ".###."},
+	'6': {"..##.", ".#...", "#....", "####.", "#...#", "#...#", ".###."},
+	'7': {"#####", "....#", "...#.", "..#..", ".#...", ".#...", ".#..."},
+	'8': {".###.", "#...#", "#...#", ".###.", "#...#", "#...#", ".###."},
+}
+
+// Draws the letter of a coordinate centered on the point, with each pixel of its glyph as a square of the given size.
+func drawGlyph(img draw.Image, r rune, center point, scale int) {
+	glyph := glyphs[r]
+	left := int(center.x) - 5*scale/2
+	top := int(center.y) - 7*scale/2
+	for y, row := range glyph {
+		for x, c := range row {
+			if c != '#' {
+				continue
+			}
+
+			pixel := image.Rect(left+x*scale, top+y*scale, left+(x+1)*scale, top+(y+1)*scale)
+			draw.Draw(img, pixel, image.NewUniform(coordinateColor), image.Point{}, draw.Src)
+		}
+	}
+}
+
+// Returns the board drawn as an image, with the same things on it as the SVG diagram of the board.
+func Image(b *chomp.Board, o Options) *image.RGBA {
+	sq := o.squareSize()
+	size := o.imageSize()
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	if o.Coordinates {
+		draw.Draw(img, img.Rect, image.NewUniform(frameColor), image.Point{}, draw.Src)
+	}
+
+	for row := 0; row < 8; row++ {
+		for col := 0; col < 8; col++ {
+			p := o.positionAt(row, col)
+			origin := o.squareOrigin(p)
+			r := image.Rect(int(origin.x), int(origin.y), int(origin.x)+sq, int(origin.y)+sq)
+			c := darkSquareColor
+			if isLight(p) {
+				c = lightSquareColor
+			}
+
+			draw.Draw(img, r, image.NewUniform(c), image.Point{}, draw.Src)
+			if o.isLastMove(p) {
+				draw.Draw(img, r, image.NewUniform(translucent(lastMoveColor, lastMoveOpacity)), image.Point{}, draw.Over)
+			}
+
+			if brush, ok := o.markOn(p); ok {
+				draw.Draw(img, r, image.NewUniform(translucent(brush.color(), markOpacity)), image.Point{}, draw.Over)
+			}
+
+			if piece := b.At(p); piece.Color() != chomp.ColorNone {
+				draw.Draw(img, r, pieceImage(piece, sq), image.Point{}, draw.Over)
+			}
+		}
+	}
+
+	if o.Coordinates {
+		margin := float64(o.margin())
+		scale := int(math.Max(1, math.Round(margin*0.6/7)))
+		for i := 0; i < 8; i++ {
+			file := o.squareCenter(o.positionAt(0, i))
+			rank := o.squareCenter(o.positionAt(i, 0))
+			drawGlyph(img, 'a'+rune(o.positionAt(0, i).X), point{file.x, float64(size) - margin/2}, scale)
+			drawGlyph(img, '1'+rune(o.positionAt(i, 0).Y), point{margin / 2, rank.y}, scale)
+		}
+	}
+
+	for _, a := range o.Arrows {
+		points := o.arrowPolygon(a)
+		if points == nil {
+			continue
+		}
+
+		arrow := part{polygon: points}
+		r := arrow.pixels(size, 1, 0)
+		paint(img, coverage(r, arrow.contains), translucent(a.Brush.color(), arrowOpacity))
+	}
+
+	return img
+}
+
+// Writes the board drawn as a PNG image.
+func PNG(w io.Writer, b *chomp.Board, o Options) error {
+	return png.Encode(w, Image(b, o))
+}
@@ -0,0 +1,112 @@
+package render
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"testing"
+	"time"
+
+	"github.com/apachejuice/chomp/internal/chomp"
+)
+
+func TestPNG(t *testing.T) {
+	g := chomp.NewGame()
+	tests := []struct {
+		o    Options
+		size int
+	}{
+		{Options{}, 8 * DefaultSquareSize},
+		{Options{SquareSize: 20, Flipped: true}, 160},
+	}
+
+	for _, test := range tests {
+		var buf bytes.Buffer
+		if err := PNG(&buf, &g.Board, test.o); err != nil {
+			t.Fatal(err)
+		}
+
+		img, err := png.Decode(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if b := img.Bounds(); b.Dx() != test.size || b.Dy() != test.size {
+			t.Errorf("%+v: the image is %dx%d, want %dx%d", test.o, b.Dx(), b.Dy(), test.size, test.size)
+		}
+
+		// the top left corner is a8 or h1, both light squares, and the one next to it is dark
+		sq := test.o.squareSize()
+		if got := color.RGBAModel.Convert(img.At(1, 1)); got != lightSquareColor {
+			t.Errorf("%+v: the top left square is %v, want %v", test.o, got, lightSquareColor)
+		}
+
+		if got := color.RGBAModel.Convert(img.At(sq+1, 1)); got != darkSquareColor {
+			t.Errorf("%+v: the second square is %v, want %v", test.o, got, darkSquareColor)
+		}
+	}
+
+	if b := Image(&g.Board, Options{Coordinates: true}).Bounds(); b.Dx() <= 8*DefaultSquareSize {
+		t.Errorf("the image with coordinates is %d pixels wide, which leaves no room for them", b.Dx())
+	}
+}
+
+func TestGIF(t *testing.T) {
+	g := chomp.NewGame()
+	for _, uci := range []string{"e2e4", "e7e5", "g1f3"} {
+		m, err := g.ParseUCI(uci)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := g.Apply(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := GIF(&buf, g, Options{}, 500*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	anim, err := gif.DecodeAll(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(anim.Image) != 4 {
+		t.Fatalf("the GIF has %d frames, want one for each of the 4 positions", len(anim.Image))
+	}
+
+	for i, delay := range anim.Delay {
+		want := 50
+		if i == len(anim.Delay)-1 {
+			want *= lastFrameHold
+		}
+
+		if delay != want {
+			t.Errorf("frame %d is shown for %d hundredths of a second, want %d", i, delay, want)
+		}
+	}
+
+	// the frames drawn over each other give the last position, in the colors of the palette
+	size := 8 * DefaultSquareSize
+	canvas := image.NewRGBA(image.Rect(0, 0, size, size))
+	for _, frame := range anim.Image {
+		draw.Draw(canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Src)
+	}
+
+	last := g.Moves()[len(g.Moves())-1]
+	want := Image(&g.Board, Options{LastMove: &last})
+	palette := anim.Image[0].Palette
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			if got, want := canvas.RGBAAt(x, y), palette.Convert(want.RGBAAt(x, y)); got != want {
+				t.Fatalf("the pixel at %d, %d of the last position is %v, want %v", x, y, got, want)
+			}
+		}
+	}
+}
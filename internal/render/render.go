@@ -0,0 +1,252 @@
+// Package render draws chess boards as text diagrams and images.
+package render
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"strings"
+
+	"github.com/apachejuice/chomp/internal/chomp"
+)
+
+// The width of a square in pixels if the options don't set one.
+const DefaultSquareSize = 45
+
+// A color for arrows and marks, named by the letter lichess uses for it in the shapes of PGN comments.
+type Brush byte
+
+const (
+	BrushGreen  = 'G'
+	BrushRed    = 'R'
+	BrushYellow = 'Y'
+	BrushBlue   = 'B'
+)
+
+var brushColors = map[Brush]color.RGBA{
+	BrushGreen:  {0x15, 0x78, 0x1b, 0xff},
+	BrushRed:    {0x88, 0x20, 0x20, 0xff},
+	BrushYellow: {0xe6, 0x8f, 0x00, 0xff},
+	BrushBlue:   {0x00, 0x30, 0x88, 0xff},
+}
+
+// Returns the color of the brush, which is green if the brush is not a valid one.
+func (b Brush) color() color.RGBA {
+	c, ok := brushColors[b]
+	if !ok {
+		return brushColors[BrushGreen]
+	}
+
+	return c
+}
+
+// An arrow drawn from the center of one square to the center of another.
+type Arrow struct {
+	From, To chomp.Position
+	Brush    Brush
+}
+
+// A square highlighted in a color.
+type Mark struct {
+	Position chomp.Position
+	Brush    Brush
+}
+
+// Options of how a board is drawn. The zero value draws the board from white's side with nothing else on it.
+type Options struct {
+	// Whether the board is seen from black's side, with the h8 square in the bottom left corner
+	Flipped bool
+	// Whether the files and ranks are labelled
+	Coordinates bool
+	// The move to highlight the squares of, or nil
+	LastMove *chomp.Move
+	// The arrows drawn over the board. Text diagrams leave them out.
+	Arrows []Arrow
+	// The squares highlighted
+	Marks []Mark
+	// The width of a square in pixels, or 0 for DefaultSquareSize. Not used by text diagrams.
+	SquareSize int
+	// Whether text diagrams show pieces as Unicode figurines instead of FEN letters
+	Unicode bool
+	// Whether text diagrams color the squares and pieces with ANSI escape codes
+	ANSI bool
+}
+
+// Returns the width of a square in pixels.
+func (o *Options) squareSize() int {
+	if o.SquareSize <= 0 {
+		return DefaultSquareSize
+	}
+
+	return o.SquareSize
+}
+
+// Returns the position shown at the given row and column of the diagram, counted from the top left corner.
+func (o *Options) positionAt(row, col int) chomp.Position {
+	if o.Flipped {
+		return chomp.NewPos(int8(7-col), int8(row))
+	}
+
+	return chomp.NewPos(int8(col), int8(7-row))
+}
+
+// Returns the row and column of the diagram the position is shown at.
+func (o *Options) cellOf(p chomp.Position) (row, col int) {
+	if o.Flipped {
+		return int(p.Y), 7 - int(p.X)
+	}
+
+	return 7 - int(p.Y), int(p.X)
+}
+
+// Returns whether the position is one of the squares of the last move.
+func (o *Options) isLastMove(p chomp.Position) bool {
+	return o.LastMove != nil && (o.LastMove.From == p || o.LastMove.To == p)
+}
+
+// Returns the brush of the mark on the position, or false if it has none.
+func (o *Options) markOn(p chomp.Position) (Brush, bool) {
+	for _, m := range o.Marks {
+		if m.Position == p {
+			return m.Brush, true
+		}
+	}
+
+	return 0, false
+}
+
+var (
+	lightSquareColor = color.RGBA{0xf0, 0xd9, 0xb5, 0xff}
+	darkSquareColor  = color.RGBA{0xb5, 0x88, 0x63, 0xff}
+	lastMoveColor    = color.RGBA{0x9b, 0xc7, 0x00, 0xff}
+	frameColor       = color.RGBA{0x30, 0x2e, 0x2c, 0xff}
+	coordinateColor  = color.RGBA{0xe0, 0xe0, 0xe0, 0xff}
+)
+
+// How opaque the highlights drawn over the squares are.
+const (
+	lastMoveOpacity = 0.41
+	markOpacity     = 0.5
+	arrowOpacity    = 0.8
+)
+
+// Returns the width of the frame around the board that holds the coordinates, which is 0 if there are none.
+func (o *Options) margin() int {
+	if !o.Coordinates {
+		return 0
+	}
+
+	return o.squareSize() / 2
+}
+
+// Returns the width and height of the image of the board in pixels.
+func (o *Options) imageSize() int {
+	return 8*o.squareSize() + 2*o.margin()
+}
+
+// Returns the top left corner of the square of the position in the image of the board.
+func (o *Options) squareOrigin(p chomp.Position) point {
+	row, col := o.cellOf(p)
+	sq, margin := o.squareSize(), o.margin()
+	return point{float64(margin + col*sq), float64(margin + row*sq)}
+}
+
+// Returns the center of the square of the position in the image of the board.
+func (o *Options) squareCenter(p chomp.Position) point {
+	origin := o.squareOrigin(p)
+	half := float64(o.squareSize()) / 2
+	return point{origin.x + half, origin.y + half}
+}
+
+// Returns the outline of an arrow in the image of the board: a shaft from the center of its first square
+// with a head whose tip is a little short of the center of the other. Returns nil if both squares are the same.
+func (o *Options) arrowPolygon(a Arrow) []point {
+	from, to := o.squareCenter(a.From), o.squareCenter(a.To)
+	length := math.Hypot(to.x-from.x, to.y-from.y)
+	if length == 0 {
+		return nil
+	}
+
+	sq := float64(o.squareSize())
+	// the unit vector along the arrow and the one across it
+	ux, uy := (to.x-from.x)/length, (to.y-from.y)/length
+	nx, ny := -uy, ux
+
+	shaft, head, headLength := 0.08*sq, 0.24*sq, 0.4*sq
+	tip := point{to.x - ux*0.15*sq, to.y - uy*0.15*sq}
+	base := point{tip.x - ux*headLength, tip.y - uy*headLength}
+	at := func(p point, across float64) point {
+		return point{p.x + nx*across, p.y + ny*across}
+	}
+
+	return []point{
+		at(from, shaft), at(base, shaft), at(base, head), tip, at(base, -head), at(base, -shaft), at(from, -shaft),
+	}
+}
+
+// Returns whether the position is one of the light squares.
+func isLight(p chomp.Position) bool {
+	return (p.X+p.Y)%2 == 1
+}
+
+// Parses the brush letter at the start of an arrow or a mark, if there is one. Green is the default.
+func parseBrush(s string) (Brush, string) {
+	if len(s) > 0 {
+		if _, ok := brushColors[Brush(s[0])]; ok {
+			return Brush(s[0]), s[1:]
+		}
+	}
+
+	return BrushGreen, s
+}
+
+// Parses a comma-separated list of arrows written like lichess writes them in the %cal command of PGN comments,
+// such as Ge2e4,Rg8f6: the letter of the brush, which may be left out for green, then the two squares.
+func ParseArrows(s string) ([]Arrow, error) {
+	arrows := []Arrow{}
+	if s == "" {
+		return arrows, nil
+	}
+
+	for _, a := range strings.Split(s, ",") {
+		brush, squares := parseBrush(a)
+		if len(squares) != 4 {
+			return nil, fmt.Errorf("invalid arrow '%s'", a)
+		}
+
+		from, err := chomp.ParsePosition(squares[:2])
+		if err != nil {
+			return nil, fmt.Errorf("invalid arrow '%s': %s", a, err.Error())
+		}
+
+		to, err := chomp.ParsePosition(squares[2:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid arrow '%s': %s", a, err.Error())
+		}
+
+		arrows = append(arrows, Arrow{From: from, To: to, Brush: brush})
+	}
+
+	return arrows, nil
+}
+
+// Parses a comma-separated list of marks written like lichess writes them in the %csl command of PGN comments,
+// such as Ge4,Rd5: the letter of the brush, which may be left out for green, then the square.
+func ParseMarks(s string) ([]Mark, error) {
+	marks := []Mark{}
+	if s == "" {
+		return marks, nil
+	}
+
+	for _, m := range strings.Split(s, ",") {
+		brush, square := parseBrush(m)
+		p, err := chomp.ParsePosition(square)
+		if err != nil {
+			return nil, fmt.Errorf("invalid mark '%s': %s", m, err.Error())
+		}
+
+		marks = append(marks, Mark{Position: p, Brush: brush})
+	}
+
+	return marks, nil
+}
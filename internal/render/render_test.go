@@ -0,0 +1,127 @@
+package render
+
+import (
+	"encoding/xml"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/apachejuice/chomp/internal/chomp"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata with the output of the renderers")
+
+// Compares the output with the golden file of the given name in testdata, or rewrites the file with -update.
+func checkGolden(t *testing.T, name, got string) {
+	t.Helper()
+
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got != string(want) {
+		t.Errorf("%s: the output differs from the golden file:\n%s", name, got)
+	}
+}
+
+// Returns the game after the Ruy Lopez moves, and options showing the last of them with an arrow and marks.
+func ruyLopez(t *testing.T) (*chomp.Game, Options) {
+	t.Helper()
+
+	g := chomp.NewGame()
+	var last chomp.Move
+	for _, uci := range []string{"e2e4", "e7e5", "g1f3", "b8c6", "f1b5"} {
+		m, err := g.ParseUCI(uci)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := g.Apply(m); err != nil {
+			t.Fatal(err)
+		}
+
+		last = m
+	}
+
+	arrows, err := ParseArrows("Gb5c6,Rf3e5")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	marks, err := ParseMarks("Ye5,c6")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return g, Options{LastMove: &last, Arrows: arrows, Marks: marks}
+}
+
+func TestText(t *testing.T) {
+	start := chomp.NewGame()
+	checkGolden(t, "start.txt", Text(&start.Board, Options{}))
+	checkGolden(t, "start-unicode-flipped.txt", Text(&start.Board, Options{Unicode: true, Coordinates: true, Flipped: true}))
+
+	g, o := ruyLopez(t)
+	o.Coordinates = true
+	checkGolden(t, "ruy-lopez.txt", Text(&g.Board, o))
+	o.ANSI = true
+	checkGolden(t, "ruy-lopez-ansi.txt", Text(&g.Board, o))
+}
+
+func TestSVG(t *testing.T) {
+	start := chomp.NewGame()
+	g, o := ruyLopez(t)
+	flipped := o
+	flipped.Flipped, flipped.Coordinates, flipped.SquareSize = true, true, 60
+	tests := []struct {
+		name string
+		svg  string
+	}{
+		{"start.svg", SVG(&start.Board, Options{})},
+		{"ruy-lopez.svg", SVG(&g.Board, o)},
+		{"ruy-lopez-flipped.svg", SVG(&g.Board, flipped)},
+	}
+
+	for _, test := range tests {
+		checkGolden(t, test.name, test.svg)
+
+		// whatever the golden file says, the output must be well-formed XML
+		d := xml.NewDecoder(strings.NewReader(test.svg))
+		for {
+			_, err := d.Token()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				t.Errorf("%s: %s", test.name, err.Error())
+				break
+			}
+		}
+	}
+}
+
+func TestParseArrowsAndMarks(t *testing.T) {
+	for _, s := range []string{"e2e", "Ge2e9", "e2e4,"} {
+		if _, err := ParseArrows(s); err == nil {
+			t.Errorf("the arrows '%s' were accepted", s)
+		}
+	}
+
+	for _, s := range []string{"e9", "Ge", "e4,"} {
+		if _, err := ParseMarks(s); err == nil {
+			t.Errorf("the marks '%s' were accepted", s)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package render
+
+import (
+	"fmt"
+	"image/color"
+	"strings"
+
+	"github.com/apachejuice/chomp/internal/chomp"
+)
+
+// Returns the color in the #rrggbb notation of SVG and CSS.
+func hex(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// Returns the SVG id of the drawing of a piece, such as wn for a white knight.
+func svgPieceID(piece chomp.Piece) string {
+	side := "w"
+	if piece.Color() == chomp.ColorBlack {
+		side = "b"
+	}
+
+	return side + strings.ToLower(string(piece.Letter()))
+}
+
+// Writes the parts as SVG elements with the given attributes.
+func writeSVGParts(sb *strings.Builder, parts []part, attributes string) {
+	for _, p := range parts {
+		if p.polygon == nil {
+			fmt.Fprintf(sb, `<circle cx="%g" cy="%g" r="%g" %s/>`, p.circle.center.x, p.circle.center.y, p.circle.radius,
+				attributes)
+			continue
+		}
+
+		fmt.Fprintf(sb, `<polygon points="%s" %s/>`, svgPoints(p.polygon), attributes)
+	}
+}
+
+// Returns the points of a polygon as the points attribute of SVG polygons wants them.
+func svgPoints(points []point) string {
+	coords := make([]string, len(points))
+	for i, p := range points {
+		coords[i] = fmt.Sprintf("%g,%g", p.x, p.y)
+	}
+
+	return strings.Join(coords, " ")
+}
+
+// Returns an SVG diagram of the board, with the squares of the last move, the marks and the arrows of the options
+// drawn on it in that order, and the coordinates around it in a frame if the options say so.
+// The diagram is 8 squares of the options' square size wide, plus the frame.
+func SVG(b *chomp.Board, o Options) string {
+	sq := o.squareSize()
+	size := o.imageSize()
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink" `+
+		`width="%d" height="%d" viewBox="0 0 %d %d">`, size, size, size, size)
+
+	// every piece on the board is drawn once here, and placed on its squares below
+	sb.WriteString("<defs>")
+	used := map[chomp.Piece]bool{}
+	for x := 0; x < 8; x++ {
+		for y := 0; y < 8; y++ {
+			piece := b.Grid[x][y]
+			if piece.Color() == chomp.ColorNone || used[piece] {
+				continue
+			}
+
+			used[piece] = true
+			shape := pieceShapes[piece.Type()]
+			fill, detail := pieceColors(piece)
+			fmt.Fprintf(&sb, `<g id="%s">`, svgPieceID(piece))
+			writeSVGParts(&sb, shape.body, fmt.Sprintf(`fill="%s" stroke="%s" stroke-width="%g" stroke-linejoin="round"`,
+				hex(fill), hex(outlineColor), pieceStroke))
+			writeSVGParts(&sb, shape.details, fmt.Sprintf(`fill="%s"`, hex(detail)))
+			sb.WriteString("</g>")
+		}
+	}
+
+	sb.WriteString("</defs>")
+
+	if o.Coordinates {
+		fmt.Fprintf(&sb, `<rect width="%d" height="%d" fill="%s"/>`, size, size, hex(frameColor))
+	}
+
+	for row := 0; row < 8; row++ {
+		for col := 0; col < 8; col++ {
+			p := o.positionAt(row, col)
+			origin := o.squareOrigin(p)
+			c := darkSquareColor
+			if isLight(p) {
+				c = lightSquareColor
+			}
+
+			fmt.Fprintf(&sb, `<rect x="%g" y="%g" width="%d" height="%d" fill="%s"/>`, origin.x, origin.y, sq, sq, hex(c))
+			if o.isLastMove(p) {
+				fmt.Fprintf(&sb, `<rect x="%g" y="%g" width="%d" height="%d" fill="%s" fill-opacity="%g"/>`,
+					origin.x, origin.y, sq, sq, hex(lastMoveColor), lastMoveOpacity)
+			}
+
+			if brush, ok := o.markOn(p); ok {
+				fmt.Fprintf(&sb, `<rect x="%g" y="%g" width="%d" height="%d" fill="%s" fill-opacity="%g"/>`,
+					origin.x, origin.y, sq, sq, hex(brush.color()), markOpacity)
+			}
+		}
+	}
+
+	if o.Coordinates {
+		margin := float64(o.margin())
+		font := fmt.Sprintf(`font-family="sans-serif" font-size="%g" fill="%s" text-anchor="middle" dominant-baseline="central"`,
+			margin*0.6, hex(coordinateColor))
+		for i := 0; i < 8; i++ {
+			file := o.squareCenter(o.positionAt(0, i))
+			rank := o.squareCenter(o.positionAt(i, 0))
+			fmt.Fprintf(&sb, `<text x="%g" y="%g" %s>%c</text>`, file.x, float64(size)-margin/2, font, 'a'+o.positionAt(0, i).X)
+			fmt.Fprintf(&sb, `<text x="%g" y="%g" %s>%d</text>`, margin/2, rank.y, font, o.positionAt(i, 0).Y+1)
+		}
+	}
+
+	scale := float64(sq) / pieceUnits
+	for row := 0; row < 8; row++ {
+		for col := 0; col < 8; col++ {
+			p := o.positionAt(row, col)
+			piece := b.At(p)
+			if piece.Color() == chomp.ColorNone {
+				continue
+			}
+
+			origin := o.squareOrigin(p)
+			fmt.Fprintf(&sb, `<use xlink:href="#%s" transform="translate(%g %g) scale(%g)"/>`,
+				svgPieceID(piece), origin.x, origin.y, scale)
+		}
+	}
+
+	for _, a := range o.Arrows {
+		if points := o.arrowPolygon(a); points != nil {
+			fmt.Fprintf(&sb, `<polygon points="%s" fill="%s" fill-opacity="%g"/>`, svgPoints(points), hex(a.Brush.color()),
+				arrowOpacity)
+		}
+	}
+
+	sb.WriteString("</svg>\n")
+	return sb.String()
+}
@@ -0,0 +1,123 @@
+package render
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/apachejuice/chomp/internal/chomp"
+)
+
+// The ANSI 256-color codes of the backgrounds of the squares in text diagrams.
+const (
+	ansiLight    = 180
+	ansiDark     = 137
+	ansiLastMove = 143
+)
+
+// The ANSI 256-color codes of the backgrounds of marked squares, by brush.
+var ansiBrushes = map[Brush]int{
+	BrushGreen:  71,
+	BrushRed:    167,
+	BrushYellow: 178,
+	BrushBlue:   68,
+}
+
+const ansiReset = "\x1b[0m"
+
+// Returns a diagram of the board as text, one rank per line. Pieces are shown as FEN letters, or as figurines if
+// the options say so, and empty squares as dots. With ANSI colors, the squares are colored, along with the last
+// move and the marks, and pieces are drawn in the color of their side, figurines always being the solid ones.
+// Arrows are left out.
+func Text(b *chomp.Board, o Options) string {
+	var sb strings.Builder
+	for row := 0; row < 8; row++ {
+		if o.Coordinates {
+			fmt.Fprintf(&sb, "%d ", o.positionAt(row, 0).Y+1)
+		}
+
+		for col := 0; col < 8; col++ {
+			p := o.positionAt(row, col)
+			if o.ANSI {
+				sb.WriteString(ansiSquare(b, p, &o))
+				continue
+			}
+
+			if col > 0 {
+				sb.WriteByte(' ')
+			}
+
+			sb.WriteString(textPiece(b.At(p), o.Unicode))
+		}
+
+		if o.ANSI {
+			sb.WriteString(ansiReset)
+		}
+
+		sb.WriteByte('\n')
+	}
+
+	if o.Coordinates {
+		sb.WriteString("  ")
+		for col := 0; col < 8; col++ {
+			file := 'a' + rune(o.positionAt(0, col).X)
+			if o.ANSI {
+				fmt.Fprintf(&sb, " %c ", file)
+			} else if col > 0 {
+				fmt.Fprintf(&sb, " %c", file)
+			} else {
+				sb.WriteRune(file)
+			}
+		}
+
+		sb.WriteByte('\n')
+	}
+
+	return sb.String()
+}
+
+// Returns how a piece is shown in a text diagram without colors.
+func textPiece(piece chomp.Piece, unicode bool) string {
+	switch {
+	case piece == chomp.PieceNone && unicode:
+		return "·"
+	case piece == chomp.PieceNone:
+		return "."
+	case unicode:
+		return string(piece.Figurine())
+	}
+
+	return string(piece.Letter())
+}
+
+// Returns a square of a text diagram with ANSI colors: a space on each side of the piece, on the square's background.
+func ansiSquare(b *chomp.Board, p chomp.Position, o *Options) string {
+	background := ansiDark
+	if isLight(p) {
+		background = ansiLight
+	}
+
+	if o.isLastMove(p) {
+		background = ansiLastMove
+	}
+
+	if brush, ok := o.markOn(p); ok {
+		background = ansiBrushes[brush]
+	}
+
+	piece := b.At(p)
+	shown := " "
+	if piece != chomp.PieceNone {
+		// the black figurines are solid, so they show up well in either color
+		shown = string(chomp.MakePiece(piece.Type(), chomp.ColorBlack).Figurine())
+		if !o.Unicode {
+			shown = string(piece.Letter())
+		}
+	}
+
+	foreground := 16
+	if piece.Color() == chomp.ColorWhite {
+		foreground = 231
+	}
+
+	return fmt.Sprintf("\x1b[48;5;%dm\x1b[38;5;%dm %s ", background, foreground, shown)
+}
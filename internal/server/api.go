@@ -1,18 +1,30 @@
 package server
 
 import (
+	"bytes"
+	encjson "encoding/json"
 	"fmt"
 	"net/http"
 	"path/filepath"
+	"strings"
 
+	"github.com/apachejuice/chomp/internal/book"
+	"github.com/apachejuice/chomp/internal/chomp"
+	"github.com/apachejuice/chomp/internal/engine"
+	"github.com/apachejuice/chomp/internal/render"
 	"github.com/apachejuice/chomp/internal/server/auth"
+	"github.com/apachejuice/chomp/internal/syzygy"
 	"github.com/gin-gonic/gin"
 	"golang.org/x/crypto/acme/autocert"
 )
 
 type API struct {
-	eng *gin.Engine
-	db  Database
+	eng       *gin.Engine
+	db        Database
+	games     *GameManager
+	book      *book.Book
+	tablebase *syzygy.Tablebase
+	weights   *engine.Weights
 }
 
 // request json type
@@ -66,11 +78,30 @@ func NewApi() (*API, error) {
 		return nil, err
 	}
 
-	engine := gin.Default()
-	engine.SetTrustedProxies(nil)
+	b, err := loadBook()
+	if err != nil {
+		return nil, err
+	}
+
+	tb, err := loadTablebase()
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := loadWeights()
+	if err != nil {
+		return nil, err
+	}
+
+	eng := gin.Default()
+	eng.SetTrustedProxies(nil)
 	return &API{
-		eng: engine,
-		db:  db,
+		eng:       eng,
+		db:        db,
+		games:     NewGameManager(b, tb, w),
+		book:      b,
+		tablebase: tb,
+		weights:   w,
 	}, nil
 }
 
@@ -114,6 +145,16 @@ func (a *API) SetEndpoints() {
 	a.eng.POST(filepath.Join(br, "/logout"), a.apiLogout)
 	a.eng.POST(filepath.Join(br, "/register"), a.apiRegister)
 	a.eng.GET(filepath.Join(br, "/loggedIn"), a.apiLoggedIn)
+	a.eng.POST(filepath.Join(br, "/games"), a.apiCreateGame)
+	a.eng.GET(filepath.Join(br, "/games/:id"), a.apiGetGame)
+	a.eng.POST(filepath.Join(br, "/games/:id/join"), a.apiJoinGame)
+	a.eng.POST(filepath.Join(br, "/games/:id/move"), a.apiMove)
+	a.eng.GET(filepath.Join(br, "/explorer"), a.apiExplorer)
+	a.eng.GET(filepath.Join(br, "/tablebase"), a.apiTablebase)
+	a.eng.GET(filepath.Join(br, "/evaluate"), a.apiEvaluate)
+	a.eng.GET(filepath.Join(br, "/validate"), a.apiValidate)
+	a.eng.GET(filepath.Join(br, "/board.svg"), a.apiBoardSVG)
+	a.eng.GET(filepath.Join(br, "/position.png"), a.apiPositionPNG)
 }
 
 func checkIP(ip string) (int, error) {
@@ -228,3 +269,380 @@ func (a *API) apiLoggedIn(c *gin.Context) {
 
 	json(c, http.StatusOK, `{"account": "%s"}`, session.Account.Username)
 }
+
+func gameResponse(c *gin.Context, g *ServerGame) {
+	data, err := encjson.Marshal(g.toJson())
+	if err != nil {
+		errJson(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	json(c, http.StatusOK, "%s", data)
+}
+
+func (a *API) apiCreateGame(c *gin.Context) {
+	if status, err := checkIP(c.ClientIP()); err != nil {
+		errJson(c, err, status)
+		return
+	}
+
+	params, err := loadJson(c)
+	if err != nil {
+		errJson(c, err)
+		return
+	}
+
+	session, err := a.db.GetSessionByToken(params["token"])
+	if err != nil {
+		errJson(c, err)
+		return
+	}
+
+	color, err := parseColor(params["color"])
+	if err != nil {
+		errJson(c, err)
+		return
+	}
+
+	level, err := parseLevel(params["level"])
+	if err != nil {
+		errJson(c, err)
+		return
+	}
+
+	variant, err := parseVariant(params["variant"])
+	if err != nil {
+		errJson(c, err)
+		return
+	}
+
+	start, err := parseStartIndex(params["index"])
+	if err != nil {
+		errJson(c, err)
+		return
+	}
+
+	control, err := parseControl(params["clock"])
+	if err != nil {
+		errJson(c, err)
+		return
+	}
+
+	g, err := a.games.Create(session.Account.Username, color, level, variant, start, control)
+	if err != nil {
+		errJson(c, err)
+		return
+	}
+
+	gameResponse(c, g)
+}
+
+func (a *API) apiGetGame(c *gin.Context) {
+	if status, err := checkIP(c.ClientIP()); err != nil {
+		errJson(c, err, status)
+		return
+	}
+
+	// gin can't route a parameter with a suffix, so the GIFs of games are served from here
+	id := c.Param("id")
+	if strings.HasSuffix(id, ".gif") {
+		a.gameGIF(c, strings.TrimSuffix(id, ".gif"))
+		return
+	}
+
+	g, err := a.games.Get(id)
+	if err != nil {
+		errJson(c, err, http.StatusNotFound)
+		return
+	}
+
+	gameResponse(c, g)
+}
+
+// Sends an animated GIF of the game with the given ID, or of one of its boards if it's a Bughouse game.
+func (a *API) gameGIF(c *gin.Context, id string) {
+	g, err := a.games.Get(id)
+	if err != nil {
+		errJson(c, err, http.StatusNotFound)
+		return
+	}
+
+	board, err := parseBoard(c.Query("board"))
+	if err != nil {
+		errJson(c, err)
+		return
+	}
+
+	switch {
+	case board < 0:
+		board = 0
+	case board >= g.boards():
+		errJson(c, fmt.Errorf("the game has no board %d", board))
+		return
+	}
+
+	o, err := viewOptions(c)
+	if err != nil {
+		errJson(c, err)
+		return
+	}
+
+	delay, err := parseFrameDelay(c.Query("delay"))
+	if err != nil {
+		errJson(c, err)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err = render.GIF(&buf, g.snapshot(board), o, delay); err != nil {
+		errJson(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	c.Data(http.StatusOK, "image/gif", buf.Bytes())
+}
+
+func (a *API) apiJoinGame(c *gin.Context) {
+	if status, err := checkIP(c.ClientIP()); err != nil {
+		errJson(c, err, status)
+		return
+	}
+
+	g, err := a.games.Get(c.Param("id"))
+	if err != nil {
+		errJson(c, err, http.StatusNotFound)
+		return
+	}
+
+	params, err := loadJson(c)
+	if err != nil {
+		errJson(c, err)
+		return
+	}
+
+	session, err := a.db.GetSessionByToken(params["token"])
+	if err != nil {
+		errJson(c, err)
+		return
+	}
+
+	board, err := parseBoard(params["board"])
+	if err != nil {
+		errJson(c, err)
+		return
+	}
+
+	color, err := parseColor(params["color"])
+	if err != nil {
+		errJson(c, err)
+		return
+	}
+
+	err = g.Join(session.Account.Username, board, color)
+	if err != nil {
+		errJson(c, err)
+		return
+	}
+
+	gameResponse(c, g)
+}
+
+func (a *API) apiMove(c *gin.Context) {
+	if status, err := checkIP(c.ClientIP()); err != nil {
+		errJson(c, err, status)
+		return
+	}
+
+	g, err := a.games.Get(c.Param("id"))
+	if err != nil {
+		errJson(c, err, http.StatusNotFound)
+		return
+	}
+
+	params, err := loadJson(c)
+	if err != nil {
+		errJson(c, err)
+		return
+	}
+
+	session, err := a.db.GetSessionByToken(params["token"])
+	if err != nil {
+		errJson(c, err)
+		return
+	}
+
+	err = g.Move(session.Account.Username, params["move"])
+	if err != nil {
+		errJson(c, err)
+		return
+	}
+
+	gameResponse(c, g)
+}
+
+func (a *API) apiExplorer(c *gin.Context) {
+	if status, err := checkIP(c.ClientIP()); err != nil {
+		errJson(c, err, status)
+		return
+	}
+
+	if a.book == nil {
+		errJson(c, errNoBook, http.StatusNotFound)
+		return
+	}
+
+	fen := c.DefaultQuery("fen", chomp.StartFEN)
+	g, err := parseValidFEN(fen)
+	if err != nil {
+		errJson(c, err)
+		return
+	}
+
+	data, err := encjson.Marshal(explore(a.book, g))
+	if err != nil {
+		errJson(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	json(c, http.StatusOK, "%s", data)
+}
+
+func (a *API) apiTablebase(c *gin.Context) {
+	if status, err := checkIP(c.ClientIP()); err != nil {
+		errJson(c, err, status)
+		return
+	}
+
+	if a.tablebase == nil {
+		errJson(c, errNoTablebase, http.StatusNotFound)
+		return
+	}
+
+	p, err := params(c, "fen")
+	if err != nil {
+		errJson(c, err)
+		return
+	}
+
+	g, err := parseValidFEN(p["fen"])
+	if err != nil {
+		errJson(c, err)
+		return
+	}
+
+	j, err := probe(a.tablebase, g)
+	if err != nil {
+		errJson(c, err)
+		return
+	}
+
+	data, err := encjson.Marshal(j)
+	if err != nil {
+		errJson(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	json(c, http.StatusOK, "%s", data)
+}
+
+func (a *API) apiEvaluate(c *gin.Context) {
+	if status, err := checkIP(c.ClientIP()); err != nil {
+		errJson(c, err, status)
+		return
+	}
+
+	p, err := params(c, "fen")
+	if err != nil {
+		errJson(c, err)
+		return
+	}
+
+	g, err := parseValidFEN(p["fen"])
+	if err != nil {
+		errJson(c, err)
+		return
+	}
+
+	data, err := encjson.Marshal(evaluationJson{FEN: g.FEN(), Evaluation: a.weights.Evaluate(&g.Board)})
+	if err != nil {
+		errJson(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	json(c, http.StatusOK, "%s", data)
+}
+
+func (a *API) apiValidate(c *gin.Context) {
+	if status, err := checkIP(c.ClientIP()); err != nil {
+		errJson(c, err, status)
+		return
+	}
+
+	p, err := params(c, "fen")
+	if err != nil {
+		errJson(c, err)
+		return
+	}
+
+	g, err := chomp.ParseFEN(p["fen"])
+	if err != nil {
+		errJson(c, err)
+		return
+	}
+
+	data, err := encjson.Marshal(validate(g))
+	if err != nil {
+		errJson(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	json(c, http.StatusOK, "%s", data)
+}
+
+func (a *API) apiBoardSVG(c *gin.Context) {
+	if status, err := checkIP(c.ClientIP()); err != nil {
+		errJson(c, err, status)
+		return
+	}
+
+	g, err := chomp.ParseFEN(c.DefaultQuery("fen", chomp.StartFEN))
+	if err != nil {
+		errJson(c, err)
+		return
+	}
+
+	o, err := diagramOptions(c)
+	if err != nil {
+		errJson(c, err)
+		return
+	}
+
+	c.Data(http.StatusOK, "image/svg+xml", []byte(render.SVG(&g.Board, o)))
+}
+
+func (a *API) apiPositionPNG(c *gin.Context) {
+	if status, err := checkIP(c.ClientIP()); err != nil {
+		errJson(c, err, status)
+		return
+	}
+
+	g, err := chomp.ParseFEN(c.DefaultQuery("fen", chomp.StartFEN))
+	if err != nil {
+		errJson(c, err)
+		return
+	}
+
+	o, err := diagramOptions(c)
+	if err != nil {
+		errJson(c, err)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err = render.PNG(&buf, &g.Board, o); err != nil {
+		errJson(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	c.Data(http.StatusOK, "image/png", buf.Bytes())
+}
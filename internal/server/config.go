@@ -10,6 +10,8 @@ type ChompConfig struct {
 	Version   string         `json:"version"`
 	APIConfig APIConfig      `json:"apiConfig"`
 	DBConfig  DatabaseConfig `json:"dbConfig"`
+	// Optional; the engine plays without a book if not set
+	EngineConfig *EngineConfig `json:"engineConfig"`
 }
 
 type APIConfig struct {
@@ -25,6 +27,17 @@ type DatabaseConfig struct {
 	AccountDatabase string `json:"accountDatabase"`
 }
 
+type EngineConfig struct {
+	// The Polyglot opening book the engine plays from and the explorer shows, if any
+	BookFile string `json:"bookFile"`
+	// How many plies into a game the engine plays book moves, or 0 for as long as the book has them
+	BookDepth int `json:"bookDepth"`
+	// The directories of the Syzygy tablebase files, separated like in PATH, if any
+	SyzygyPath string `json:"syzygyPath"`
+	// The JSON file of the weights the engine evaluates positions with, if not the default ones
+	WeightsFile string `json:"weightsFile"`
+}
+
 type TLSConfig struct {
 	HostWhitelist []string `json:"hostWhitelist"`
 	DirCache      string   `json:"dirCache"`
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/apachejuice/chomp/internal/chomp"
+	"github.com/apachejuice/chomp/internal/render"
+	"github.com/gin-gonic/gin"
+)
+
+// The largest size of a square clients may ask diagrams to be drawn with, in pixels.
+const maxSquareSize = 200
+
+// The shortest and longest time clients may ask each position of an animated game to be shown for, in milliseconds.
+// Browsers show GIF frames shorter than 20 milliseconds for much longer.
+const (
+	minFrameDelay = 20
+	maxFrameDelay = 10000
+)
+
+// Parses how a board is shown from the query of a request: flip and coords are booleans,
+// and size is the width of a square in pixels.
+func viewOptions(c *gin.Context) (render.Options, error) {
+	o := render.Options{}
+	var err error
+	if o.Flipped, err = parseFlag(c.Query("flip"), "flip"); err != nil {
+		return o, err
+	}
+
+	if o.Coordinates, err = parseFlag(c.Query("coords"), "coords"); err != nil {
+		return o, err
+	}
+
+	if s := c.Query("size"); s != "" {
+		o.SquareSize, err = strconv.Atoi(s)
+		if err != nil || o.SquareSize < 1 || o.SquareSize > maxSquareSize {
+			return o, fmt.Errorf("size must be a number from 1 to %d", maxSquareSize)
+		}
+	}
+
+	return o, nil
+}
+
+// Parses the options of a board diagram from the query of a request: the ones of viewOptions, lastMove,
+// which is a move in UCI notation, and arrows and marks, which are written like in the shapes of lichess
+// PGN comments, such as Ge2e4,Rg8f6 and Ge4,Rd5.
+func diagramOptions(c *gin.Context) (render.Options, error) {
+	o, err := viewOptions(c)
+	if err != nil {
+		return o, err
+	}
+
+	if s := c.Query("lastMove"); s != "" {
+		m, err := chomp.ParseUCI(s)
+		if err != nil {
+			return o, err
+		}
+
+		o.LastMove = &m
+	}
+
+	if o.Arrows, err = render.ParseArrows(c.Query("arrows")); err != nil {
+		return o, err
+	}
+
+	if o.Marks, err = render.ParseMarks(c.Query("marks")); err != nil {
+		return o, err
+	}
+
+	return o, nil
+}
+
+// Parses how long each position of an animated game is shown, in milliseconds, or the default if it's left out.
+func parseFrameDelay(s string) (time.Duration, error) {
+	if s == "" {
+		return render.DefaultFrameDelay, nil
+	}
+
+	ms, err := strconv.Atoi(s)
+	if err != nil || ms < minFrameDelay || ms > maxFrameDelay {
+		return 0, fmt.Errorf("delay must be a number from %d to %d", minFrameDelay, maxFrameDelay)
+	}
+
+	return time.Duration(ms) * time.Millisecond, nil
+}
+
+// Parses a boolean query parameter, which is false if it's left out.
+func parseFlag(s, name string) (bool, error) {
+	if s == "" {
+		return false, nil
+	}
+
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return false, fmt.Errorf("%s must be true or false, got '%s'", name, s)
+	}
+
+	return b, nil
+}
@@ -0,0 +1,28 @@
+package server
+
+import (
+	"fmt"
+
+	"github.com/apachejuice/chomp/internal/engine"
+)
+
+// The evaluation of a position as the evaluation endpoint sends it to clients.
+type evaluationJson struct {
+	FEN string `json:"fen"`
+	engine.Evaluation
+}
+
+// Loads the evaluation weights set in the configuration, or returns the default ones if there are none.
+func loadWeights() (*engine.Weights, error) {
+	if config.EngineConfig == nil || config.EngineConfig.WeightsFile == "" {
+		return &engine.DefaultWeights, nil
+	}
+
+	w, err := engine.LoadWeights(config.EngineConfig.WeightsFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load evaluation weights: %s", err.Error())
+	}
+
+	slog.Printf("Loaded evaluation weights from %s\n", config.EngineConfig.WeightsFile)
+	return w, nil
+}
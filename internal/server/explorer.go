@@ -0,0 +1,68 @@
+package server
+
+import (
+	"fmt"
+
+	"github.com/apachejuice/chomp/internal/book"
+	"github.com/apachejuice/chomp/internal/chomp"
+)
+
+var errNoBook = fmt.Errorf("no opening book is configured")
+
+// A book move as the opening explorer sends it to clients.
+type explorerMoveJson struct {
+	UCI    string `json:"uci"`
+	SAN    string `json:"san"`
+	Weight uint16 `json:"weight"`
+	// The share of the move in the weights of all moves of the position, between 0 and 1
+	Share float64 `json:"share"`
+}
+
+// The book moves of a position as the opening explorer sends them to clients.
+type explorerJson struct {
+	FEN   string             `json:"fen"`
+	Moves []explorerMoveJson `json:"moves"`
+}
+
+// Loads the opening book set in the configuration, or returns nil if there is none.
+func loadBook() (*book.Book, error) {
+	if config.EngineConfig == nil || config.EngineConfig.BookFile == "" {
+		return nil, nil
+	}
+
+	b, err := book.Open(config.EngineConfig.BookFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load opening book: %s", err.Error())
+	}
+
+	b.MaxPly = config.EngineConfig.BookDepth
+	slog.Printf("Loaded opening book %s with %d entries\n", config.EngineConfig.BookFile, b.Len())
+	return b, nil
+}
+
+// Returns the book moves of the current position of the game. The book's depth limit only applies
+// to the engine, so the explorer shows every move the book has.
+func explore(b *book.Book, g *chomp.Game) explorerJson {
+	moves := b.Moves(g)
+	total := 0
+	for _, m := range moves {
+		total += int(m.Weight)
+	}
+
+	j := explorerJson{FEN: g.FEN(), Moves: []explorerMoveJson{}}
+	for _, m := range moves {
+		san, err := g.SAN(m.Move)
+		if err != nil {
+			continue
+		}
+
+		share := 0.0
+		if total > 0 {
+			share = float64(m.Weight) / float64(total)
+		}
+
+		j.Moves = append(j.Moves, explorerMoveJson{UCI: m.Move.UCI(), SAN: san, Weight: m.Weight, Share: share})
+	}
+
+	return j
+}
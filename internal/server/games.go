@@ -0,0 +1,727 @@
+package server
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apachejuice/chomp/internal/book"
+	"github.com/apachejuice/chomp/internal/chomp"
+	"github.com/apachejuice/chomp/internal/clock"
+	"github.com/apachejuice/chomp/internal/engine"
+	"github.com/apachejuice/chomp/internal/syzygy"
+	uuid "github.com/satori/go.uuid"
+)
+
+// The name of the engine when it has a seat in a game.
+const enginePlayer = "engine"
+
+// How long the engine may think about a move. Weaker levels usually take less.
+const engineMoveTime = 2 * time.Second
+
+var (
+	errNoSuchGame  = fmt.Errorf("no such game")
+	errNotSeated   = fmt.Errorf("you are not playing in this game")
+	errNotYourTurn = fmt.Errorf("it is not your turn")
+	errGameFull    = fmt.Errorf("the game has no free seats")
+	errGameOver    = fmt.Errorf("the game is over")
+	errNoEngine    = fmt.Errorf("the engine only plays standard chess and Chess960")
+	errSeatTaken   = fmt.Errorf("the seat is taken")
+)
+
+// The variants of chess games can be played in.
+const (
+	variantStandard = "standard"
+	variantChess960 = "chess960"
+	variantBughouse = "bughouse"
+)
+
+// A game being played on the server, along with who is playing it.
+type ServerGame struct {
+	ID   string
+	Game *chomp.Game
+	// The usernames of the players, enginePlayer for the engine, or empty for a free seat
+	White, Black string
+	// The players of the second board of a Bughouse game, whose first board is Game.
+	// White's partner is Black2, and Black's partner is White2.
+	White2, Black2 string
+	// The strength level of the engine, if it's playing
+	Level int
+	// The variant of chess being played
+	Variant string
+	// The number of the starting position of a Chess960 game
+	StartIndex int
+	// The outcome the server has decided on, or OutcomeOngoing. Games are adjudicated when the tablebase
+	// says a player wins before the fifty-move rule can save the other one.
+	Adjudicated chomp.Outcome
+	// The time control of the game, or nil if it's untimed
+	Control clock.Control
+
+	engine    *engine.Engine
+	tablebase *syzygy.Tablebase
+	// The boards of a Bughouse game, or nil if the game has only one
+	match *chomp.BughouseMatch
+	// The clocks of each board, or nil if the game is untimed. They start once every seat is taken.
+	clocks []*clock.Clock
+	mu     sync.Mutex
+}
+
+// Keeps track of the games being played on the server.
+type GameManager struct {
+	games     map[string]*ServerGame
+	book      *book.Book
+	tablebase *syzygy.Tablebase
+	weights   *engine.Weights
+	// The time the clocks of the games are kept with
+	time clock.Source
+	mu   sync.Mutex
+}
+
+// Creates a game manager whose engines play from the given opening book and tablebase, which may be nil,
+// and evaluate with the given weights. The tablebase is also used to adjudicate games.
+func NewGameManager(b *book.Book, tb *syzygy.Tablebase, w *engine.Weights) *GameManager {
+	return &GameManager{games: make(map[string]*ServerGame), book: b, tablebase: tb, weights: w, time: clock.System}
+}
+
+// Creates a new game of the given variant with the given player in it. If color is ColorNone, the player gets
+// a random color. If level is not 0, the engine takes the other seat and plays at that strength level, otherwise
+// the seat is left free for another player to join. Chess960 games start from the position with the given number,
+// or a random one if it is negative. Games of the other variants can't be played against the engine.
+// The game is played with the given time control, or untimed if it is nil.
+func (m *GameManager) Create(player string, color chomp.Color, level int, variant string, start int,
+	control clock.Control) (*ServerGame, error) {
+	id, err := uuid.NewV4()
+	if err != nil {
+		return nil, err
+	}
+
+	g := &ServerGame{ID: id.String(), Game: chomp.NewGame(), Variant: variant, tablebase: m.tablebase}
+	switch variant {
+	case variantStandard:
+	case variantBughouse:
+		if level != 0 {
+			return nil, errNoEngine
+		}
+
+		g.match = chomp.NewBughouseMatch()
+		g.Game = g.match.Boards[0]
+		g.tablebase = nil
+	case variantChess960:
+		if start < 0 {
+			start = rand.Intn(chomp.Chess960Positions)
+		}
+
+		if g.Game, err = chomp.NewChess960Game(start); err != nil {
+			return nil, err
+		}
+
+		g.StartIndex = start
+	default:
+		if level != 0 {
+			return nil, errNoEngine
+		}
+
+		v, err := chomp.VariantByName(variant)
+		if err != nil {
+			return nil, err
+		}
+
+		if g.Game, err = chomp.NewVariantGame(v); err != nil {
+			return nil, err
+		}
+
+		// the tablebase knows only the rules of standard chess
+		g.tablebase = nil
+	}
+
+	if level != 0 {
+		l, err := engine.GetLevel(level)
+		if err != nil {
+			return nil, err
+		}
+
+		g.Level = level
+		g.engine = engine.New(l)
+		g.engine.Book = m.book
+		g.engine.Tablebase = m.tablebase
+		g.engine.Weights = m.weights
+	}
+
+	if control != nil {
+		g.Control = control
+		for b := 0; b < g.boards(); b++ {
+			c, err := clock.New(control, m.time)
+			if err != nil {
+				return nil, err
+			}
+
+			g.clocks = append(g.clocks, c)
+		}
+	}
+
+	if color == chomp.ColorNone {
+		color = chomp.ColorWhite
+		if rand.Intn(2) == 1 {
+			color = chomp.ColorBlack
+		}
+	}
+
+	opponent := ""
+	if g.engine != nil {
+		opponent = enginePlayer
+	}
+
+	g.White, g.Black = player, opponent
+	if color == chomp.ColorBlack {
+		g.White, g.Black = opponent, player
+	}
+
+	m.mu.Lock()
+	m.games[g.ID] = g
+	m.mu.Unlock()
+
+	slog.Printf("Created %s game %s: '%s' against '%s'\n", g.Variant, g.ID, g.White, g.Black)
+	g.mu.Lock()
+	g.startClocks()
+	g.playEngine()
+	g.mu.Unlock()
+	return g, nil
+}
+
+// Returns the game with the given ID.
+func (m *GameManager) Get(id string) (*ServerGame, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	g, ok := m.games[id]
+	if !ok {
+		return nil, errNoSuchGame
+	}
+
+	return g, nil
+}
+
+// Seats the player in a free seat of the game: the one at the given board and color, or the first free one
+// if board is negative and color is ColorNone. Only Bughouse games have a second board.
+func (g *ServerGame) Join(player string, board int, color chomp.Color) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if _, _, ok := g.seatOf(player); ok {
+		return fmt.Errorf("you are already playing in this game")
+	}
+
+	if board >= g.boards() {
+		return fmt.Errorf("the game has no board %d", board)
+	}
+
+	taken := false
+	for b := 0; b < g.boards(); b++ {
+		for _, c := range []chomp.Color{chomp.ColorWhite, chomp.ColorBlack} {
+			if (board >= 0 && b != board) || (color != chomp.ColorNone && c != color) {
+				continue
+			}
+
+			seat := g.seat(b, c)
+			if *seat != "" {
+				taken = true
+				continue
+			}
+
+			*seat = player
+			slog.Printf("User '%s' joined game %s on board %d as %s\n", player, g.ID, b, c.String())
+			g.startClocks()
+			return nil
+		}
+	}
+
+	if taken && (board >= 0 || color != chomp.ColorNone) {
+		return errSeatTaken
+	}
+
+	return errGameFull
+}
+
+// Makes a move for the player, written in any notation the game understands.
+// If the engine plays the other side, it starts thinking about its reply.
+func (g *ServerGame) Move(player, move string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	board, color, ok := g.seatOf(player)
+	if !ok {
+		return errNotSeated
+	}
+
+	if g.outcome() != chomp.OutcomeOngoing {
+		return errGameOver
+	}
+
+	game := g.board(board)
+	if game.Turn != color {
+		return errNotYourTurn
+	}
+
+	m, err := game.ParseMove(move)
+	if err != nil {
+		return err
+	}
+
+	if err = g.play(board, m); err != nil {
+		return err
+	}
+
+	g.adjudicate()
+	g.stopClocks()
+	g.playEngine()
+	return nil
+}
+
+// Makes the move on the board with the given index, and charges the time it took to the clock of the player
+// who made it, if the game is timed. The game must be locked.
+func (g *ServerGame) play(board int, m chomp.Move) error {
+	apply := func() error {
+		if g.match != nil {
+			return g.match.Apply(board, m)
+		}
+
+		return g.Game.Apply(m)
+	}
+
+	if g.clocks == nil {
+		return apply()
+	}
+
+	return g.clocks[board].Move(g.board(board).Turn, apply)
+}
+
+// Starts the clocks of a timed game once every seat of it is taken. The game must be locked.
+func (g *ServerGame) startClocks() {
+	for b := 0; b < g.boards(); b++ {
+		for _, c := range []chomp.Color{chomp.ColorWhite, chomp.ColorBlack} {
+			if *g.seat(b, c) == "" {
+				return
+			}
+		}
+	}
+
+	for b, c := range g.clocks {
+		c.Start(g.board(b).Turn)
+	}
+}
+
+// Stops the clocks of a timed game once it's over. The game must be locked.
+func (g *ServerGame) stopClocks() {
+	if g.outcome() == chomp.OutcomeOngoing {
+		return
+	}
+
+	for _, c := range g.clocks {
+		c.Stop()
+	}
+}
+
+// Returns a copy of the game on the board with the given index, which can be read while the game goes on.
+func (g *ServerGame) snapshot(board int) *chomp.Game {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.board(board).Clone()
+}
+
+// Returns the number of boards of the game: two for Bughouse and one otherwise.
+func (g *ServerGame) boards() int {
+	if g.match != nil {
+		return chomp.BughouseBoards
+	}
+
+	return 1
+}
+
+// Returns the game on the board with the given index.
+func (g *ServerGame) board(board int) *chomp.Game {
+	if g.match != nil {
+		return g.match.Boards[board]
+	}
+
+	return g.Game
+}
+
+// Returns the seat of the side of the given color on the board with the given index.
+func (g *ServerGame) seat(board int, c chomp.Color) *string {
+	switch {
+	case board == 1 && c == chomp.ColorBlack:
+		return &g.Black2
+	case board == 1:
+		return &g.White2
+	case c == chomp.ColorBlack:
+		return &g.Black
+	}
+
+	return &g.White
+}
+
+// Returns the board and color the player plays, or false if the player has no seat in the game.
+func (g *ServerGame) seatOf(player string) (int, chomp.Color, bool) {
+	for b := 0; b < g.boards(); b++ {
+		for _, c := range []chomp.Color{chomp.ColorWhite, chomp.ColorBlack} {
+			if *g.seat(b, c) == player {
+				return b, c, true
+			}
+		}
+	}
+
+	return 0, chomp.ColorNone, false
+}
+
+// Starts the engine thinking in the background if it's its turn. The game must be locked.
+func (g *ServerGame) playEngine() {
+	if g.engine == nil || g.outcome() != chomp.OutcomeOngoing || *g.seat(0, g.Game.Turn) != enginePlayer {
+		return
+	}
+
+	game := g.Game.Clone()
+	limits := engine.Limits{MoveTime: engineMoveTime}
+	if g.clocks != nil {
+		c := g.clocks[0]
+		limits = engine.Limits{
+			WhiteTime:      c.Remaining(chomp.ColorWhite),
+			BlackTime:      c.Remaining(chomp.ColorBlack),
+			WhiteIncrement: c.Stage(chomp.ColorWhite).Bonus,
+			BlackIncrement: c.Stage(chomp.ColorBlack).Bonus,
+			MovesToGo:      c.MovesToGo(game.Turn),
+		}
+	}
+
+	go func() {
+		result, err := g.engine.Search(game, limits)
+		if err != nil {
+			slog.Printf("Engine failed to move in game %s: %s\n", g.ID, err.Error())
+			return
+		}
+
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		if err := g.play(0, result.Move); err != nil {
+			slog.Printf("Engine failed to make its move in game %s: %s\n", g.ID, err.Error())
+			return
+		}
+
+		g.adjudicate()
+		g.stopClocks()
+	}()
+}
+
+// Returns the outcome of the game, taking adjudication and the clocks into account. The game must be locked.
+// The outcome of a Bughouse game is that of its match, where white winning means White and Black2 have won.
+func (g *ServerGame) outcome() chomp.Outcome {
+	if g.Adjudicated != chomp.OutcomeOngoing {
+		return g.Adjudicated
+	}
+
+	o := g.Game.Outcome()
+	if g.match != nil {
+		o = g.match.Outcome()
+	}
+
+	if o != chomp.OutcomeOngoing {
+		return o
+	}
+
+	board, flagged := g.flagged()
+	switch {
+	case flagged == chomp.ColorNone:
+		return chomp.OutcomeOngoing
+	case g.match == nil:
+		return g.Game.TimeoutOutcome(flagged)
+	case chomp.BughouseTeam(board, flagged) == 0:
+		return chomp.OutcomeBlackWins
+	}
+
+	return chomp.OutcomeWhiteWins
+}
+
+// Returns the board and color of the player who ran out of time, or ColorNone if no one has.
+// The game must be locked.
+func (g *ServerGame) flagged() (int, chomp.Color) {
+	for b, c := range g.clocks {
+		if flagged := c.Flagged(); flagged != chomp.ColorNone {
+			return b, flagged
+		}
+	}
+
+	return 0, chomp.ColorNone
+}
+
+// Ends the game if the tablebase says the player to move wins or loses in time to beat the fifty-move rule.
+// The game must be locked.
+func (g *ServerGame) adjudicate() {
+	if g.tablebase == nil || g.Game.IsOver() || !g.tablebase.Covers(g.Game) {
+		return
+	}
+
+	wdl, err := g.tablebase.ProbeWDL(g.Game)
+	if err != nil {
+		slog.Printf("Failed to probe the tablebase in game %s: %s\n", g.ID, err.Error())
+		return
+	}
+
+	if wdl != syzygy.WDLWin && wdl != syzygy.WDLLoss {
+		return
+	}
+
+	dtz, err := g.tablebase.ProbeDTZ(g.Game)
+	if err != nil {
+		slog.Printf("Failed to probe the tablebase in game %s: %s\n", g.ID, err.Error())
+		return
+	}
+
+	if dtz < 0 {
+		dtz = -dtz
+	}
+
+	// the distance can be off by one, so a win that only just makes it is left to be played out
+	if dtz+g.Game.HalfmoveClock > 99 {
+		return
+	}
+
+	winner := g.Game.Turn
+	if wdl == syzygy.WDLLoss {
+		winner = g.Game.Turn.Opposite()
+	}
+
+	g.Adjudicated = chomp.OutcomeWhiteWins
+	if winner == chomp.ColorBlack {
+		g.Adjudicated = chomp.OutcomeBlackWins
+	}
+
+	slog.Printf("Adjudicated game %s by tablebase: %s\n", g.ID, g.Adjudicated.String())
+}
+
+// The state of a game as sent to clients.
+type gameJson struct {
+	ID      string `json:"id"`
+	White   string `json:"white"`
+	Black   string `json:"black"`
+	Level   int    `json:"level,omitempty"`
+	Variant string `json:"variant"`
+	// The number of the starting position of a Chess960 game
+	StartIndex *int     `json:"startIndex,omitempty"`
+	FEN        string   `json:"fen"`
+	Moves      []string `json:"moves"`
+	Turn       string   `json:"turn"`
+	Outcome    string   `json:"outcome"`
+	DrawReason string   `json:"drawReason,omitempty"`
+	// Whether the outcome was decided by the tablebase rather than on the board
+	Adjudicated bool `json:"adjudicated,omitempty"`
+	// The color of the player who ran out of time, if the game ended that way
+	Timeout string `json:"timeout,omitempty"`
+	// The clocks of a timed game
+	Clock *clockJson `json:"clock,omitempty"`
+	// Both boards of a Bughouse game, the first of which is also described by the fields above.
+	// The outcome above is then that of the match, where white winning means the first team has won.
+	Boards []boardJson `json:"boards,omitempty"`
+	// The players of the two teams of a Bughouse game
+	Teams [][]string `json:"teams,omitempty"`
+}
+
+// The state of one board of a Bughouse game as sent to clients.
+type boardJson struct {
+	White      string   `json:"white"`
+	Black      string   `json:"black"`
+	FEN        string   `json:"fen"`
+	Moves      []string `json:"moves"`
+	Turn       string   `json:"turn"`
+	Outcome    string   `json:"outcome"`
+	DrawReason string   `json:"drawReason,omitempty"`
+	// The clocks of the board, if the game is timed
+	Clock *clockJson `json:"clock,omitempty"`
+}
+
+// The state of the clocks of a board as sent to clients.
+type clockJson struct {
+	Control string `json:"control"`
+	// The time each player has left, in milliseconds
+	White int64 `json:"white"`
+	Black int64 `json:"black"`
+	// The color whose clock is running, if one is
+	Running string `json:"running,omitempty"`
+}
+
+// Returns the state of the clocks of the board with the given index, or nil if the game is untimed.
+// The game must be locked.
+func (g *ServerGame) clockJson(board int) *clockJson {
+	if g.clocks == nil {
+		return nil
+	}
+
+	c := g.clocks[board]
+	j := &clockJson{
+		Control: g.Control.String(),
+		White:   c.Remaining(chomp.ColorWhite).Milliseconds(),
+		Black:   c.Remaining(chomp.ColorBlack).Milliseconds(),
+	}
+
+	if c.Running() != chomp.ColorNone && g.outcome() == chomp.OutcomeOngoing {
+		j.Running = c.Running().String()
+	}
+
+	return j
+}
+
+// Returns the moves of the game in UCI notation.
+func uciMoves(g *chomp.Game) []string {
+	moves := []string{}
+	for _, m := range g.Moves() {
+		moves = append(moves, m.UCI())
+	}
+
+	return moves
+}
+
+// Returns the state of the game to send to clients.
+func (g *ServerGame) toJson() gameJson {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	moves := uciMoves(g.Game)
+
+	j := gameJson{
+		ID:          g.ID,
+		White:       g.White,
+		Black:       g.Black,
+		Level:       g.Level,
+		Variant:     g.Variant,
+		FEN:         g.Game.FEN(),
+		Moves:       moves,
+		Turn:        g.Game.Turn.String(),
+		Outcome:     g.outcome().String(),
+		Adjudicated: g.Adjudicated != chomp.OutcomeOngoing,
+		Clock:       g.clockJson(0),
+	}
+
+	if _, flagged := g.flagged(); flagged != chomp.ColorNone && g.Adjudicated == chomp.OutcomeOngoing {
+		j.Timeout = flagged.String()
+	}
+
+	if g.Variant == variantChess960 {
+		start := g.StartIndex
+		j.StartIndex = &start
+	}
+
+	if g.Game.DrawReason != chomp.DrawReasonNone {
+		j.DrawReason = g.Game.DrawReason.String()
+	}
+
+	if g.match != nil {
+		for i, game := range g.match.Boards {
+			b := boardJson{
+				White:   *g.seat(i, chomp.ColorWhite),
+				Black:   *g.seat(i, chomp.ColorBlack),
+				FEN:     game.FEN(),
+				Moves:   uciMoves(game),
+				Turn:    game.Turn.String(),
+				Outcome: game.Outcome().String(),
+				Clock:   g.clockJson(i),
+			}
+
+			if game.DrawReason != chomp.DrawReasonNone {
+				b.DrawReason = game.DrawReason.String()
+			}
+
+			j.Boards = append(j.Boards, b)
+		}
+
+		j.Teams = [][]string{{g.White, g.Black2}, {g.Black, g.White2}}
+	}
+
+	return j
+}
+
+// Parses the color a player asked to play as. An empty string means either one.
+func parseColor(s string) (chomp.Color, error) {
+	switch s {
+	case "white":
+		return chomp.ColorWhite, nil
+	case "black":
+		return chomp.ColorBlack, nil
+	case "", "random":
+		return chomp.ColorNone, nil
+	}
+
+	return chomp.ColorNone, fmt.Errorf("invalid color '%s'", s)
+}
+
+// Parses the board of a Bughouse game a player asked to sit at, numbered from 0.
+// An empty string means any board, which is returned as -1.
+func parseBoard(s string) (int, error) {
+	if s == "" {
+		return -1, nil
+	}
+
+	board, err := strconv.Atoi(s)
+	if err != nil || board < 0 || board >= chomp.BughouseBoards {
+		return 0, fmt.Errorf("board must be a number from 0 to %d", chomp.BughouseBoards-1)
+	}
+
+	return board, nil
+}
+
+// Parses the variant a player asked to play. An empty string means standard chess.
+func parseVariant(s string) (string, error) {
+	switch strings.ToLower(s) {
+	case "", variantStandard:
+		return variantStandard, nil
+	case variantChess960, "960", "fischerandom":
+		return variantChess960, nil
+	}
+
+	v, err := chomp.VariantByName(s)
+	if err != nil {
+		return "", fmt.Errorf("invalid variant '%s'", s)
+	}
+
+	return v.Name(), nil
+}
+
+// Parses the number of the Chess960 starting position a player asked for. An empty string means a random one,
+// which is returned as -1.
+func parseStartIndex(s string) (int, error) {
+	if s == "" {
+		return -1, nil
+	}
+
+	start, err := strconv.Atoi(s)
+	if err != nil || start < 0 || start >= chomp.Chess960Positions {
+		return 0, fmt.Errorf("start index must be a number from 0 to %d", chomp.Chess960Positions-1)
+	}
+
+	return start, nil
+}
+
+// Parses the time control a player asked to play with, written as clock.ParseControl wants it.
+// An empty string means an untimed game, which is returned as nil.
+func parseControl(s string) (clock.Control, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	return clock.ParseControl(s)
+}
+
+// Parses the strength level of the engine a player asked to play against. An empty string means no engine.
+func parseLevel(s string) (int, error) {
+	if s == "" {
+		return 0, nil
+	}
+
+	level, err := strconv.Atoi(s)
+	if err != nil || level < 1 || level > len(engine.Levels) {
+		return 0, fmt.Errorf("level must be a number from 1 to %d", len(engine.Levels))
+	}
+
+	return level, nil
+}
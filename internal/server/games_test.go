@@ -0,0 +1,91 @@
+package server
+
+import (
+	"io"
+	"log"
+	"testing"
+
+	"github.com/apachejuice/chomp/internal/chomp"
+	"github.com/apachejuice/chomp/internal/syzygy"
+)
+
+func init() {
+	slog = log.New(io.Discard, logPrefix, log.LstdFlags)
+}
+
+// Returns a game between two players from the given position, adjudicated with the KQvK and KRvK tables
+// of the syzygy tests.
+func tablebaseGame(t *testing.T, fen string) *ServerGame {
+	t.Helper()
+
+	tb, err := syzygy.Open("../syzygy/testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { tb.Close() })
+	g, err := chomp.ParseFEN(fen)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &ServerGame{ID: "test", Game: g, White: "alice", Black: "bob", tablebase: tb}
+}
+
+func TestAdjudicate(t *testing.T) {
+	tests := []struct {
+		fen  string
+		want chomp.Outcome
+	}{
+		// wins and losses for the player to move
+		{"k7/8/2K5/8/8/8/8/7R w - - 0 1", chomp.OutcomeWhiteWins},
+		{"k7/8/1K6/8/8/8/8/7R b - - 0 1", chomp.OutcomeWhiteWins},
+		{"7r/8/8/8/8/1k6/8/K7 b - - 0 1", chomp.OutcomeBlackWins},
+		{"7r/8/8/8/8/1k6/8/K7 w - - 0 1", chomp.OutcomeBlackWins},
+		// the rook can be taken
+		{"8/8/8/8/8/8/k7/1R5K b - - 0 1", chomp.OutcomeOngoing},
+		// mate in two plus 96 plies is in time for the fifty-move rule, but plus 97 might not be
+		{"k7/8/2K5/8/8/8/8/7R w - - 96 80", chomp.OutcomeWhiteWins},
+		{"k7/8/2K5/8/8/8/8/7R w - - 97 80", chomp.OutcomeOngoing},
+		// too many pieces, castling rights and tables that are missing
+		{"k7/8/2K5/8/8/8/8/6QR w - - 0 1", chomp.OutcomeOngoing},
+		{"r3k3/8/8/8/8/8/8/4K3 w q - 0 1", chomp.OutcomeOngoing},
+		{"k7/8/2K5/8/8/8/8/7B w - - 0 1", chomp.OutcomeOngoing},
+	}
+
+	for _, test := range tests {
+		g := tablebaseGame(t, test.fen)
+		g.adjudicate()
+		if g.Adjudicated != test.want {
+			t.Errorf("%s: adjudicated %s, want %s", test.fen, g.Adjudicated, test.want)
+		}
+	}
+
+	// games without a tablebase are played out
+	g := tablebaseGame(t, "k7/8/2K5/8/8/8/8/7R w - - 0 1")
+	g.tablebase = nil
+	g.adjudicate()
+	if g.Adjudicated != chomp.OutcomeOngoing {
+		t.Errorf("adjudicated %s without a tablebase", g.Adjudicated)
+	}
+}
+
+func TestAdjudicateAfterMove(t *testing.T) {
+	// taking the queen leaves a rook that wins
+	g := tablebaseGame(t, "k7/8/2K5/8/8/8/8/q6R w - - 0 1")
+	if err := g.Move("alice", "Rxa1+"); err != nil {
+		t.Fatal(err)
+	}
+
+	if g.Adjudicated != chomp.OutcomeWhiteWins {
+		t.Fatalf("adjudicated %s after taking the queen", g.Adjudicated)
+	}
+
+	if err := g.Move("bob", "Kb8"); err != errGameOver {
+		t.Errorf("a move after adjudication gave the error %v, want %v", err, errGameOver)
+	}
+
+	if j := g.toJson(); j.Outcome != chomp.Outcome(chomp.OutcomeWhiteWins).String() || !j.Adjudicated {
+		t.Errorf("the game was sent with the outcome %s, adjudicated %t", j.Outcome, j.Adjudicated)
+	}
+}
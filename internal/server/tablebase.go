@@ -0,0 +1,77 @@
+package server
+
+import (
+	"fmt"
+
+	"github.com/apachejuice/chomp/internal/chomp"
+	"github.com/apachejuice/chomp/internal/syzygy"
+)
+
+var errNoTablebase = fmt.Errorf("no tablebase is configured")
+
+// A move of a probed position as the tablebase endpoint sends it to clients.
+type tablebaseMoveJson struct {
+	UCI string `json:"uci"`
+	SAN string `json:"san"`
+	// The result after the move, from the point of view of the player making it
+	WDL       string `json:"wdl"`
+	DTZ       int    `json:"dtz"`
+	Checkmate bool   `json:"checkmate,omitempty"`
+}
+
+// A probed position as the tablebase endpoint sends it to clients.
+type tablebaseJson struct {
+	FEN string `json:"fen"`
+	// The result for the player to move
+	WDL      string              `json:"wdl"`
+	DTZ      int                 `json:"dtz"`
+	BestMove *tablebaseMoveJson  `json:"bestMove,omitempty"`
+	Moves    []tablebaseMoveJson `json:"moves"`
+}
+
+// Loads the Syzygy tablebase set in the configuration, or returns nil if there is none.
+func loadTablebase() (*syzygy.Tablebase, error) {
+	if config.EngineConfig == nil || config.EngineConfig.SyzygyPath == "" {
+		return nil, nil
+	}
+
+	tb, err := syzygy.Open(config.EngineConfig.SyzygyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tablebase: %s", err.Error())
+	}
+
+	slog.Printf("Loaded Syzygy tablebase from %s for up to %d pieces\n", config.EngineConfig.SyzygyPath, tb.MaxPieces())
+	return tb, nil
+}
+
+// Probes the current position of the game and every move from it.
+func probe(tb *syzygy.Tablebase, g *chomp.Game) (tablebaseJson, error) {
+	r, err := tb.Probe(g)
+	if err != nil {
+		return tablebaseJson{}, err
+	}
+
+	j := tablebaseJson{FEN: g.FEN(), WDL: r.WDL.String(), DTZ: r.DTZ, Moves: []tablebaseMoveJson{}}
+	for _, m := range r.Moves {
+		san, err := g.SAN(m.Move)
+		if err != nil {
+			continue
+		}
+
+		j.Moves = append(j.Moves, tablebaseMoveJson{
+			UCI:       m.Move.UCI(),
+			SAN:       san,
+			WDL:       m.WDL.String(),
+			DTZ:       m.DTZ,
+			Checkmate: m.Checkmate,
+		})
+	}
+
+	// the moves are sorted best first
+	if len(j.Moves) > 0 {
+		best := j.Moves[0]
+		j.BestMove = &best
+	}
+
+	return j, nil
+}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/apachejuice/chomp/internal/chomp"
+)
+
+// A problem of a position as the validation endpoint sends it to clients.
+type problemJson struct {
+	Kind        string   `json:"kind"`
+	Color       string   `json:"color,omitempty"`
+	Positions   []string `json:"positions"`
+	Description string   `json:"description"`
+}
+
+// The result of validating a position as the validation endpoint sends it to clients.
+type validationJson struct {
+	FEN      string        `json:"fen"`
+	Valid    bool          `json:"valid"`
+	Problems []problemJson `json:"problems"`
+}
+
+// Validates the position of the game.
+func validate(g *chomp.Game) validationJson {
+	problems := g.Validate()
+	j := validationJson{FEN: g.FEN(), Valid: len(problems) == 0, Problems: []problemJson{}}
+	for _, p := range problems {
+		pj := problemJson{Kind: p.Kind.String(), Positions: []string{}, Description: p.String()}
+		if p.Color != chomp.ColorNone {
+			pj.Color = p.Color.String()
+		}
+
+		for _, pos := range p.Positions {
+			pj.Positions = append(pj.Positions, pos.Name())
+		}
+
+		j.Problems = append(j.Problems, pj)
+	}
+
+	return j
+}
+
+// Parses a position sent by a client, returning an error listing its problems if it is not a valid one.
+func parseValidFEN(fen string) (*chomp.Game, error) {
+	g, err := chomp.ParseFEN(fen)
+	if err != nil {
+		return nil, err
+	}
+
+	problems := g.Validate()
+	if len(problems) == 0 {
+		return g, nil
+	}
+
+	descriptions := make([]string, len(problems))
+	for i, p := range problems {
+		descriptions[i] = p.String()
+	}
+
+	return nil, fmt.Errorf("invalid position: %s", strings.Join(descriptions, "; "))
+}
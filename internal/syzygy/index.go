@@ -0,0 +1,129 @@
+package syzygy
+
+// The lookup tables used to turn a position into its index in a table. They follow the encoding of the
+// tablebase generator, which maps every legal placement of the pieces, up to symmetry, to a unique number.
+var (
+	// Maps the squares below the a1-h8 diagonal to 0..27
+	mapB1H1H7 [64]int
+	// Maps the squares of the a1-d1-d4 triangle to 0..9, the ones on the diagonal last
+	mapA1D1D4 [64]int
+	// Maps the 462 legal placements of two kings, the first one in the a1-d1-d4 triangle, to 0..461
+	mapKK [10][64]int
+	// Maps the pawn squares a2-h7 to 0..47, the squares closest to the a and h files and the lowest ranks highest
+	mapPawns [64]int
+	// The number of ways to choose k of n things, indexed [k][n]
+	binomial [7][64]uint64
+	// The index of the leading pawn group by the number of pawns in it and the square of the leading pawn
+	leadPawnIdx [6][64]uint64
+	// The number of placements of the leading pawn group by the number of pawns in it and the file of the leading pawn
+	leadPawnsSize [6][4]uint64
+)
+
+// Returns how far the square is above the a1-h8 diagonal: positive above it, negative below it and 0 on it.
+func offDiagonal(sq int) int {
+	return sq>>3 - sq&7
+}
+
+// Mirrors the square along the a1-h8 diagonal.
+func flipDiagonal(sq int) int {
+	return (sq>>3 | sq<<3) & 63
+}
+
+// Returns whether two squares are the same or next to each other.
+func kingsTouch(a, b int) bool {
+	dx, dy := a&7-b&7, a>>3-b>>3
+	return dx >= -1 && dx <= 1 && dy >= -1 && dy <= 1
+}
+
+func init() {
+	code := 0
+	for sq := 0; sq < 64; sq++ {
+		if offDiagonal(sq) < 0 {
+			mapB1H1H7[sq] = code
+			code++
+		}
+	}
+
+	code = 0
+	diagonal := []int{}
+	for rank := 0; rank < 4; rank++ {
+		for file := 0; file < 4; file++ {
+			sq := rank*8 + file
+			if offDiagonal(sq) < 0 {
+				mapA1D1D4[sq] = code
+				code++
+			} else if offDiagonal(sq) == 0 {
+				diagonal = append(diagonal, sq)
+			}
+		}
+	}
+
+	for _, sq := range diagonal {
+		mapA1D1D4[sq] = code
+		code++
+	}
+
+	// placements with both kings on the diagonal come last
+	type placement struct{ idx, sq int }
+	bothOnDiagonal := []placement{}
+	code = 0
+	for idx := 0; idx < 10; idx++ {
+		for k1 := 0; k1 < 28; k1++ {
+			// b1 is mapped to 0 like the squares outside the triangle, which are left out
+			if mapA1D1D4[k1] != idx || (idx == 0 && k1 != 1) {
+				continue
+			}
+
+			for k2 := 0; k2 < 64; k2++ {
+				switch {
+				case kingsTouch(k1, k2):
+				case offDiagonal(k1) == 0 && offDiagonal(k2) > 0:
+					// the first king is on the diagonal, so the second one can be mirrored below it
+				case offDiagonal(k1) == 0 && offDiagonal(k2) == 0:
+					bothOnDiagonal = append(bothOnDiagonal, placement{idx, k2})
+				default:
+					mapKK[idx][k2] = code
+					code++
+				}
+			}
+		}
+	}
+
+	for _, p := range bothOnDiagonal {
+		mapKK[p.idx][p.sq] = code
+		code++
+	}
+
+	binomial[0][0] = 1
+	for n := 1; n < 64; n++ {
+		for k := 0; k < len(binomial) && k <= n; k++ {
+			if k > 0 {
+				binomial[k][n] += binomial[k-1][n-1]
+			}
+
+			if k < n {
+				binomial[k][n] += binomial[k][n-1]
+			}
+		}
+	}
+
+	available := 47
+	for count := 1; count < len(leadPawnIdx); count++ {
+		for file := 0; file < 4; file++ {
+			idx := uint64(0)
+			for rank := 1; rank < 7; rank++ {
+				sq := rank*8 + file
+				if count == 1 {
+					mapPawns[sq] = available
+					mapPawns[sq^7] = available - 1
+					available -= 2
+				}
+
+				leadPawnIdx[count][sq] = idx
+				idx += binomial[count-1][mapPawns[sq]]
+			}
+
+			leadPawnsSize[count][file] = idx
+		}
+	}
+}
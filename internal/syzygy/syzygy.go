@@ -0,0 +1,469 @@
+// Package syzygy probes Syzygy endgame tablebases, which hold the result of perfect play for every position
+// with up to seven pieces. WDL tables (.rtbw) tell whether a position is won, drawn or lost, and DTZ tables
+// (.rtbz) how far it is to the next capture or pawn move that keeps the result, which is enough to play
+// the position perfectly.
+package syzygy
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/apachejuice/chomp/internal/chomp"
+)
+
+// Represents the result of a position with perfect play, from the point of view of the player to move.
+// Cursed wins and blessed losses are wins and losses that take too long to be won under the fifty-move rule.
+type WDL int8
+
+const (
+	WDLLoss        WDL = -2
+	WDLBlessedLoss WDL = -1
+	WDLDraw        WDL = 0
+	WDLCursedWin   WDL = 1
+	WDLWin         WDL = 2
+)
+
+var wdlNames = map[WDL]string{
+	WDLLoss:        "loss",
+	WDLBlessedLoss: "blessed loss",
+	WDLDraw:        "draw",
+	WDLCursedWin:   "cursed win",
+	WDLWin:         "win",
+}
+
+func (w WDL) String() string {
+	name, ok := wdlNames[w]
+	if !ok {
+		return "<invalid>"
+	}
+
+	return name
+}
+
+var (
+	errNoTables      = fmt.Errorf("no tablebase files found")
+	errTooManyPieces = fmt.Errorf("the position has too many pieces for the tablebase")
+	errCastling      = fmt.Errorf("positions with castling rights are not in the tablebase")
+)
+
+// A set of Syzygy tables. Tables are opened the first time they are needed, and can be probed
+// from several goroutines at once.
+type Tablebase struct {
+	// the tables by their name and by the name with the colors swapped
+	tables    [2]map[string]*table
+	maxPieces int
+}
+
+// Finds the tables in the given directories, which are separated like in the PATH environment variable.
+// If a table is in more than one of them, the one in the first directory is used.
+func Open(dirs string) (*Tablebase, error) {
+	t := &Tablebase{tables: [2]map[string]*table{make(map[string]*table), make(map[string]*table)}}
+	for _, dir := range filepath.SplitList(dirs) {
+		if dir == "" {
+			continue
+		}
+
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, e := range entries {
+			ext := filepath.Ext(e.Name())
+			for kind, extension := range tableExtensions {
+				if ext != extension {
+					continue
+				}
+
+				tb, ok := newTable(filepath.Join(dir, e.Name()), strings.TrimSuffix(e.Name(), ext), tableKind(kind))
+				if !ok {
+					continue
+				}
+
+				tables := t.tables[kind]
+				if _, ok := tables[tb.key]; ok {
+					continue
+				}
+
+				tables[tb.key], tables[tb.key2] = tb, tb
+				if tb.kind == kindWDL && tb.pieceCount > t.maxPieces {
+					t.maxPieces = tb.pieceCount
+				}
+			}
+		}
+	}
+
+	if len(t.tables[kindWDL]) == 0 {
+		return nil, errNoTables
+	}
+
+	return t, nil
+}
+
+// Returns the most pieces a position can have to be in the tablebase, kings included.
+// Smaller positions may still be missing if not all tables of their size are there.
+func (t *Tablebase) MaxPieces() int {
+	return t.maxPieces
+}
+
+// Closes the files of the tables. The tablebase can't be used afterwards.
+func (t *Tablebase) Close() error {
+	var err error
+	for _, tables := range t.tables {
+		for key, tb := range tables {
+			// each table is in the map twice, but there is only one file to close
+			if key != tb.key {
+				continue
+			}
+
+			if e := tb.close(); e != nil {
+				err = e
+			}
+		}
+	}
+
+	return err
+}
+
+// Returns whether the position of the game can be probed: it must have few enough pieces and no castling rights.
+func (t *Tablebase) Covers(g *chomp.Game) bool {
+	return t.check(g) == nil
+}
+
+func (t *Tablebase) check(g *chomp.Game) error {
+	if g.Castling != chomp.CastlingNone {
+		return errCastling
+	}
+
+	if positionOf(g).pieces > t.maxPieces {
+		return errTooManyPieces
+	}
+
+	return nil
+}
+
+// Returns the result of the position of the game with perfect play.
+func (t *Tablebase) ProbeWDL(g *chomp.Game) (WDL, error) {
+	if err := t.check(g); err != nil {
+		return WDLDraw, err
+	}
+
+	wdl, _, err := t.search(g.Clone(), false)
+	return wdl, err
+}
+
+// Returns the distance to zeroing of the position of the game: how many plies it takes with perfect play until
+// the next capture or pawn move, after which the player to move still wins or the opponent still loses.
+// The distance is positive if the player to move wins, negative if they lose and 0 if the position is drawn.
+// Cursed wins and blessed losses are counted 100 plies further away, like in the tables.
+func (t *Tablebase) ProbeDTZ(g *chomp.Game) (int, error) {
+	if err := t.check(g); err != nil {
+		return 0, err
+	}
+
+	return t.probeDTZ(g.Clone())
+}
+
+// The result of a move of a probed position, from the point of view of the player making it.
+type MoveResult struct {
+	Move chomp.Move `json:"move"`
+	// The result of the game after the move
+	WDL WDL `json:"wdl"`
+	// The distance to zeroing counted from before the move, with the sign of the result
+	DTZ int `json:"dtz"`
+	// Whether the move checkmates
+	Checkmate bool `json:"checkmate"`
+}
+
+// The result of probing a position and all of its moves.
+type Result struct {
+	WDL WDL `json:"wdl"`
+	DTZ int `json:"dtz"`
+	// The legal moves of the position, the best first. A winning move is better the sooner it zeroes,
+	// and a losing one the longer it holds out.
+	Moves []MoveResult `json:"moves"`
+}
+
+// Returns the best move of the result, or false if the position has no legal moves.
+func (r Result) BestMove() (chomp.Move, bool) {
+	if len(r.Moves) == 0 {
+		return chomp.Move{}, false
+	}
+
+	return r.Moves[0].Move, true
+}
+
+// Probes the position of the game and every move from it.
+func (t *Tablebase) Probe(g *chomp.Game) (Result, error) {
+	if err := t.check(g); err != nil {
+		return Result{}, err
+	}
+
+	g = g.Clone()
+	var r Result
+	var err error
+	if r.WDL, _, err = t.search(g, false); err != nil {
+		return Result{}, err
+	}
+
+	if r.DTZ, err = t.probeDTZ(g); err != nil {
+		return Result{}, err
+	}
+
+	r.Moves = []MoveResult{}
+	for _, m := range g.LegalMoves() {
+		g.MakeMove(m)
+		mr, err := t.probeMove(g, m)
+		g.Undo()
+		if err != nil {
+			return Result{}, err
+		}
+
+		r.Moves = append(r.Moves, mr)
+	}
+
+	sort.SliceStable(r.Moves, func(i, j int) bool {
+		a, b := r.Moves[i], r.Moves[j]
+		switch {
+		case a.WDL != b.WDL:
+			return a.WDL > b.WDL
+		case a.Checkmate != b.Checkmate:
+			return a.Checkmate
+		}
+
+		// the distance is negative for losses, so a longer loss is a lower number too
+		return a.WDL != WDLDraw && a.DTZ < b.DTZ
+	})
+
+	return r, nil
+}
+
+// Returns the result of the move that was just made in the game.
+func (t *Tablebase) probeMove(g *chomp.Game, m chomp.Move) (MoveResult, error) {
+	mr := MoveResult{Move: m}
+	wdl, _, err := t.search(g, false)
+	if err != nil {
+		return mr, err
+	}
+
+	mr.WDL = -wdl
+	if g.HalfmoveClock == 0 {
+		// the move zeroes, so it's the end of the distance
+		mr.DTZ = dtzBeforeZeroing(mr.WDL)
+	} else {
+		dtz, err := t.probeDTZ(g)
+		if err != nil {
+			return mr, err
+		}
+
+		mr.DTZ = -dtz + signOf(-dtz)
+	}
+
+	if g.Board.InCheck(g.Turn) && len(g.LegalMoves()) == 0 {
+		mr.Checkmate = true
+		mr.DTZ = 1
+	}
+
+	return mr, nil
+}
+
+// Returns the result of the position, making sure of it with the captures the tables don't store, and, if
+// zeroing is set, the pawn moves too. Returns true if the best move is one of those, in which case the DTZ
+// tables can't be trusted.
+func (t *Tablebase) search(g *chomp.Game, zeroing bool) (WDL, bool, error) {
+	best := WDLLoss
+	moves := g.LegalMoves()
+	searched := 0
+	for _, m := range moves {
+		if !g.IsCapture(m) && (!zeroing || g.Board.At(m.From).Type() != chomp.PieceTypePawn) {
+			continue
+		}
+
+		searched++
+		g.MakeMove(m)
+		wdl, _, err := t.search(g, false)
+		g.Undo()
+		if err != nil {
+			return WDLDraw, false, err
+		}
+
+		if -wdl > best {
+			best = -wdl
+			if best == WDLWin {
+				return best, true, nil
+			}
+		}
+	}
+
+	// if every move has been searched, the tables aren't needed, and they might be wrong anyway:
+	// they don't know about en passant, and store whatever compresses best when a capture wins
+	allSearched := searched > 0 && searched == len(moves)
+	wdl := best
+	if !allSearched {
+		value, _, err := t.probeTable(g, kindWDL, WDLDraw)
+		if err != nil {
+			return WDLDraw, false, err
+		}
+
+		wdl = WDL(value)
+	}
+
+	if best >= wdl {
+		return best, best > WDLDraw || allSearched, nil
+	}
+
+	return wdl, false, nil
+}
+
+func (t *Tablebase) probeDTZ(g *chomp.Game) (int, error) {
+	wdl, zeroingBest, err := t.search(g, true)
+	if err != nil || wdl == WDLDraw {
+		return 0, err
+	}
+
+	if zeroingBest {
+		return dtzBeforeZeroing(wdl), nil
+	}
+
+	dtz, ok, err := t.probeTable(g, kindDTZ, wdl)
+	if err != nil {
+		return 0, err
+	}
+
+	if ok {
+		if wdl == WDLCursedWin || wdl == WDLBlessedLoss {
+			dtz += 100
+		}
+
+		return dtz * signOf(int(wdl)), nil
+	}
+
+	// the table is only for the other side to move, so the distance is found from the moves
+	best := 0xffff
+	for _, m := range g.LegalMoves() {
+		zeroing := g.IsCapture(m) || g.Board.At(m.From).Type() == chomp.PieceTypePawn
+		g.MakeMove(m)
+
+		var dtz int
+		if zeroing {
+			var w WDL
+			w, _, err = t.search(g, false)
+			dtz = -dtzBeforeZeroing(w)
+		} else {
+			dtz, err = t.probeDTZ(g)
+			dtz = -dtz
+		}
+
+		if dtz == 1 && g.Board.InCheck(g.Turn) && len(g.LegalMoves()) == 0 {
+			best = 1
+		}
+
+		g.Undo()
+		if err != nil {
+			return 0, err
+		}
+
+		// the distance of a zeroing move is already the one from before it
+		if !zeroing {
+			dtz += signOf(dtz)
+		}
+
+		if dtz < best && signOf(dtz) == signOf(int(wdl)) {
+			best = dtz
+		}
+	}
+
+	// without legal moves, the player to move is mated
+	if best == 0xffff {
+		return -1, nil
+	}
+
+	return best, nil
+}
+
+// Looks up the position of the game in the table of its pieces.
+func (t *Tablebase) probeTable(g *chomp.Game, kind tableKind, wdl WDL) (int, bool, error) {
+	p := positionOf(g)
+	if p.pieces == 2 {
+		// two kings are a draw, and have no table
+		return int(WDLDraw), true, nil
+	}
+
+	tb, ok := t.tables[kind][p.key]
+	if !ok {
+		return 0, false, fmt.Errorf("missing table %s%s", p.key, tableExtensions[kind])
+	}
+
+	if err := tb.open(); err != nil {
+		return 0, false, err
+	}
+
+	return tb.probe(&p, wdl)
+}
+
+// Returns the distance to zeroing of a position whose best move zeroes.
+func dtzBeforeZeroing(wdl WDL) int {
+	switch wdl {
+	case WDLWin:
+		return 1
+	case WDLCursedWin:
+		return 101
+	case WDLBlessedLoss:
+		return -101
+	case WDLLoss:
+		return -1
+	}
+
+	return 0
+}
+
+func signOf(n int) int {
+	switch {
+	case n > 0:
+		return 1
+	case n < 0:
+		return -1
+	}
+
+	return 0
+}
+
+// A position as the tables see it.
+type position struct {
+	// the pieces of the tables' encoding, or 0 for empty squares, by square from a1 to h8
+	board [64]byte
+	// the name of the table of the pieces, with the pieces of white first
+	key         string
+	blackToMove bool
+	pieces      int
+}
+
+func positionOf(g *chomp.Game) position {
+	p := position{blackToMove: g.Turn == chomp.ColorBlack}
+	var counts [12]int
+	for x := 0; x < 8; x++ {
+		for y := 0; y < 8; y++ {
+			piece := g.Board.Grid[x][y]
+			if piece == chomp.PieceNone {
+				continue
+			}
+
+			p.board[y*8+x] = tablePieces[piece]
+			p.pieces++
+			counts[piece]++
+		}
+	}
+
+	sides := [2]string{}
+	for i, c := range []chomp.Color{chomp.ColorWhite, chomp.ColorBlack} {
+		for j, t := range tableTypes {
+			sides[i] += strings.Repeat(tableLetters[j:j+1], counts[chomp.MakePiece(t, c)])
+		}
+	}
+
+	p.key = sides[0] + "v" + sides[1]
+	return p
+}
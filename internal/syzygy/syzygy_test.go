@@ -0,0 +1,780 @@
+package syzygy
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/apachejuice/chomp/internal/chomp"
+)
+
+var update = flag.Bool("update", false, "rewrite the tables in testdata with the ones worked out by the tests")
+
+// The number of positions in each side of a table of two kings and one other piece.
+const threePieceSize = 31332
+
+// Returns the index of a position of a table of three unique pieces without pawns, given the squares of the
+// pieces in the order of the table. It follows the description of the format rather than the code of the
+// package: the board is mirrored until the first piece is in the a1-d1-d4 triangle and the first of the pieces
+// off the a1-h8 diagonal is below it, and the placements are numbered with the ones with more pieces on the
+// diagonal last.
+func referenceIndex(squares [3]int) uint64 {
+	rank := func(sq int) int { return sq / 8 }
+	file := func(sq int) int { return sq % 8 }
+	if file(squares[0]) > 3 {
+		for i := range squares {
+			squares[i] = rank(squares[i])*8 + 7 - file(squares[i])
+		}
+	}
+
+	if rank(squares[0]) > 3 {
+		for i := range squares {
+			squares[i] = (7-rank(squares[i]))*8 + file(squares[i])
+		}
+	}
+
+	for _, sq := range squares {
+		if rank(sq) == file(sq) {
+			continue
+		}
+
+		if rank(sq) > file(sq) {
+			for i := range squares {
+				squares[i] = file(squares[i])*8 + rank(squares[i])
+			}
+		}
+
+		break
+	}
+
+	// the squares below the diagonal are numbered from b1 to h1, then from c2 to h2 and so on
+	below := func(sq int) int {
+		n := 0
+		for r := 0; r < rank(sq); r++ {
+			n += 7 - r
+		}
+
+		return n + file(sq) - rank(sq) - 1
+	}
+
+	// b1, c1, d1, c2, d2 and d3 are the squares of the triangle below the diagonal
+	triangle := map[int]int{1: 0, 2: 1, 3: 2, 10: 3, 11: 4, 19: 5}
+	s0, s1, s2 := squares[0], squares[1], squares[2]
+	adjust1, adjust2 := 0, 0
+	if s1 > s0 {
+		adjust1 = 1
+	}
+
+	for _, sq := range []int{s0, s1} {
+		if s2 > sq {
+			adjust2++
+		}
+	}
+
+	onDiagonal := func(sq int) bool { return rank(sq) == file(sq) }
+	var idx int
+	switch {
+	case !onDiagonal(s0):
+		idx = (triangle[s0]*63+s1-adjust1)*62 + s2 - adjust2
+	case !onDiagonal(s1):
+		idx = (6*63+rank(s0)*28+below(s1))*62 + s2 - adjust2
+	case !onDiagonal(s2):
+		idx = 6*63*62 + 4*28*62 + rank(s0)*7*28 + (rank(s1)-adjust1)*28 + below(s2)
+	default:
+		idx = 6*63*62 + 4*28*62 + 4*7*28 + rank(s0)*7*6 + (rank(s1)-adjust1)*6 + rank(s2) - adjust2
+	}
+
+	return uint64(idx)
+}
+
+// Returns the square with the given name, like e4, numbered from a1 to h8.
+func squareOf(name string) int {
+	return int(name[1]-'1')*8 + int(name[0]-'a')
+}
+
+func TestReferenceIndex(t *testing.T) {
+	// worked out by hand, with the white king, the other white piece and the black king in that order
+	tests := []struct {
+		squares [3]string
+		idx     uint64
+	}{
+		// the first piece below the diagonal: (0*63 + 18-1)*62 + 63-2
+		{[3]string{"b1", "c3", "h8"}, 1115},
+		// mirrored to b2, d3 and h8, with the second piece below the diagonal: (6*63 + 1*28 + 13)*62 + 63-2
+		{[3]string{"g7", "e6", "a1"}, 26039},
+		// mirrored along the diagonal to a1, b1 and h3: (6*63 + 0*28 + 0)*62 + 23-2
+		{[3]string{"a1", "a2", "c8"}, 23457},
+		// the third piece below the diagonal: 6*63*62 + 4*28*62 + 0*7*28 + (1-1)*28 + 6
+		{[3]string{"a1", "b2", "h1"}, 30386},
+		// all on the diagonal: 6*63*62 + 4*28*62 + 4*7*28 + 0*7*6 + (1-1)*6 + 7-2
+		{[3]string{"a1", "b2", "h8"}, 31169},
+	}
+
+	for _, test := range tests {
+		squares := [3]int{squareOf(test.squares[0]), squareOf(test.squares[1]), squareOf(test.squares[2])}
+		if idx := referenceIndex(squares); idx != test.idx {
+			t.Errorf("%v: got %d, want %d", test.squares, idx, test.idx)
+		}
+	}
+}
+
+// The positions of a table of two kings and one other white piece, worked out from the rules.
+type threePieceTable struct {
+	piece chomp.PieceType
+	// the WDL values plus 2 by side to move and index
+	wdl [2][]byte
+	// the distance to mate of the positions with white to move, in moves minus one, by index
+	dtz []byte
+}
+
+// Returns the placement of the white king, the white piece and the black king with the given number.
+func placementOf(n int) (wk, piece, bk int) {
+	return n >> 12, n >> 6 & 63, n & 63
+}
+
+func boardOf(t chomp.PieceType, wk, piece, bk int) chomp.Board {
+	b := chomp.EmptyBoard()
+	b.Grid[wk%8][wk/8] = chomp.MakePiece(chomp.PieceTypeKing, chomp.ColorWhite)
+	b.Grid[piece%8][piece/8] = chomp.MakePiece(t, chomp.ColorWhite)
+	b.Grid[bk%8][bk/8] = chomp.MakePiece(chomp.PieceTypeKing, chomp.ColorBlack)
+	return b
+}
+
+// Works out every position of a table of two kings and a white queen or rook by retrograde analysis. The white
+// piece always wins, unless the black king can take it or is stalemated, so the distance to zeroing of the
+// winning side is the distance to mate.
+func solveThreePieces(t chomp.PieceType) threePieceTable {
+	const placements = 64 * 64 * 64
+	whiteMoves := make([][]int32, placements)
+	blackMoves := make([][]int32, placements)
+	// the plies to mate with white to move, and the plies until mated plus one with black to move, or 0 if unknown
+	whiteWin := make([]int, placements)
+	blackLoss := make([]int, placements)
+	blackDraw := make([]bool, placements)
+	for n := 0; n < placements; n++ {
+		wk, piece, bk := placementOf(n)
+		if wk == piece || wk == bk || piece == bk || kingsTouch(wk, bk) {
+			continue
+		}
+
+		b := boardOf(t, wk, piece, bk)
+		for _, m := range b.LegalMoves(chomp.ColorBlack) {
+			to := int(m.To.Y)*8 + int(m.To.X)
+			if to == piece {
+				// taking the piece leaves two kings
+				blackDraw[n] = true
+				continue
+			}
+
+			blackMoves[n] = append(blackMoves[n], int32(wk<<12|piece<<6|to))
+		}
+
+		switch {
+		case len(blackMoves[n]) == 0 && !blackDraw[n] && b.InCheck(chomp.ColorBlack):
+			blackLoss[n] = 1
+		case len(blackMoves[n]) == 0:
+			blackDraw[n] = true
+		}
+
+		if b.InCheck(chomp.ColorBlack) {
+			// white can't be to move with black in check
+			continue
+		}
+
+		for _, m := range b.LegalMoves(chomp.ColorWhite) {
+			from, to := int(m.From.Y)*8+int(m.From.X), int(m.To.Y)*8+int(m.To.X)
+			if from == wk {
+				whiteMoves[n] = append(whiteMoves[n], int32(to<<12|piece<<6|bk))
+			} else {
+				whiteMoves[n] = append(whiteMoves[n], int32(wk<<12|to<<6|bk))
+			}
+		}
+	}
+
+	// the positions are solved a ply at a time, so the first win found is the fastest one
+	// and the last successor of a loss to be solved the slowest one
+	for plies := 1; ; plies += 2 {
+		solved := false
+		for n, moves := range whiteMoves {
+			for _, m := range moves {
+				if whiteWin[n] == 0 && blackLoss[m] == plies {
+					whiteWin[n], solved = plies, true
+				}
+			}
+		}
+
+		for n, moves := range blackMoves {
+			if blackLoss[n] != 0 || blackDraw[n] || len(moves) == 0 {
+				continue
+			}
+
+			longest := 0
+			for _, m := range moves {
+				if whiteWin[m] == 0 {
+					longest = -1
+					break
+				}
+
+				if whiteWin[m] > longest {
+					longest = whiteWin[m]
+				}
+			}
+
+			if longest == plies {
+				blackLoss[n], solved = plies+2, true
+			}
+		}
+
+		if !solved {
+			break
+		}
+	}
+
+	tb := threePieceTable{piece: t}
+	tb.wdl = [2][]byte{make([]byte, threePieceSize), make([]byte, threePieceSize)}
+	tb.dtz = make([]byte, threePieceSize)
+	for i := range tb.dtz {
+		tb.wdl[0][i], tb.wdl[1][i] = byte(WDLWin+2), byte(WDLDraw+2)
+	}
+
+	for n := 0; n < placements; n++ {
+		wk, piece, bk := placementOf(n)
+		if wk == piece || wk == bk || piece == bk || kingsTouch(wk, bk) {
+			continue
+		}
+
+		idx := referenceIndex([3]int{wk, piece, bk})
+		if blackLoss[n] != 0 {
+			tb.wdl[1][idx] = byte(WDLLoss + 2)
+		}
+
+		if whiteWin[n] != 0 {
+			tb.dtz[idx] = byte(whiteWin[n] / 2)
+		}
+	}
+
+	return tb
+}
+
+var (
+	solveOnce sync.Once
+	solved    map[string]threePieceTable
+)
+
+// Returns the solved tables by name.
+func solvedTables() map[string]threePieceTable {
+	solveOnce.Do(func() {
+		solved = map[string]threePieceTable{
+			"KQvK": solveThreePieces(chomp.PieceTypeQueen),
+			"KRvK": solveThreePieces(chomp.PieceTypeRook),
+		}
+	})
+
+	return solved
+}
+
+// Writes bits into bytes, the highest bit first.
+type bitWriter struct {
+	bytes []byte
+	bits  int
+}
+
+func (w *bitWriter) write(code, length int) {
+	for i := length - 1; i >= 0; i-- {
+		if w.bits%8 == 0 {
+			w.bytes = append(w.bytes, 0)
+		}
+
+		if code>>uint(i)&1 == 1 {
+			w.bytes[len(w.bytes)-1] |= 1 << uint(7-w.bits%8)
+		}
+
+		w.bits++
+	}
+}
+
+// A canonical Huffman code in which every value is a symbol of its own.
+type testCode struct {
+	minLength, maxLength int
+	// the lowest symbol of each length from the shortest one
+	lowest []uint16
+	// the code and its length by symbol
+	codes []struct{ code, length int }
+}
+
+// The code WDL tables are written with: wins take two bits and the other values three.
+var wdlCode = testCode{
+	minLength: 2, maxLength: 3,
+	lowest: []uint16{4, 0},
+	codes:  []struct{ code, length int }{{0, 3}, {1, 3}, {2, 3}, {3, 3}, {2, 2}},
+}
+
+// The code DTZ tables are written with: every value takes four bits.
+var dtzCode = func() testCode {
+	c := testCode{minLength: 4, maxLength: 4, lowest: []uint16{0}}
+	for v := 0; v < 16; v++ {
+		c.codes = append(c.codes, struct{ code, length int }{v, 4})
+	}
+
+	return c
+}()
+
+const (
+	testBlockLog = 8
+	testSpanLog  = 6
+)
+
+// The compressed values of one side of a table.
+type testPairs struct {
+	blocks  [][]byte
+	lengths []uint16
+	// the block and the offset in it of the value at k*span + span/2, for every k
+	sparse [][2]int
+}
+
+func compress(values []byte, code testCode) testPairs {
+	var tp testPairs
+	starts := []int{0}
+	var w bitWriter
+	count := 0
+	for i, v := range values {
+		c := code.codes[v]
+		if w.bits+c.length > 8<<testBlockLog {
+			tp.blocks = append(tp.blocks, w.bytes)
+			tp.lengths = append(tp.lengths, uint16(count-1))
+			starts = append(starts, i)
+			w, count = bitWriter{}, 0
+		}
+
+		w.write(c.code, c.length)
+		count++
+	}
+
+	tp.blocks = append(tp.blocks, w.bytes)
+	tp.lengths = append(tp.lengths, uint16(count-1))
+	const span = 1 << testSpanLog
+	for k := 0; k*span < len(values); k++ {
+		target := k*span + span/2
+		block := 0
+		for block+1 < len(starts) && starts[block+1] <= target {
+			block++
+		}
+
+		tp.sparse = append(tp.sparse, [2]int{block, target - starts[block]})
+	}
+
+	return tp
+}
+
+func appendUint16(b []byte, v uint16) []byte {
+	return append(b, byte(v), byte(v>>8))
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	return appendUint16(appendUint16(b, uint16(v)), uint16(v>>16))
+}
+
+// Returns the file of a table of two kings and one other white piece. WDL tables have a side for each player to
+// move, and DTZ tables only the one for white to move, whose wins are stored in moves.
+func tableFile(t chomp.PieceType, kind tableKind, sides [][]byte) []byte {
+	piece := tablePieces[chomp.MakePiece(t, chomp.ColorWhite)]
+	code, split := wdlCode, byte(1)
+	if kind == kindDTZ {
+		code, split = dtzCode, 0
+	}
+
+	// the flags, the order of the groups and the pieces of each side
+	f := append(append([]byte{}, tableMagics[kind][:]...), split, 0x00, 0x66, piece|piece<<4, 0xee, 0)
+	pairs := []testPairs{}
+	for _, values := range sides {
+		pairs = append(pairs, compress(values, code))
+	}
+
+	for _, tp := range pairs {
+		f = append(f, 0, testBlockLog, testSpanLog, 0)
+		f = appendUint32(f, uint32(len(tp.blocks)))
+		f = append(f, byte(code.maxLength), byte(code.minLength))
+		for _, sym := range code.lowest {
+			f = appendUint16(f, sym)
+		}
+
+		f = appendUint16(f, uint16(len(code.codes)))
+		for v := range code.codes {
+			f = append(f, byte(v), 0xf0, 0xff)
+		}
+
+		if len(code.codes)%2 == 1 {
+			f = append(f, 0)
+		}
+	}
+
+	// the map of DTZ values, which isn't used, ends on an even offset
+	if kind == kindDTZ && len(f)%2 == 1 {
+		f = append(f, 0)
+	}
+
+	for _, tp := range pairs {
+		for _, e := range tp.sparse {
+			f = appendUint32(f, uint32(e[0]))
+			f = appendUint16(f, uint16(e[1]))
+		}
+	}
+
+	for _, tp := range pairs {
+		for _, l := range tp.lengths {
+			f = appendUint16(f, l)
+		}
+	}
+
+	for _, tp := range pairs {
+		for len(f)%64 != 0 {
+			f = append(f, 0)
+		}
+
+		for _, b := range tp.blocks {
+			f = append(f, b...)
+			f = append(f, make([]byte, 1<<testBlockLog-len(b))...)
+		}
+	}
+
+	// the decompression reads ahead a little
+	return append(f, make([]byte, 16)...)
+}
+
+// Returns the files of the solved tables by file name.
+func tableFiles() map[string][]byte {
+	files := map[string][]byte{}
+	for name, tb := range solvedTables() {
+		files[name+".rtbw"] = tableFile(tb.piece, kindWDL, tb.wdl[:])
+		files[name+".rtbz"] = tableFile(tb.piece, kindDTZ, [][]byte{tb.dtz})
+	}
+
+	return files
+}
+
+func TestSolvedTables(t *testing.T) {
+	// the longest mates are known to take 10 moves with a queen and 16 with a rook
+	for name, longest := range map[string]int{"KQvK": 10, "KRvK": 16} {
+		got := 0
+		for _, moves := range solvedTables()[name].dtz {
+			if int(moves)+1 > got {
+				got = int(moves) + 1
+			}
+		}
+
+		if got != longest {
+			t.Errorf("%s: the longest mate takes %d moves, want %d", name, got, longest)
+		}
+	}
+}
+
+// The tables in testdata are the solved ones, which the server tests use too.
+func TestTestdata(t *testing.T) {
+	for name, f := range tableFiles() {
+		path := filepath.Join("testdata", name)
+		if *update {
+			if err := os.WriteFile(path, f, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			continue
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(data, f) {
+			t.Errorf("%s is out of date, run the tests with -update", path)
+		}
+	}
+}
+
+// Opens the tables in testdata.
+func openTestdata(t *testing.T) *Tablebase {
+	t.Helper()
+
+	tb, err := Open("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { tb.Close() })
+	return tb
+}
+
+func parseFEN(t *testing.T, fen string) *chomp.Game {
+	t.Helper()
+
+	g, err := chomp.ParseFEN(fen)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return g
+}
+
+func TestIndex(t *testing.T) {
+	tb := openTestdata(t)
+	for _, name := range []string{"KQvK", "KRvK"} {
+		for _, table := range []*table{tb.tables[kindWDL][name], tb.tables[kindDTZ][name]} {
+			if err := table.open(); err != nil {
+				t.Fatal(err)
+			}
+
+			piece := table.get(0, 0).pieces[1]
+			for n := 0; n < 2*64*64*64; n++ {
+				wk, sq, bk := placementOf(n % (64 * 64 * 64))
+				blackToMove := n >= 64*64*64
+				if wk == sq || wk == bk || sq == bk || kingsTouch(wk, bk) || (blackToMove && table.kind == kindDTZ) {
+					continue
+				}
+
+				p := position{key: name, pieces: 3, blackToMove: blackToMove}
+				p.board[wk], p.board[sq], p.board[bk] = 6, piece, 14
+				_, _, idx, ok := table.index(&p)
+				if want := referenceIndex([3]int{wk, sq, bk}); !ok || idx != want {
+					t.Fatalf("%s%s: got the index %d, %t for %d, want %d", name, tableExtensions[table.kind], idx, ok, n, want)
+				}
+			}
+
+			// the DTZ tables are only for white to move
+			if table.kind == kindDTZ {
+				p := position{key: name, pieces: 3, blackToMove: true}
+				p.board[0], p.board[1], p.board[63] = 6, piece, 14
+				if _, _, _, ok := table.index(&p); ok {
+					t.Errorf("%s%s was indexed with black to move", name, tableExtensions[table.kind])
+				}
+			}
+		}
+	}
+}
+
+func TestProbeWDL(t *testing.T) {
+	tb := openTestdata(t)
+	if tb.MaxPieces() != 3 {
+		t.Errorf("the tablebase is for %d pieces, want 3", tb.MaxPieces())
+	}
+
+	tests := []struct {
+		fen string
+		wdl WDL
+	}{
+		{"8/8/8/4k3/8/8/8/KQ6 w - - 0 1", WDLWin},
+		{"8/8/8/4k3/8/8/8/KQ6 b - - 0 1", WDLLoss},
+		{"8/8/8/4K3/8/8/8/kq6 b - - 0 1", WDLWin},
+		{"8/8/8/4K3/8/8/8/kq6 w - - 0 1", WDLLoss},
+		{"8/8/8/4k3/8/8/8/KR6 b - - 0 1", WDLLoss},
+		// the queen or rook can be taken
+		{"8/8/8/8/8/8/k7/1Q5K b - - 0 1", WDLDraw},
+		{"8/8/8/8/8/8/K7/1q5k w - - 0 1", WDLDraw},
+		{"8/8/8/8/8/8/k7/1R5K b - - 0 1", WDLDraw},
+		// the queen is defended
+		{"8/8/8/8/8/8/k7/1QK5 b - - 0 1", WDLLoss},
+		// stalemate and checkmate
+		{"k7/2Q5/1K6/8/8/8/8/8 b - - 0 1", WDLDraw},
+		{"k7/1Q6/1K6/8/8/8/8/8 b - - 0 1", WDLLoss},
+		{"7K/5q2/6k1/8/8/8/8/8 w - - 0 1", WDLDraw},
+		{"k7/8/K7/8/8/8/8/1R6 b - - 0 1", WDLDraw},
+		// two kings have no table
+		{"8/8/8/4k3/8/8/8/K7 w - - 0 1", WDLDraw},
+	}
+
+	for _, test := range tests {
+		wdl, err := tb.ProbeWDL(parseFEN(t, test.fen))
+		if err != nil {
+			t.Errorf("%s: %s", test.fen, err.Error())
+		} else if wdl != test.wdl {
+			t.Errorf("%s: got %s, want %s", test.fen, wdl, test.wdl)
+		}
+	}
+
+	if _, err := tb.ProbeWDL(chomp.NewGame()); err != errCastling {
+		t.Errorf("the starting position was probed with the error %v, want %v", err, errCastling)
+	}
+
+	if _, err := tb.ProbeWDL(parseFEN(t, "8/8/8/4k3/8/8/8/KQR5 w - - 0 1")); err != errTooManyPieces {
+		t.Errorf("a position with four pieces was probed with the error %v, want %v", err, errTooManyPieces)
+	}
+
+	if _, err := tb.ProbeWDL(parseFEN(t, "8/8/8/4k3/8/8/8/KB6 w - - 0 1")); err == nil {
+		t.Errorf("a position whose table is missing was probed")
+	}
+}
+
+func TestProbeDTZ(t *testing.T) {
+	tb := openTestdata(t)
+	tests := []struct {
+		fen string
+		dtz int
+	}{
+		// mate in one and in two
+		{"k7/8/1K6/8/8/8/8/7R w - - 0 1", 1},
+		{"k7/8/2K5/8/8/8/8/7R w - - 0 1", 3},
+		{"k7/8/1K6/8/8/8/8/7Q w - - 0 1", 1},
+		// black's only move lets white mate, and checkmate is the end of the distance
+		{"k7/8/1K6/8/8/8/8/7R b - - 0 1", -2},
+		{"R6k/8/6K1/8/8/8/8/8 b - - 0 1", -1},
+		// the same with the colors swapped, which is looked up with the board flipped
+		{"7r/8/8/8/8/1k6/8/K7 b - - 0 1", 1},
+		{"7r/8/8/8/8/1k6/8/K7 w - - 0 1", -2},
+		{"8/8/8/8/8/8/k7/1Q5K b - - 0 1", 0},
+	}
+
+	for _, test := range tests {
+		dtz, err := tb.ProbeDTZ(parseFEN(t, test.fen))
+		if err != nil {
+			t.Errorf("%s: %s", test.fen, err.Error())
+		} else if dtz != test.dtz {
+			t.Errorf("%s: got %d, want %d", test.fen, dtz, test.dtz)
+		}
+	}
+}
+
+func TestProbe(t *testing.T) {
+	tb := openTestdata(t)
+	r, err := tb.Probe(parseFEN(t, "k7/8/1K6/8/8/8/8/7Q w - - 0 1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.WDL != WDLWin || r.DTZ != 1 || len(r.Moves) == 0 {
+		t.Fatalf("got %s with the distance %d and %d moves, want a win in 1", r.WDL, r.DTZ, len(r.Moves))
+	}
+
+	// both mates come first, then the other wins by how soon they mate, then the draws of hanging the queen
+	// or stalemating
+	mates := map[string]bool{}
+	for _, m := range r.Moves[:2] {
+		if m.Checkmate && m.WDL == WDLWin && m.DTZ == 1 {
+			mates[m.Move.UCI()] = true
+		}
+	}
+
+	if !mates["h1h8"] || !mates["h1b7"] {
+		t.Errorf("the moves start with %+v and %+v, want the mates h1h8 and h1b7", r.Moves[0], r.Moves[1])
+	}
+
+	for i := 1; i < len(r.Moves); i++ {
+		a, b := r.Moves[i-1], r.Moves[i]
+		if a.WDL < b.WDL || (a.WDL == b.WDL && a.WDL == WDLWin && !a.Checkmate && a.DTZ > b.DTZ) {
+			t.Errorf("%+v comes before %+v", a, b)
+		}
+	}
+
+	if last := r.Moves[len(r.Moves)-1]; last.WDL != WDLDraw {
+		t.Errorf("the worst move %s is a %s, want a draw", last.Move.UCI(), last.WDL)
+	}
+
+	if best, ok := r.BestMove(); !ok || best != r.Moves[0].Move {
+		t.Errorf("got the best move %s, %t, want %s", best.UCI(), ok, r.Moves[0].Move.UCI())
+	}
+
+	// the losing side holds out longest by going to c8, from where there is no mate in one
+	r, err = tb.Probe(parseFEN(t, "1k6/8/1K6/8/8/8/8/7R b - - 0 1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(r.Moves) != 2 || r.Moves[0].Move.UCI() != "b8c8" || r.Moves[1].Move.UCI() != "b8a8" || r.Moves[1].DTZ != -2 {
+		t.Errorf("got the moves %+v, want b8c8 before b8a8, which is mated in two plies", r.Moves)
+	}
+
+	if r.Moves[0].DTZ >= r.Moves[1].DTZ || r.DTZ != r.Moves[0].DTZ || r.WDL != WDLLoss {
+		t.Errorf("got %s with the distance %d and the moves %+v", r.WDL, r.DTZ, r.Moves)
+	}
+
+	// a checkmated position has no best move
+	r, err = tb.Probe(parseFEN(t, "R6k/8/6K1/8/8/8/8/8 b - - 0 1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := r.BestMove(); ok || r.WDL != WDLLoss || r.DTZ != -1 {
+		t.Errorf("got %s with the distance %d and the moves %+v in a checkmate", r.WDL, r.DTZ, r.Moves)
+	}
+}
+
+func TestProbeCursed(t *testing.T) {
+	// none of the tables small enough to be worked out here have wins that take too long for the fifty-move
+	// rule, so this is a made-up KRvK table in which every win is cursed and every loss blessed
+	tb := solvedTables()["KRvK"]
+	var wdl [2][]byte
+	for side, values := range tb.wdl {
+		wdl[side] = make([]byte, len(values))
+		for i, v := range values {
+			wdl[side][i] = v
+			switch WDL(v) - 2 {
+			case WDLWin:
+				wdl[side][i] = byte(WDLCursedWin + 2)
+			case WDLLoss:
+				wdl[side][i] = byte(WDLBlessedLoss + 2)
+			}
+		}
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "KRvK.rtbw"), tableFile(chomp.PieceTypeRook, kindWDL, wdl[:]), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "KRvK.rtbz"), tableFile(chomp.PieceTypeRook, kindDTZ, [][]byte{tb.dtz}), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cursed, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer cursed.Close()
+	tests := []struct {
+		fen string
+		wdl WDL
+		dtz int
+	}{
+		// the distances are 100 plies further away than the mates in two and one
+		{"k7/8/2K5/8/8/8/8/7R w - - 0 1", WDLCursedWin, 103},
+		{"k7/8/1K6/8/8/8/8/7R w - - 0 1", WDLCursedWin, 101},
+		{"k7/8/1K6/8/8/8/8/7R b - - 0 1", WDLBlessedLoss, -102},
+		{"7r/8/8/8/8/1k6/8/K7 w - - 0 1", WDLBlessedLoss, -102},
+		{"8/8/8/8/8/8/k7/1R5K b - - 0 1", WDLDraw, 0},
+	}
+
+	for _, test := range tests {
+		g := parseFEN(t, test.fen)
+		wdl, err := cursed.ProbeWDL(g)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		dtz, err := cursed.ProbeDTZ(g)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if wdl != test.wdl || dtz != test.dtz {
+			t.Errorf("%s: got %s with the distance %d, want %s with %d", test.fen, wdl, dtz, test.wdl, test.dtz)
+		}
+	}
+
+	// a move the distance is counted from keeps the 100 extra plies
+	r, err := cursed.Probe(parseFEN(t, "k7/8/2K5/8/8/8/8/7R w - - 0 1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if best := r.Moves[0]; best.Move.UCI() != "c6b6" || best.WDL != WDLCursedWin || best.DTZ != 103 {
+		t.Errorf("got the best move %+v, want c6b6 as a cursed win in 103", best)
+	}
+}
+
+func TestOpenNoTables(t *testing.T) {
+	if _, err := Open(t.TempDir()); err != errNoTables {
+		t.Errorf("got the error %v, want %v", err, errNoTables)
+	}
+}
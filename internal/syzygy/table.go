@@ -0,0 +1,774 @@
+package syzygy
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/apachejuice/chomp/internal/chomp"
+)
+
+// The kinds of table files.
+type tableKind int
+
+const (
+	kindWDL tableKind = iota
+	kindDTZ
+)
+
+// The magic numbers table files start with, and their extensions, by kind.
+var (
+	tableMagics     = [2][4]byte{{0x71, 0xe8, 0x23, 0x5d}, {0xd7, 0x66, 0x0c, 0xa5}}
+	tableExtensions = [2]string{".rtbw", ".rtbz"}
+)
+
+// The flags of the compressed data of a table.
+const (
+	// DTZ tables: the table is for black to move
+	flagSTM = 1 << 0
+	// DTZ tables: the values are remapped through the map of the file
+	flagMapped = 1 << 1
+	// DTZ tables: wins are stored in plies rather than moves
+	flagWinPlies = 1 << 2
+	// DTZ tables: losses are stored in plies rather than moves
+	flagLossPlies = 1 << 3
+	// DTZ tables: the map holds 16-bit values
+	flagWide = 1 << 4
+	// All positions of the table have the same value
+	flagSingleValue = 1 << 7
+)
+
+// The most pieces a table can be for.
+const maxTablePieces = 7
+
+// The pieces of the tables' encoding by chomp piece: pawn, knight, bishop, rook, queen and king are 1 to 6,
+// and black pieces have the fourth bit set.
+var tablePieces = [12]byte{1, 4, 2, 3, 5, 6, 9, 12, 10, 11, 13, 14}
+
+// The letters of the piece types in table names, in the order they appear in, and their types.
+const tableLetters = "KQRBNP"
+
+var tableTypes = [6]chomp.PieceType{
+	chomp.PieceTypeKing, chomp.PieceTypeQueen, chomp.PieceTypeRook,
+	chomp.PieceTypeBishop, chomp.PieceTypeKnight, chomp.PieceTypePawn,
+}
+
+var errCorrupted = fmt.Errorf("corrupted table")
+
+// The information needed to decompress the values of one part of a table: one side to move and,
+// in tables with pawns, one file of the leading pawn.
+type pairsData struct {
+	flags     byte
+	minSymLen int
+	// the index of the first value of each block in the sparse index is a multiple of span, plus half of it
+	span      uint64
+	blockSize uint64
+	numBlocks uint64
+	// where the parts of the data are in the file, and how many entries the indexes have
+	sparseIndex     int
+	sparseIndexSize uint64
+	blockLength     int
+	blockLengthSize uint64
+	lowestSym       int
+	btree           int
+	data            int
+	// base64[l] is the lowest symbol of length l + minSymLen, padded to 64 bits on the right
+	base64 []uint64
+	// the number of values each symbol stands for, minus one
+	symlen []int
+	// the pieces of the table, in the order they are encoded in
+	pieces [maxTablePieces]byte
+	// the pieces are encoded in groups of the same piece, except for the leading group
+	groupLen [maxTablePieces + 1]int
+	groupIdx [maxTablePieces + 1]uint64
+	// where the values of each of the four results start in the DTZ map
+	mapIdx [4]int
+}
+
+// A table file: the WDL or DTZ values of all positions with the same pieces.
+type table struct {
+	path string
+	kind tableKind
+	// the name of the table, with the pieces of white first, and the name with the colors swapped
+	key, key2 string
+
+	pieceCount      int
+	hasPawns        bool
+	hasUniquePieces bool
+	// the number of pawns of the leading color and of the other one
+	pawnCount [2]int
+
+	once  sync.Once
+	err   error
+	data  []byte
+	unmap func() error
+	// [side to move][file of the leading pawn, or 0 without pawns]
+	items  [2][4]pairsData
+	dtzMap int
+}
+
+// Creates a table for the file of the given kind with the given name, such as KRvK. Returns false if the name
+// isn't one of a table.
+func newTable(path, name string, kind tableKind) (*table, bool) {
+	sides := strings.Split(name, "v")
+	if len(sides) != 2 || len(name)-1 > maxTablePieces {
+		return nil, false
+	}
+
+	var counts [2][6]int
+	for i, side := range sides {
+		if !strings.HasPrefix(side, "K") {
+			return nil, false
+		}
+
+		for _, r := range side {
+			t := strings.IndexRune(tableLetters, r)
+			if t < 0 {
+				return nil, false
+			}
+
+			counts[i][t]++
+		}
+
+		if counts[i][0] != 1 {
+			return nil, false
+		}
+	}
+
+	t := &table{
+		path:       path,
+		kind:       kind,
+		key:        name,
+		key2:       sides[1] + "v" + sides[0],
+		pieceCount: len(name) - 1,
+		hasPawns:   counts[0][5]+counts[1][5] > 0,
+	}
+
+	for _, c := range counts {
+		for _, n := range c[1:] {
+			if n == 1 {
+				t.hasUniquePieces = true
+			}
+		}
+	}
+
+	// the color with less pawns leads, because that compresses better
+	white, black := counts[0][5], counts[1][5]
+	if black == 0 || (white > 0 && black >= white) {
+		t.pawnCount = [2]int{white, black}
+	} else {
+		t.pawnCount = [2]int{black, white}
+	}
+
+	return t, true
+}
+
+// Returns the compressed data for a side to move and a file of the leading pawn.
+func (t *table) get(stm, file int) *pairsData {
+	if t.kind == kindDTZ {
+		stm = 0
+	}
+
+	if !t.hasPawns {
+		file = 0
+	}
+
+	return &t.items[stm][file]
+}
+
+// Maps the file and reads its headers, the first time the table is used.
+func (t *table) open() error {
+	t.once.Do(func() {
+		data, unmap, err := mapFile(t.path)
+		if err != nil {
+			t.err = err
+			return
+		}
+
+		t.data, t.unmap = data, unmap
+		if err = t.parse(); err != nil {
+			t.err = fmt.Errorf("%s: %s", t.path, err.Error())
+		}
+	})
+
+	return t.err
+}
+
+// Closes the file of the table if it was opened.
+func (t *table) close() error {
+	if t.unmap == nil {
+		return nil
+	}
+
+	return t.unmap()
+}
+
+// Reads the headers of the file, which say how the positions are encoded and where their values are.
+func (t *table) parse() (err error) {
+	// the offsets come from the file, so a broken one can point anywhere
+	defer func() {
+		if recover() != nil {
+			err = errCorrupted
+		}
+	}()
+
+	if len(t.data) < 5 || string(t.data[:4]) != string(tableMagics[t.kind][:]) {
+		return errCorrupted
+	}
+
+	const (
+		split    = 1 << 0
+		hasPawns = 1 << 1
+	)
+
+	p := 4
+	flags := t.data[p]
+	// only WDL tables are split into one part for each side to move
+	if (flags&hasPawns != 0) != t.hasPawns || (t.kind == kindWDL && (flags&split != 0) != (t.key != t.key2)) {
+		return errCorrupted
+	}
+
+	p++
+	sides := 1
+	if t.kind == kindWDL && t.key != t.key2 {
+		sides = 2
+	}
+
+	files := 1
+	if t.hasPawns {
+		files = 4
+	}
+
+	pp := t.hasPawns && t.pawnCount[1] > 0
+	for f := 0; f < files; f++ {
+		order := [2][2]int{{int(t.data[p] & 0xf), 0xf}, {int(t.data[p] >> 4), 0xf}}
+		p++
+		if pp {
+			order[0][1], order[1][1] = int(t.data[p]&0xf), int(t.data[p]>>4)
+			p++
+		}
+
+		for k := 0; k < t.pieceCount; k++ {
+			for i := 0; i < sides; i++ {
+				piece := t.data[p] & 0xf
+				if i == 1 {
+					piece = t.data[p] >> 4
+				}
+
+				t.get(i, f).pieces[k] = piece
+			}
+
+			p++
+		}
+
+		for i := 0; i < sides; i++ {
+			t.setGroups(t.get(i, f), order[i], f)
+		}
+	}
+
+	p += p & 1
+	for f := 0; f < files; f++ {
+		for i := 0; i < sides; i++ {
+			p = t.setSizes(t.get(i, f), p)
+		}
+	}
+
+	if t.kind == kindDTZ {
+		p = t.setDTZMap(p, files)
+	}
+
+	for f := 0; f < files; f++ {
+		for i := 0; i < sides; i++ {
+			d := t.get(i, f)
+			d.sparseIndex = p
+			p += int(d.sparseIndexSize) * 6
+		}
+	}
+
+	for f := 0; f < files; f++ {
+		for i := 0; i < sides; i++ {
+			d := t.get(i, f)
+			d.blockLength = p
+			p += int(d.blockLengthSize) * 2
+		}
+	}
+
+	for f := 0; f < files; f++ {
+		for i := 0; i < sides; i++ {
+			d := t.get(i, f)
+			p = (p + 0x3f) &^ 0x3f
+			d.data = p
+			p += int(d.numBlocks * d.blockSize)
+			if d.numBlocks > 0 && p > len(t.data) {
+				return errCorrupted
+			}
+		}
+	}
+
+	return nil
+}
+
+// Returns the number of groups the pieces are encoded in.
+func (d *pairsData) groups() int {
+	n := 0
+	for d.groupLen[n] != 0 {
+		n++
+	}
+
+	return n
+}
+
+// Splits the pieces into the groups they are encoded in, and works out the factor of each group in the index.
+// The leading group is the leading pawns, the three unique pieces that come first or the two kings;
+// after it come the other pawns, and then the rest of the pieces, each kind of piece in its own group.
+func (t *table) setGroups(d *pairsData, order [2]int, file int) {
+	firstLen := 2
+	if t.hasPawns {
+		firstLen = 0
+	} else if t.hasUniquePieces {
+		firstLen = 3
+	}
+
+	n := 0
+	d.groupLen[0] = 1
+	for i := 1; i < t.pieceCount; i++ {
+		firstLen--
+		if firstLen > 0 || d.pieces[i] == d.pieces[i-1] {
+			d.groupLen[n]++
+		} else {
+			n++
+			d.groupLen[n] = 1
+		}
+	}
+
+	n++
+	d.groupLen[n] = 0
+
+	// the groups are combined in the order the table gives, which needn't be the order of the pieces
+	pp := t.hasPawns && t.pawnCount[1] > 0
+	next := 1
+	freeSquares := 64 - d.groupLen[0]
+	if pp {
+		next = 2
+		freeSquares -= d.groupLen[1]
+	}
+
+	idx := uint64(1)
+	for k := 0; next < n || k == order[0] || k == order[1]; k++ {
+		switch {
+		case k == order[0]:
+			d.groupIdx[0] = idx
+			switch {
+			case t.hasPawns:
+				idx *= leadPawnsSize[d.groupLen[0]][file]
+			case t.hasUniquePieces:
+				idx *= 31332
+			default:
+				idx *= 462
+			}
+		case k == order[1]:
+			d.groupIdx[1] = idx
+			idx *= binomial[d.groupLen[1]][48-d.groupLen[0]]
+		default:
+			d.groupIdx[next] = idx
+			idx *= binomial[d.groupLen[next]][freeSquares]
+			freeSquares -= d.groupLen[next]
+			next++
+		}
+	}
+
+	d.groupIdx[n] = idx
+}
+
+// Reads the sizes and the Huffman code of the compressed data starting at p, and returns where they end.
+func (t *table) setSizes(d *pairsData, p int) int {
+	d.flags = t.data[p]
+	p++
+	if d.flags&flagSingleValue != 0 {
+		// the value is stored in place of the symbol length
+		d.minSymLen = int(t.data[p])
+		return p + 1
+	}
+
+	d.blockSize = 1 << t.data[p]
+	d.span = 1 << t.data[p+1]
+	d.sparseIndexSize = (d.groupIdx[d.groups()] + d.span - 1) / d.span
+	d.numBlocks = uint64(binary.LittleEndian.Uint32(t.data[p+3:]))
+	// the block lengths are padded so that the sparse index can't point past them
+	d.blockLengthSize = d.numBlocks + uint64(t.data[p+2])
+	maxSymLen := int(t.data[p+7])
+	d.minSymLen = int(t.data[p+8])
+	p += 9
+
+	// longer symbols have lower values in the canonical Huffman code, so the lowest symbols
+	// of each length padded to 64 bits decrease as the length grows
+	d.lowestSym = p
+	d.base64 = make([]uint64, maxSymLen-d.minSymLen+1)
+	for i := len(d.base64) - 2; i >= 0; i-- {
+		d.base64[i] = (d.base64[i+1] + uint64(t.lowestSym(d, i)) - uint64(t.lowestSym(d, i+1))) / 2
+	}
+
+	for i := range d.base64 {
+		d.base64[i] <<= uint(64 - i - d.minSymLen)
+	}
+
+	p += len(d.base64) * 2
+	d.symlen = make([]int, binary.LittleEndian.Uint16(t.data[p:]))
+	p += 2
+	d.btree = p
+
+	visited := make([]bool, len(d.symlen))
+	for sym := range d.symlen {
+		if !visited[sym] {
+			d.symlen[sym] = t.setSymlen(d, sym, visited)
+		}
+	}
+
+	return p + len(d.symlen)*3 + len(d.symlen)&1
+}
+
+// Works out how many values the symbol stands for. Every symbol is either a value or a pair of other symbols.
+func (t *table) setSymlen(d *pairsData, sym int, visited []bool) int {
+	visited[sym] = true
+	left, right := t.pair(d, sym)
+	if right == 0xfff {
+		return 0
+	}
+
+	if !visited[left] {
+		d.symlen[left] = t.setSymlen(d, left, visited)
+	}
+
+	if !visited[right] {
+		d.symlen[right] = t.setSymlen(d, right, visited)
+	}
+
+	return d.symlen[left] + d.symlen[right] + 1
+}
+
+// Returns the pair of symbols a symbol stands for. For a symbol that stands for a value, the left one is the value.
+func (t *table) pair(d *pairsData, sym int) (int, int) {
+	lr := t.data[d.btree+sym*3:]
+	return int(lr[1]&0xf)<<8 | int(lr[0]), int(lr[2])<<4 | int(lr[1]>>4)
+}
+
+// Returns the lowest symbol of length l + minSymLen.
+func (t *table) lowestSym(d *pairsData, l int) uint16 {
+	return binary.LittleEndian.Uint16(t.data[d.lowestSym+l*2:])
+}
+
+// Reads the map DTZ values are remapped through starting at p, and returns where it ends.
+func (t *table) setDTZMap(p, files int) int {
+	t.dtzMap = p
+	for f := 0; f < files; f++ {
+		d := t.get(0, f)
+		if d.flags&flagMapped == 0 {
+			continue
+		}
+
+		for i := 0; i < 4; i++ {
+			if d.flags&flagWide != 0 {
+				p += p & 1
+				d.mapIdx[i] = (p-t.dtzMap)/2 + 1
+				p += 2*int(binary.LittleEndian.Uint16(t.data[p:])) + 2
+			} else {
+				d.mapIdx[i] = p - t.dtzMap + 1
+				p += int(t.data[p]) + 1
+			}
+		}
+	}
+
+	return p + p&1
+}
+
+// Returns the value at the given index of the compressed data.
+func (t *table) decompress(d *pairsData, idx uint64) int {
+	if d.flags&flagSingleValue != 0 {
+		return d.minSymLen
+	}
+
+	// the sparse index says in which block and where in it the value at k*span + span/2 is,
+	// from which the blocks are walked through to the one holding the value
+	k := idx / d.span
+	entry := t.data[d.sparseIndex+int(k)*6:]
+	block := int(binary.LittleEndian.Uint32(entry))
+	offset := int(binary.LittleEndian.Uint16(entry[4:])) + int(idx%d.span) - int(d.span/2)
+	for offset < 0 {
+		block--
+		offset += t.blockLength(d, block) + 1
+	}
+
+	for offset > t.blockLength(d, block) {
+		offset -= t.blockLength(d, block) + 1
+		block++
+	}
+
+	// each block is a sequence of Huffman coded symbols, each standing for one or more values
+	p := d.data + block*int(d.blockSize)
+	buf := binary.BigEndian.Uint64(t.data[p:])
+	p += 8
+	bufSize := 64
+	var sym int
+	for {
+		l := 0
+		for buf < d.base64[l] {
+			l++
+		}
+
+		sym = int(uint16((buf-d.base64[l])>>uint(64-l-d.minSymLen)) + t.lowestSym(d, l))
+		if offset < d.symlen[sym]+1 {
+			break
+		}
+
+		offset -= d.symlen[sym] + 1
+		l += d.minSymLen
+		buf <<= uint(l)
+		bufSize -= l
+		if bufSize <= 32 {
+			bufSize += 32
+			buf |= uint64(binary.BigEndian.Uint32(t.data[p:])) << uint(64-bufSize)
+			p += 4
+		}
+	}
+
+	// the symbol stands for pairs of symbols standing for pairs, down to the values
+	for d.symlen[sym] != 0 {
+		left, right := t.pair(d, sym)
+		if offset < d.symlen[left]+1 {
+			sym = left
+		} else {
+			offset -= d.symlen[left] + 1
+			sym = right
+		}
+	}
+
+	value, _ := t.pair(d, sym)
+	return value
+}
+
+// Returns the number of values in the block, minus one.
+func (t *table) blockLength(d *pairsData, block int) int {
+	return int(binary.LittleEndian.Uint16(t.data[d.blockLength+block*2:]))
+}
+
+// Turns a value of a DTZ table into a distance to zeroing in plies, without the sign.
+func (t *table) mapDTZ(file, value int, wdl WDL) int {
+	d := t.get(0, file)
+	if d.flags&flagMapped != 0 {
+		i := d.mapIdx[[5]int{1, 3, 0, 2, 0}[wdl+2]] + value
+		if d.flags&flagWide != 0 {
+			value = int(binary.LittleEndian.Uint16(t.data[t.dtzMap+i*2:]))
+		} else {
+			value = int(t.data[t.dtzMap+i])
+		}
+	}
+
+	if (wdl == WDLWin && d.flags&flagWinPlies == 0) || (wdl == WDLLoss && d.flags&flagLossPlies == 0) ||
+		wdl == WDLCursedWin || wdl == WDLBlessedLoss {
+		value *= 2
+	}
+
+	return value + 1
+}
+
+// Looks up the position in the table. The pieces must be the ones of the table. WDL tables return the WDL
+// of the position; DTZ tables return the distance to zeroing in plies without the sign, which needs the WDL,
+// or false if the table is only for the other side to move.
+func (t *table) probe(p *position, wdl WDL) (value int, ok bool, err error) {
+	defer func() {
+		if recover() != nil {
+			value, ok, err = 0, false, fmt.Errorf("%s: %s", t.path, errCorrupted.Error())
+		}
+	}()
+
+	d, file, idx, ok := t.index(p)
+	if !ok {
+		return 0, false, nil
+	}
+
+	value = t.decompress(d, idx)
+	if t.kind == kindWDL {
+		return value - 2, true, nil
+	}
+
+	return t.mapDTZ(file, value, wdl), true, nil
+}
+
+// Returns the part of the table the position is in, the file of its leading pawn and its index in the part.
+// Returns false if the table is a DTZ table for the other side to move.
+func (t *table) index(p *position) (*pairsData, int, uint64, bool) {
+	// tables are for the stronger side as white, and symmetric ones only for white to move,
+	// so other positions are looked up with the colors swapped and the board flipped
+	flip := p.key != t.key || (t.key == t.key2 && p.blackToMove)
+	flipColor, flipSquares, stm := byte(0), 0, 0
+	if flip {
+		flipColor, flipSquares = 8, 56
+	}
+
+	if flip != p.blackToMove {
+		stm = 1
+	}
+
+	var squares [maxTablePieces]int
+	var pieces [maxTablePieces]byte
+	size, leadPawns, file := 0, 0, 0
+	if t.hasPawns {
+		// the leading pawn is the one closest to the a or h file, and the lowest of those
+		lead := t.get(0, 0).pieces[0] ^ flipColor
+		for sq, piece := range p.board {
+			if piece == lead {
+				squares[size] = sq ^ flipSquares
+				size++
+			}
+		}
+
+		leadPawns = size
+		best := 0
+		for i := 1; i < leadPawns; i++ {
+			if mapPawns[squares[i]] > mapPawns[squares[best]] {
+				best = i
+			}
+		}
+
+		squares[0], squares[best] = squares[best], squares[0]
+		file = squares[0] & 7
+		if file > 3 {
+			file = 7 - file
+		}
+	}
+
+	if t.kind == kindDTZ && t.get(0, file).flags&flagSTM != byte(stm) && (t.key != t.key2 || t.hasPawns) {
+		return nil, 0, 0, false
+	}
+
+	for sq, piece := range p.board {
+		if piece == 0 || (t.hasPawns && piece == t.get(0, 0).pieces[0]^flipColor) {
+			continue
+		}
+
+		squares[size] = sq ^ flipSquares
+		pieces[size] = piece ^ flipColor
+		size++
+	}
+
+	// the pieces are put in the order of the table
+	d := t.get(stm, file)
+	for i := leadPawns; i < size-1; i++ {
+		for j := i + 1; j < size; j++ {
+			if d.pieces[i] == pieces[j] {
+				pieces[i], pieces[j] = pieces[j], pieces[i]
+				squares[i], squares[j] = squares[j], squares[i]
+				break
+			}
+		}
+	}
+
+	// the board is mirrored so that the leading piece is on the a-d files,
+	// and without pawns on ranks 1-4 and below the a1-h8 diagonal too
+	if squares[0]&7 > 3 {
+		for i := 0; i < size; i++ {
+			squares[i] ^= 7
+		}
+	}
+
+	idx := uint64(0)
+	if t.hasPawns {
+		idx = leadPawnIdx[leadPawns][squares[0]]
+		rest := squares[1:leadPawns]
+		sort.SliceStable(rest, func(i, j int) bool { return mapPawns[rest[i]] < mapPawns[rest[j]] })
+		for i := 1; i < leadPawns; i++ {
+			idx += binomial[i][mapPawns[squares[i]]]
+		}
+	} else {
+		if squares[0]>>3 > 3 {
+			for i := 0; i < size; i++ {
+				squares[i] ^= 56
+			}
+		}
+
+		for i := 0; i < d.groupLen[0]; i++ {
+			if offDiagonal(squares[i]) == 0 {
+				continue
+			}
+
+			if offDiagonal(squares[i]) > 0 {
+				for j := i; j < size; j++ {
+					squares[j] = flipDiagonal(squares[j])
+				}
+			}
+
+			break
+		}
+
+		idx = t.leadingIndex(squares[:size])
+	}
+
+	// the other groups are encoded as combinations of the squares the groups before them leave free
+	idx *= d.groupIdx[0]
+	start := d.groupLen[0]
+	remainingPawns := t.hasPawns && t.pawnCount[1] > 0
+	for next := 1; d.groupLen[next] != 0; next++ {
+		group := squares[start : start+d.groupLen[next]]
+		sort.Ints(group)
+		n := uint64(0)
+		for i, sq := range group {
+			adjust := 0
+			for _, before := range squares[:start] {
+				if sq > before {
+					adjust++
+				}
+			}
+
+			if remainingPawns {
+				adjust += 8
+			}
+
+			n += binomial[i+1][sq-adjust]
+		}
+
+		remainingPawns = false
+		idx += n * d.groupIdx[next]
+		start += d.groupLen[next]
+	}
+
+	return d, file, idx, true
+}
+
+// Returns the index of the leading group of a table without pawns: the three unique pieces that come first,
+// or the two kings if there aren't enough unique pieces.
+func (t *table) leadingIndex(squares []int) uint64 {
+	if !t.hasUniquePieces {
+		return uint64(mapKK[mapA1D1D4[squares[0]]][squares[1]])
+	}
+
+	// the squares of the other pieces are taken out of the ones left for the next piece
+	s0, s1, s2 := squares[0], squares[1], squares[2]
+	adjust1, adjust2 := 0, 0
+	if s1 > s0 {
+		adjust1++
+	}
+
+	if s2 > s0 {
+		adjust2++
+	}
+
+	if s2 > s1 {
+		adjust2++
+	}
+
+	var idx int
+	switch {
+	case offDiagonal(s0) != 0:
+		idx = (mapA1D1D4[s0]*63+s1-adjust1)*62 + s2 - adjust2
+	case offDiagonal(s1) != 0:
+		idx = (6*63+(s0>>3)*28+mapB1H1H7[s1])*62 + s2 - adjust2
+	case offDiagonal(s2) != 0:
+		idx = 6*63*62 + 4*28*62 + (s0>>3)*7*28 + (s1>>3-adjust1)*28 + mapB1H1H7[s2]
+	default:
+		idx = 6*63*62 + 4*28*62 + 4*7*28 + (s0>>3)*7*6 + (s1>>3-adjust1)*6 + s2>>3 - adjust2
+	}
+
+	return uint64(idx)
+}
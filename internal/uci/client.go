@@ -0,0 +1,434 @@
+package uci
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apachejuice/chomp/internal/chomp"
+)
+
+// How long an engine may take to answer commands that should be answered right away.
+const responseTimeout = 10 * time.Second
+
+var (
+	errEngineExited = fmt.Errorf("the engine has exited")
+	errTimeout      = fmt.Errorf("the engine did not respond in time")
+	errSearching    = fmt.Errorf("the engine is already searching")
+	errNoBestMove   = fmt.Errorf("the engine has no move to play")
+)
+
+// Describes an option the engine can be configured with.
+type Option struct {
+	// The name of the option, which may contain spaces
+	Name string `json:"name"`
+	// The type of the option: check, spin, combo, button or string
+	Type string `json:"type"`
+	// The default value, if any
+	Default string `json:"default"`
+	// The smallest value of a spin option
+	Min int `json:"min"`
+	// The largest value of a spin option
+	Max int `json:"max"`
+	// The values a combo option can have
+	Vars []string `json:"vars"`
+}
+
+// Limits a search. Zero fields are not sent to the engine.
+type SearchParams struct {
+	// The time white and black have left on their clocks
+	WhiteTime, BlackTime time.Duration
+	// How much time white and black get back after each move
+	WhiteIncrement, BlackIncrement time.Duration
+	// How many moves are left until the next time control
+	MovesToGo int
+	// The deepest to search
+	Depth int
+	// The most positions to search
+	Nodes uint64
+	// Searches for a mate in this many moves
+	Mate int
+	// Exactly how long to search
+	MoveTime time.Duration
+	// Searches until stopped
+	Infinite bool
+	// Searches while the opponent thinks, the last move of the position being the one the engine expects them to play
+	Ponder bool
+	// Only these moves are searched, if any are given
+	SearchMoves []chomp.Move
+}
+
+// The result of a search.
+type BestMove struct {
+	// The move the engine chose
+	Move chomp.Move `json:"move"`
+	// The move the engine expects the opponent to answer with, or a move from ErrorPos if it didn't say
+	Ponder chomp.Move `json:"ponder"`
+	// The last info line with a principal variation the engine sent during the search
+	Info Info `json:"info"`
+}
+
+// A client that talks to a UCI engine, usually one running in another process.
+type Client struct {
+	// The name and author the engine gave
+	Name, Author string
+	// The options the engine supports, by name
+	Options map[string]Option
+	// Called for every info line the engine sends while searching, if set
+	OnInfo func(Info)
+
+	cmd       *exec.Cmd
+	w         io.Writer
+	lines     chan string
+	writeMu   sync.Mutex
+	searching bool
+	mu        sync.Mutex
+}
+
+// Starts the engine executable at the given path and goes through the UCI handshake with it.
+func Start(path string, args ...string) (*Client, error) {
+	cmd := exec.Command(path, args...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	if err = cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	c, err := NewClient(stdout, stdin)
+	if err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return nil, err
+	}
+
+	c.cmd = cmd
+	return c, nil
+}
+
+// Creates a client that reads the engine's output from r and writes commands to w,
+// and goes through the UCI handshake with it.
+func NewClient(r io.Reader, w io.Writer) (*Client, error) {
+	c := &Client{
+		Options: make(map[string]Option),
+		w:       w,
+		lines:   make(chan string, 64),
+	}
+
+	go func() {
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			c.lines <- strings.TrimSpace(scanner.Text())
+		}
+
+		close(c.lines)
+	}()
+
+	if err := c.send("uci"); err != nil {
+		return nil, err
+	}
+
+	err := c.readUntil("uciok", responseTimeout, func(line string) {
+		switch {
+		case strings.HasPrefix(line, "id name "):
+			c.Name = strings.TrimPrefix(line, "id name ")
+		case strings.HasPrefix(line, "id author "):
+			c.Author = strings.TrimPrefix(line, "id author ")
+		case strings.HasPrefix(line, "option "):
+			opt := parseOption(strings.TrimPrefix(line, "option "))
+			c.Options[opt.Name] = opt
+		}
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Sends a command to the engine.
+func (c *Client) send(command string) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	_, err := io.WriteString(c.w, command+"\n")
+	return err
+}
+
+// Reads lines from the engine until one is exactly the given one, or starts with it followed by a space.
+// Every line before it is passed to handle. A timeout of 0 waits forever.
+func (c *Client) readUntil(command string, timeout time.Duration, handle func(string)) error {
+	var deadline <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	for {
+		select {
+		case line, ok := <-c.lines:
+			if !ok {
+				return errEngineExited
+			}
+
+			if line == command || strings.HasPrefix(line, command+" ") {
+				handle(line)
+				return nil
+			}
+
+			handle(line)
+		case <-deadline:
+			return errTimeout
+		}
+	}
+}
+
+// Waits until the engine is ready to take more commands.
+func (c *Client) IsReady() error {
+	if err := c.send("isready"); err != nil {
+		return err
+	}
+
+	return c.readUntil("readyok", responseTimeout, func(string) {})
+}
+
+// Sets an option of the engine. Options without a value, like buttons, are set with an empty value.
+func (c *Client) SetOption(name, value string) error {
+	command := "setoption name " + name
+	if value != "" {
+		command += " value " + value
+	}
+
+	if err := c.send(command); err != nil {
+		return err
+	}
+
+	return c.IsReady()
+}
+
+// Tells the engine that the next search is from a different game.
+func (c *Client) NewGame() error {
+	if err := c.send("ucinewgame"); err != nil {
+		return err
+	}
+
+	return c.IsReady()
+}
+
+// Sets the position to search: the position of the given FEN, or the starting position if it is empty,
+// followed by the given moves.
+func (c *Client) Position(fen string, moves []chomp.Move) error {
+	command := "position startpos"
+	if fen != "" && fen != chomp.StartFEN {
+		command = "position fen " + fen
+	}
+
+	if len(moves) > 0 {
+		command += " moves"
+		for _, m := range moves {
+			command += " " + m.UCI()
+		}
+	}
+
+	return c.send(command)
+}
+
+// Searches the position set with Position and returns the engine's move.
+// Blocks until the engine is done; use Stop from another goroutine to end an infinite search.
+// Info lines sent during the search are passed to OnInfo.
+func (c *Client) Go(params SearchParams) (BestMove, error) {
+	c.mu.Lock()
+	if c.searching {
+		c.mu.Unlock()
+		return BestMove{}, errSearching
+	}
+
+	c.searching = true
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		c.searching = false
+		c.mu.Unlock()
+	}()
+
+	if err := c.send(params.command()); err != nil {
+		return BestMove{}, err
+	}
+
+	result := BestMove{Ponder: chomp.Move{From: chomp.ErrorPos}}
+	var bestMoveLine string
+	err := c.readUntil("bestmove", 0, func(line string) {
+		switch {
+		case strings.HasPrefix(line, "info "):
+			info, err := ParseInfo(strings.TrimPrefix(line, "info "))
+			if err != nil {
+				// a broken info line is no reason to give up on the search
+				return
+			}
+
+			if info.HasPV() {
+				result.Info = info
+			}
+
+			if c.OnInfo != nil {
+				c.OnInfo(info)
+			}
+		case strings.HasPrefix(line, "bestmove"):
+			bestMoveLine = line
+		}
+	})
+
+	if err != nil {
+		return BestMove{}, err
+	}
+
+	fields := strings.Fields(bestMoveLine)
+	if len(fields) < 2 || fields[1] == "(none)" || fields[1] == "0000" {
+		return BestMove{}, errNoBestMove
+	}
+
+	result.Move, err = chomp.ParseUCI(fields[1])
+	if err != nil {
+		return BestMove{}, err
+	}
+
+	if len(fields) == 4 && fields[2] == "ponder" {
+		if ponder, err := chomp.ParseUCI(fields[3]); err == nil {
+			result.Ponder = ponder
+		}
+	}
+
+	return result, nil
+}
+
+// Tells the engine to stop searching as soon as possible. Go still returns the move it found.
+func (c *Client) Stop() error {
+	return c.send("stop")
+}
+
+// Tells the engine that the opponent played the move it was pondering on, so the search goes on as a normal one.
+func (c *Client) PonderHit() error {
+	return c.send("ponderhit")
+}
+
+// Tells the engine to quit and waits for its process to exit, killing it if it takes too long.
+func (c *Client) Close() error {
+	c.send("quit")
+	if c.cmd == nil {
+		return nil
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- c.cmd.Wait() }()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(responseTimeout):
+		c.cmd.Process.Kill()
+		return <-done
+	}
+}
+
+// Returns the go command for the parameters.
+func (p SearchParams) command() string {
+	parts := []string{"go"}
+	if p.Ponder {
+		parts = append(parts, "ponder")
+	}
+
+	durations := []struct {
+		name  string
+		value time.Duration
+	}{
+		{"wtime", p.WhiteTime},
+		{"btime", p.BlackTime},
+		{"winc", p.WhiteIncrement},
+		{"binc", p.BlackIncrement},
+		{"movetime", p.MoveTime},
+	}
+
+	for _, d := range durations {
+		if d.value > 0 {
+			parts = append(parts, d.name, strconv.FormatInt(d.value.Milliseconds(), 10))
+		}
+	}
+
+	if p.MovesToGo > 0 {
+		parts = append(parts, "movestogo", strconv.Itoa(p.MovesToGo))
+	}
+
+	if p.Depth > 0 {
+		parts = append(parts, "depth", strconv.Itoa(p.Depth))
+	}
+
+	if p.Nodes > 0 {
+		parts = append(parts, "nodes", strconv.FormatUint(p.Nodes, 10))
+	}
+
+	if p.Mate > 0 {
+		parts = append(parts, "mate", strconv.Itoa(p.Mate))
+	}
+
+	if p.Infinite {
+		parts = append(parts, "infinite")
+	}
+
+	if len(p.SearchMoves) > 0 {
+		parts = append(parts, "searchmoves")
+		for _, m := range p.SearchMoves {
+			parts = append(parts, m.UCI())
+		}
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// Parses the arguments of an option line, without the leading "option".
+func parseOption(line string) Option {
+	opt := Option{}
+	keywords := map[string]bool{"name": true, "type": true, "default": true, "min": true, "max": true, "var": true}
+	fields := strings.Fields(line)
+	for i := 0; i < len(fields); {
+		keyword := fields[i]
+		i++
+
+		// values run until the next keyword, since names and strings can contain spaces
+		start := i
+		for i < len(fields) && !keywords[fields[i]] {
+			i++
+		}
+
+		value := strings.Join(fields[start:i], " ")
+		switch keyword {
+		case "name":
+			opt.Name = value
+		case "type":
+			opt.Type = value
+		case "default":
+			opt.Default = value
+		case "min":
+			opt.Min, _ = strconv.Atoi(value)
+		case "max":
+			opt.Max, _ = strconv.Atoi(value)
+		case "var":
+			opt.Vars = append(opt.Vars, value)
+		}
+	}
+
+	return opt
+}
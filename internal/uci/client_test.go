@@ -0,0 +1,158 @@
+package uci
+
+import (
+	"bufio"
+	"io"
+	"reflect"
+	"testing"
+)
+
+// A scripted engine on the other end of a pair of pipes. For every command it reads, it writes the lines
+// the script has for it. It exits after the command the script maps to nil, or when the client stops writing.
+type scriptedEngine struct {
+	script map[string][]string
+	// The commands the engine received, in order, sent once it has exited
+	received chan []string
+}
+
+// Starts the engine and returns a client connected to it.
+func startScripted(t *testing.T, script map[string][]string) (*Client, *scriptedEngine) {
+	t.Helper()
+
+	commandsR, commandsW := io.Pipe()
+	outputR, outputW := io.Pipe()
+	e := &scriptedEngine{script: script, received: make(chan []string, 1)}
+	go func() {
+		received := []string{}
+		defer func() {
+			outputW.Close()
+			commandsR.Close()
+			e.received <- received
+		}()
+
+		scanner := bufio.NewScanner(commandsR)
+		for scanner.Scan() {
+			command := scanner.Text()
+			received = append(received, command)
+			lines, ok := e.script[command]
+			if ok && lines == nil {
+				return
+			}
+
+			for _, line := range lines {
+				if _, err := io.WriteString(outputW, line+"\n"); err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	c, err := NewClient(outputR, commandsW)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { commandsW.Close() })
+	return c, e
+}
+
+var handshake = []string{
+	"id name Scripted 1.0",
+	"id author The Testers",
+	"option name Hash type spin default 16 min 1 max 1024",
+	"option name Skill Level type combo default Normal var Easy var Normal var Very Hard",
+	"option name Clear Hash type button",
+	"uciok",
+}
+
+func TestHandshake(t *testing.T) {
+	c, _ := startScripted(t, map[string][]string{"uci": handshake})
+	if c.Name != "Scripted 1.0" || c.Author != "The Testers" {
+		t.Errorf("got the engine %q by %q", c.Name, c.Author)
+	}
+
+	want := map[string]Option{
+		"Hash":        {Name: "Hash", Type: "spin", Default: "16", Min: 1, Max: 1024},
+		"Skill Level": {Name: "Skill Level", Type: "combo", Default: "Normal", Vars: []string{"Easy", "Normal", "Very Hard"}},
+		"Clear Hash":  {Name: "Clear Hash", Type: "button"},
+	}
+
+	if !reflect.DeepEqual(c.Options, want) {
+		t.Errorf("got the options %+v, want %+v", c.Options, want)
+	}
+}
+
+func TestParseOption(t *testing.T) {
+	tests := []struct {
+		line string
+		want Option
+	}{
+		{"name Ponder type check default false", Option{Name: "Ponder", Type: "check", Default: "false"}},
+		{"name Threads type spin default 1 min 1 max 512", Option{Name: "Threads", Type: "spin", Default: "1", Min: 1, Max: 512}},
+		{"name Debug Log File type string default", Option{Name: "Debug Log File", Type: "string"}},
+		{"name Style type combo default Solid var Solid var Risky", Option{Name: "Style", Type: "combo", Default: "Solid", Vars: []string{"Solid", "Risky"}}},
+	}
+
+	for _, test := range tests {
+		if got := parseOption(test.line); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %+v, want %+v", test.line, got, test.want)
+		}
+	}
+}
+
+func TestGo(t *testing.T) {
+	c, e := startScripted(t, map[string][]string{
+		"uci": handshake,
+		"go depth 2": {
+			"info depth 1 score cp 20 nodes 20 pv e2e4",
+			"info currmove d2d4 currmovenumber 2",
+			"info depth 2 score cp 15 nodes 400 pv e2e4 e7e5",
+			"bestmove e2e4 ponder e7e5",
+		},
+		"quit": nil,
+	})
+
+	infos := []Info{}
+	c.OnInfo = func(info Info) { infos = append(infos, info) }
+	if err := c.Position("", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := c.Go(SearchParams{Depth: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Move.UCI() != "e2e4" || result.Ponder.UCI() != "e7e5" {
+		t.Errorf("got the move %s pondering on %s", result.Move.UCI(), result.Ponder.UCI())
+	}
+
+	if len(infos) != 3 {
+		t.Errorf("got %d info lines, want 3", len(infos))
+	}
+
+	if result.Info.Depth != 2 || result.Info.Score.Centipawns != 15 || len(result.Info.PV) != 2 {
+		t.Errorf("got the info %+v, want the last one with a principal variation", result.Info)
+	}
+
+	c.Close()
+	want := []string{"uci", "position startpos", "go depth 2", "quit"}
+	if got := <-e.received; !reflect.DeepEqual(got, want) {
+		t.Errorf("the engine received %q, want %q", got, want)
+	}
+}
+
+func TestGoEngineExits(t *testing.T) {
+	c, _ := startScripted(t, map[string][]string{
+		"uci":         handshake,
+		"go infinite": nil,
+	})
+
+	if _, err := c.Go(SearchParams{Infinite: true}); err != errEngineExited {
+		t.Errorf("got the error %v, want %v", err, errEngineExited)
+	}
+
+	if _, err := c.Go(SearchParams{Depth: 1}); err == nil {
+		t.Errorf("a search with no engine succeeded")
+	}
+}
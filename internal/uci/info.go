@@ -0,0 +1,217 @@
+// Package uci implements the Universal Chess Interface, the text protocol chess engines and GUIs talk over.
+// It has a client for running engines in other processes, and a server for making chomp's engine available to GUIs.
+package uci
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apachejuice/chomp/internal/chomp"
+)
+
+// Represents the score of a position as an engine reports it, from the point of view of the player to move.
+type Score struct {
+	// The score in centipawns. Not set if the score is a mate.
+	Centipawns int `json:"cp"`
+	// The number of moves to checkmate, negative if the player to move is getting mated, or 0 if the score is not a mate
+	Mate int `json:"mate"`
+	// Whether the real score is at least this, because the engine stopped searching the move once it was good enough
+	Lowerbound bool `json:"lowerbound"`
+	// Whether the real score is at most this
+	Upperbound bool `json:"upperbound"`
+}
+
+func (s Score) String() string {
+	str := fmt.Sprintf("cp %d", s.Centipawns)
+	if s.Mate != 0 {
+		str = fmt.Sprintf("mate %d", s.Mate)
+	}
+
+	if s.Lowerbound {
+		str += " lowerbound"
+	} else if s.Upperbound {
+		str += " upperbound"
+	}
+
+	return str
+}
+
+// Represents an info line sent by an engine while searching. Fields the engine didn't send are left zero.
+type Info struct {
+	// How deep the engine has searched
+	Depth int `json:"depth"`
+	// The deepest the engine has searched any move, with extensions and quiescence search
+	SelDepth int `json:"seldepth"`
+	// Which of the best lines this is when the engine is asked for several, starting from 1
+	MultiPV int `json:"multipv"`
+	// The score of the line
+	Score Score `json:"score"`
+	// How many positions have been searched
+	Nodes uint64 `json:"nodes"`
+	// How many positions are being searched per second
+	NPS uint64 `json:"nps"`
+	// How long the engine has been searching
+	Time time.Duration `json:"time"`
+	// The principal variation: the best move and the moves the engine expects both players to answer with
+	PV []chomp.Move `json:"pv"`
+	// Any text the engine wants to show
+	String string `json:"string"`
+}
+
+// Returns whether the info line reports a search result, as opposed to other information like the current move.
+func (i Info) HasPV() bool {
+	return len(i.PV) > 0
+}
+
+// Parses the arguments of an info line, without the leading "info".
+// Unknown fields are ignored, as the protocol requires.
+func ParseInfo(line string) (Info, error) {
+	var info Info
+	fields := strings.Fields(line)
+	for i := 0; i < len(fields); i++ {
+		var err error
+		switch fields[i] {
+		case "depth":
+			info.Depth, err = intField(fields, &i)
+		case "seldepth":
+			info.SelDepth, err = intField(fields, &i)
+		case "multipv":
+			info.MultiPV, err = intField(fields, &i)
+		case "nodes":
+			info.Nodes, err = uintField(fields, &i)
+		case "nps":
+			info.NPS, err = uintField(fields, &i)
+		case "time":
+			var ms int
+			ms, err = intField(fields, &i)
+			info.Time = time.Duration(ms) * time.Millisecond
+		case "score":
+			err = parseScore(fields, &i, &info.Score)
+		case "pv":
+			for i+1 < len(fields) {
+				m, err := chomp.ParseUCI(fields[i+1])
+				if err != nil {
+					break
+				}
+
+				info.PV = append(info.PV, m)
+				i++
+			}
+		case "string":
+			// the rest of the line is the string
+			info.String = strings.Join(fields[i+1:], " ")
+			i = len(fields)
+		}
+
+		if err != nil {
+			return Info{}, fmt.Errorf("invalid info line '%s': %s", line, err.Error())
+		}
+	}
+
+	return info, nil
+}
+
+// Parses the score field starting at fields[*i], which is the word "score".
+func parseScore(fields []string, i *int, s *Score) error {
+	for *i+1 < len(fields) {
+		var err error
+		switch fields[*i+1] {
+		case "cp":
+			*i++
+			s.Centipawns, err = intField(fields, i)
+		case "mate":
+			*i++
+			s.Mate, err = intField(fields, i)
+		case "lowerbound":
+			*i++
+			s.Lowerbound = true
+		case "upperbound":
+			*i++
+			s.Upperbound = true
+		default:
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Parses the number after fields[*i] and moves past it.
+func intField(fields []string, i *int) (int, error) {
+	if *i+1 >= len(fields) {
+		return 0, fmt.Errorf("missing value for '%s'", fields[*i])
+	}
+
+	*i++
+	n, err := strconv.Atoi(fields[*i])
+	if err != nil {
+		return 0, fmt.Errorf("invalid value for '%s': '%s'", fields[*i-1], fields[*i])
+	}
+
+	return n, nil
+}
+
+// Like intField, but for numbers that can't be negative.
+func uintField(fields []string, i *int) (uint64, error) {
+	if *i+1 >= len(fields) {
+		return 0, fmt.Errorf("missing value for '%s'", fields[*i])
+	}
+
+	*i++
+	n, err := strconv.ParseUint(fields[*i], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value for '%s': '%s'", fields[*i-1], fields[*i])
+	}
+
+	return n, nil
+}
+
+// Formats the info as the arguments of an info line, without the leading "info".
+func (i Info) Format() string {
+	var parts []string
+	if i.Depth > 0 {
+		parts = append(parts, fmt.Sprintf("depth %d", i.Depth))
+	}
+
+	if i.SelDepth > 0 {
+		parts = append(parts, fmt.Sprintf("seldepth %d", i.SelDepth))
+	}
+
+	if i.MultiPV > 0 {
+		parts = append(parts, fmt.Sprintf("multipv %d", i.MultiPV))
+	}
+
+	if i.HasPV() {
+		parts = append(parts, "score "+i.Score.String())
+	}
+
+	if i.Nodes > 0 {
+		parts = append(parts, fmt.Sprintf("nodes %d", i.Nodes))
+	}
+
+	if i.NPS > 0 {
+		parts = append(parts, fmt.Sprintf("nps %d", i.NPS))
+	}
+
+	parts = append(parts, fmt.Sprintf("time %d", i.Time.Milliseconds()))
+	if i.HasPV() {
+		moves := make([]string, len(i.PV))
+		for j, m := range i.PV {
+			moves[j] = m.UCI()
+		}
+
+		parts = append(parts, "pv "+strings.Join(moves, " "))
+	}
+
+	if i.String != "" {
+		parts = append(parts, "string "+i.String)
+	}
+
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,451 @@
+package uci
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apachejuice/chomp/internal/book"
+	"github.com/apachejuice/chomp/internal/chomp"
+	"github.com/apachejuice/chomp/internal/engine"
+	"github.com/apachejuice/chomp/internal/syzygy"
+)
+
+// The name and author the server gives for chomp's engine.
+const (
+	engineName   = "chomp"
+	engineAuthor = "the chomp authors"
+)
+
+// The deepest the Book Depth option can limit the book to, in plies.
+const maxBookDepth = 1000
+
+// A search running on the server.
+type search struct {
+	results <-chan engine.Result
+	turn    chomp.Color
+	params  SearchParams
+	// closed when the best move may be sent; infinite and ponder searches have to wait for stop or ponderhit
+	released    chan struct{}
+	releaseOnce sync.Once
+	// closed when the best move has been sent
+	done chan struct{}
+	// stops the search once the time given at ponderhit runs out
+	timer *time.Timer
+}
+
+func (s *search) release() {
+	s.releaseOnce.Do(func() { close(s.released) })
+}
+
+// Serves chomp's engine over UCI, reading commands from r and writing responses to w.
+type server struct {
+	w       io.Writer
+	writeMu sync.Mutex
+	engine  *engine.Engine
+	level   int
+	book    *book.Book
+	// the depth the book is limited to, kept apart from the book since the options can be set in any order
+	bookDepth int
+	tablebase *syzygy.Tablebase
+	weights   *engine.Weights
+	// whether positions are Chess960 ones, where castling is written as the king taking its own rook
+	chess960 bool
+	game     *chomp.Game
+	search   *search
+}
+
+// Makes chomp's engine speak UCI, reading commands from r and writing responses to w until
+// the quit command is given or r ends. Unknown commands are ignored, as the protocol requires.
+func Serve(r io.Reader, w io.Writer) error {
+	s := &server{
+		w:     w,
+		level: len(engine.Levels),
+		game:  chomp.NewGame(),
+	}
+
+	s.engine = engine.New(engine.Levels[s.level-1])
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		var err error
+		switch fields[0] {
+		case "uci":
+			s.send("id name %s", engineName)
+			s.send("id author %s", engineAuthor)
+			s.send("option name Skill Level type spin default %d min 1 max %d", len(engine.Levels), len(engine.Levels))
+			s.send("option name Ponder type check default false")
+			s.send("option name Book File type string default <empty>")
+			s.send("option name Book Depth type spin default 0 min 0 max %d", maxBookDepth)
+			s.send("option name SyzygyPath type string default <empty>")
+			s.send("option name Weights File type string default <empty>")
+			s.send("option name UCI_Chess960 type check default false")
+			s.send("uciok")
+		case "isready":
+			s.send("readyok")
+		case "setoption":
+			err = s.setOption(fields[1:])
+		case "ucinewgame":
+			s.stop()
+			s.engine.Clear()
+			s.game = chomp.NewGame()
+		case "position":
+			s.stop()
+			err = s.position(fields[1:])
+		case "go":
+			s.stop()
+			err = s.goSearch(fields[1:])
+		case "stop":
+			s.stop()
+		case "ponderhit":
+			s.ponderHit()
+		case "quit":
+			s.stop()
+			return nil
+		}
+
+		if err != nil {
+			s.send("info string %s", err.Error())
+		}
+	}
+
+	s.stop()
+	return scanner.Err()
+}
+
+// Writes a line to the GUI.
+func (s *server) send(format string, args ...any) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	fmt.Fprintf(s.w, format+"\n", args...)
+}
+
+// Handles the setoption command.
+func (s *server) setOption(args []string) error {
+	name, value := "", ""
+	for i, arg := range args {
+		if arg == "value" {
+			name = strings.Join(args[1:i], " ")
+			value = strings.Join(args[i+1:], " ")
+			break
+		}
+	}
+
+	if name == "" && len(args) > 0 {
+		name = strings.Join(args[1:], " ")
+	}
+
+	switch strings.ToLower(name) {
+	case "skill level":
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid skill level '%s'", value)
+		}
+
+		level, err := engine.GetLevel(n)
+		if err != nil {
+			return err
+		}
+
+		s.stop()
+		s.level = n
+		s.engine = engine.New(level)
+		s.engine.Book = s.book
+		s.engine.Tablebase = s.tablebase
+		s.engine.Weights = s.weights
+	case "ponder":
+		// the GUI decides when to ponder, so there's nothing to set up
+	case "book file":
+		var b *book.Book
+		if value != "" && value != "<empty>" {
+			var err error
+			b, err = book.Open(value)
+			if err != nil {
+				return err
+			}
+
+			b.MaxPly = s.bookDepth
+		}
+
+		s.stop()
+		s.book = b
+		s.engine.Book = b
+	case "book depth":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 || n > maxBookDepth {
+			return fmt.Errorf("invalid book depth '%s'", value)
+		}
+
+		s.stop()
+		s.bookDepth = n
+		if s.book != nil {
+			s.book.MaxPly = n
+		}
+	case "syzygypath":
+		var tb *syzygy.Tablebase
+		if value != "" && value != "<empty>" {
+			var err error
+			tb, err = syzygy.Open(value)
+			if err != nil {
+				return err
+			}
+		}
+
+		s.stop()
+		if s.tablebase != nil {
+			s.tablebase.Close()
+		}
+
+		s.tablebase = tb
+		s.engine.Tablebase = tb
+	case "uci_chess960":
+		if value != "true" && value != "false" {
+			return fmt.Errorf("invalid UCI_Chess960 value '%s'", value)
+		}
+
+		s.chess960 = value == "true"
+	case "weights file":
+		var w *engine.Weights
+		if value != "" && value != "<empty>" {
+			var err error
+			w, err = engine.LoadWeights(value)
+			if err != nil {
+				return err
+			}
+		}
+
+		s.stop()
+		s.weights = w
+		s.engine.Weights = w
+	default:
+		return fmt.Errorf("unknown option '%s'", name)
+	}
+
+	return nil
+}
+
+// Handles the position command.
+func (s *server) position(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("position: expected startpos or fen")
+	}
+
+	movesAt := len(args)
+	for i, arg := range args {
+		if arg == "moves" {
+			movesAt = i
+			break
+		}
+	}
+
+	var g *chomp.Game
+	switch args[0] {
+	case "startpos":
+		g = chomp.NewGame()
+	case "fen":
+		var err error
+		g, err = chomp.ParseFEN(strings.Join(args[1:movesAt], " "))
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("position: expected startpos or fen, got '%s'", args[0])
+	}
+
+	// FENs with Chess960 castling rights are Chess960 games either way
+	if s.chess960 {
+		g.Chess960 = true
+	}
+
+	for i := movesAt + 1; i < len(args); i++ {
+		m, err := g.ParseUCI(args[i])
+		if err != nil {
+			return err
+		}
+
+		if err = g.ApplyPastEnd(m); err != nil {
+			return err
+		}
+	}
+
+	s.game = g
+	return nil
+}
+
+// Handles the go command by starting a search in the background.
+func (s *server) goSearch(args []string) error {
+	params, err := ParseSearchParams(args)
+	if err != nil {
+		return err
+	}
+
+	s.engine.OnInfo = func(i engine.Info) {
+		s.send("info %s", infoOf(i).Format())
+	}
+
+	results, err := s.engine.Start(s.game, params.limits())
+	if err != nil {
+		s.send("bestmove (none)")
+		return err
+	}
+
+	search := &search{
+		results:  results,
+		turn:     s.game.Turn,
+		params:   params,
+		released: make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	if !params.Infinite && !params.Ponder {
+		search.release()
+	}
+
+	s.search = search
+	go func() {
+		// the search may end early if it finds a mate or there is only one move,
+		// but the best move can't be sent before the GUI allows it
+		result := <-search.results
+		<-search.released
+
+		line := "bestmove " + result.Move.UCI()
+		if len(result.PV) > 1 {
+			line += " ponder " + result.PV[1].UCI()
+		}
+
+		s.send("%s", line)
+		close(search.done)
+	}()
+
+	return nil
+}
+
+// Stops the search that is running, if any, and waits for its best move to be sent.
+func (s *server) stop() {
+	if s.search == nil {
+		return
+	}
+
+	s.engine.Stop()
+	s.search.release()
+	<-s.search.done
+	if s.search.timer != nil {
+		s.search.timer.Stop()
+	}
+
+	s.search = nil
+}
+
+// Handles the ponderhit command: the opponent played the expected move, so the pondering search
+// goes on as a normal one with the time it has been given.
+func (s *server) ponderHit() {
+	if s.search == nil || !s.search.params.Ponder {
+		return
+	}
+
+	params := s.search.params
+	params.Ponder = false
+	if budget := params.limits().TimeFor(s.search.turn); budget > 0 {
+		s.search.timer = time.AfterFunc(budget, s.engine.Stop)
+	}
+
+	s.search.release()
+}
+
+// Returns the engine limits of a search. Infinite and ponder searches are only limited by depth and nodes.
+func (p SearchParams) limits() engine.Limits {
+	limits := engine.Limits{Depth: p.Depth, Nodes: p.Nodes, Moves: p.SearchMoves}
+	if p.Mate > 0 && (limits.Depth == 0 || limits.Depth > p.Mate*2) {
+		// a mate in n moves is found by searching n moves of both players deep
+		limits.Depth = p.Mate * 2
+	}
+
+	if p.Infinite || p.Ponder {
+		return limits
+	}
+
+	limits.MoveTime = p.MoveTime
+	limits.WhiteTime, limits.BlackTime = p.WhiteTime, p.BlackTime
+	limits.WhiteIncrement, limits.BlackIncrement = p.WhiteIncrement, p.BlackIncrement
+	limits.MovesToGo = p.MovesToGo
+	return limits
+}
+
+// Turns the progress of chomp's engine into an info line.
+func infoOf(i engine.Info) Info {
+	info := Info{Depth: i.Depth, Nodes: i.Nodes, Time: i.Time, PV: i.PV}
+	if i.Score.IsMate() {
+		info.Score.Mate = i.Score.MateIn()
+	} else {
+		info.Score.Centipawns = int(i.Score)
+	}
+
+	if i.Time > 0 {
+		info.NPS = uint64(float64(i.Nodes) / i.Time.Seconds())
+	}
+
+	return info
+}
+
+// Parses the arguments of a go command, without the leading "go".
+func ParseSearchParams(args []string) (SearchParams, error) {
+	var p SearchParams
+	for i := 0; i < len(args); i++ {
+		var err error
+		var n int
+		switch args[i] {
+		case "wtime", "btime", "winc", "binc", "movetime":
+			n, err = intField(args, &i)
+			d := time.Duration(n) * time.Millisecond
+			switch args[i-1] {
+			case "wtime":
+				p.WhiteTime = d
+			case "btime":
+				p.BlackTime = d
+			case "winc":
+				p.WhiteIncrement = d
+			case "binc":
+				p.BlackIncrement = d
+			case "movetime":
+				p.MoveTime = d
+			}
+		case "movestogo":
+			p.MovesToGo, err = intField(args, &i)
+		case "depth":
+			p.Depth, err = intField(args, &i)
+		case "nodes":
+			p.Nodes, err = uintField(args, &i)
+		case "mate":
+			p.Mate, err = intField(args, &i)
+		case "infinite":
+			p.Infinite = true
+		case "ponder":
+			p.Ponder = true
+		case "searchmoves":
+			for i+1 < len(args) {
+				m, err := chomp.ParseUCI(args[i+1])
+				if err != nil {
+					break
+				}
+
+				p.SearchMoves = append(p.SearchMoves, m)
+				i++
+			}
+		}
+
+		if err != nil {
+			return SearchParams{}, fmt.Errorf("go: %s", err.Error())
+		}
+	}
+
+	return p, nil
+}
@@ -0,0 +1,378 @@
+package uci
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apachejuice/chomp/internal/book"
+	"github.com/apachejuice/chomp/internal/chomp"
+	"github.com/apachejuice/chomp/internal/engine"
+	"github.com/apachejuice/chomp/internal/pgn"
+	"github.com/apachejuice/chomp/internal/syzygy"
+)
+
+// A scripted GUI talking to the server over a pair of pipes.
+type scriptedGUI struct {
+	t        *testing.T
+	commands io.WriteCloser
+	lines    chan string
+	// The info lines read while waiting for other lines
+	infos []string
+}
+
+// Starts the server and returns a GUI connected to it.
+func startServer(t *testing.T) *scriptedGUI {
+	t.Helper()
+
+	commandsR, commandsW := io.Pipe()
+	outputR, outputW := io.Pipe()
+	g := &scriptedGUI{t: t, commands: commandsW, lines: make(chan string, 64)}
+	served := make(chan error, 1)
+	go func() {
+		served <- Serve(commandsR, outputW)
+		outputW.Close()
+	}()
+
+	go func() {
+		scanner := bufio.NewScanner(outputR)
+		for scanner.Scan() {
+			g.lines <- scanner.Text()
+		}
+
+		close(g.lines)
+	}()
+
+	t.Cleanup(func() {
+		g.send("quit")
+		commandsW.Close()
+		if err := <-served; err != nil {
+			t.Errorf("the server ended with the error %s", err.Error())
+		}
+	})
+
+	return g
+}
+
+// Sends a command to the server.
+func (g *scriptedGUI) send(command string) {
+	g.t.Helper()
+	if _, err := io.WriteString(g.commands, command+"\n"); err != nil {
+		g.t.Fatalf("%s: %s", command, err.Error())
+	}
+}
+
+// Returns the next line from the server that isn't an info line about the search.
+func (g *scriptedGUI) next() string {
+	g.t.Helper()
+	for {
+		select {
+		case line, ok := <-g.lines:
+			if !ok {
+				g.t.Fatalf("the server stopped writing")
+			}
+
+			if strings.HasPrefix(line, "info ") && !strings.HasPrefix(line, "info string ") {
+				g.infos = append(g.infos, line)
+				continue
+			}
+
+			return line
+		case <-time.After(10 * time.Second):
+			g.t.Fatalf("the server didn't answer in time")
+		}
+	}
+}
+
+// Reads the next line that isn't an info line about the search, failing unless it is the given one.
+func (g *scriptedGUI) expect(want string) {
+	g.t.Helper()
+	if line := g.next(); line != want {
+		g.t.Fatalf("got %q, want %q", line, want)
+	}
+}
+
+// Reads the best move, failing unless it is legal in the game.
+func (g *scriptedGUI) bestMove(game *chomp.Game) chomp.Move {
+	g.t.Helper()
+	line := g.next()
+	fields := strings.Fields(line)
+	if len(fields) < 2 || fields[0] != "bestmove" {
+		g.t.Fatalf("got %q, want the best move", line)
+	}
+
+	m, err := game.ParseUCI(fields[1])
+	if err != nil {
+		g.t.Fatalf("got the best move %s: %s", fields[1], err.Error())
+	}
+
+	return m
+}
+
+func TestServeHandshake(t *testing.T) {
+	g := startServer(t)
+	g.send("uci")
+	g.expect("id name chomp")
+	g.expect("id author the chomp authors")
+	g.expect(fmt.Sprintf("option name Skill Level type spin default %d min 1 max %d", len(engine.Levels), len(engine.Levels)))
+	g.expect("option name Ponder type check default false")
+	g.expect("option name Book File type string default <empty>")
+	g.expect("option name Book Depth type spin default 0 min 0 max 1000")
+	g.expect("option name SyzygyPath type string default <empty>")
+	g.expect("option name Weights File type string default <empty>")
+	g.expect("option name UCI_Chess960 type check default false")
+	g.expect("uciok")
+	g.send("isready")
+	g.expect("readyok")
+
+	// unknown commands are ignored
+	g.send("xyzzy")
+	g.send("isready")
+	g.expect("readyok")
+}
+
+func TestServeGoDepth(t *testing.T) {
+	g := startServer(t)
+	g.send("position startpos moves e2e4 e7e5 g1f3")
+	g.send("go depth 2")
+
+	game := chomp.NewGame()
+	for _, s := range []string{"e2e4", "e7e5", "g1f3"} {
+		m, _ := game.ParseUCI(s)
+		game.Apply(m)
+	}
+
+	g.bestMove(game)
+	if len(g.infos) == 0 {
+		t.Fatalf("the search sent no info")
+	}
+
+	for _, line := range g.infos {
+		info, err := ParseInfo(line)
+		if err != nil || info.Depth > 2 {
+			t.Errorf("got the info %q with the error %v", line, err)
+		}
+	}
+
+	// a position the moves can't be played in is reported, and the last good one is kept
+	g.send("position startpos moves e2e5")
+	g.expect("info string illegal move 'e2e5'")
+	g.send("go depth 1")
+	g.bestMove(game)
+}
+
+func TestServeSearchMoves(t *testing.T) {
+	g := startServer(t)
+	g.send("position startpos")
+	g.send("go depth 2 searchmoves a2a3 h2h3")
+	if m := g.bestMove(chomp.NewGame()); m.UCI() != "a2a3" && m.UCI() != "h2h3" {
+		t.Errorf("got the best move %s, want one of the moves searched", m.UCI())
+	}
+
+	g.send("position fen 4k3/P7/8/8/8/8/8/4K3 w - - 0 1")
+	g.send("go depth 2 searchmoves a7a8n")
+	game, _ := chomp.ParseFEN("4k3/P7/8/8/8/8/8/4K3 w - - 0 1")
+	if m := g.bestMove(game); m.UCI() != "a7a8n" {
+		t.Errorf("got the best move %s, want a7a8n", m.UCI())
+	}
+}
+
+func TestServeInfinite(t *testing.T) {
+	g := startServer(t)
+	g.send("position startpos")
+	g.send("go infinite")
+
+	// the best move isn't sent until the GUI stops the search, so readyok comes first
+	g.send("isready")
+	g.expect("readyok")
+	g.send("stop")
+	g.bestMove(chomp.NewGame())
+}
+
+func TestServePonder(t *testing.T) {
+	g := startServer(t)
+	g.send("position startpos moves e2e4")
+	g.send("go ponder depth 2 wtime 1000 btime 1000")
+
+	// even a search that is done keeps the best move back until the opponent plays the expected move
+	time.Sleep(50 * time.Millisecond)
+	g.send("isready")
+	g.expect("readyok")
+	g.send("ponderhit")
+
+	game := chomp.NewGame()
+	m, _ := game.ParseUCI("e2e4")
+	game.Apply(m)
+	g.bestMove(game)
+}
+
+func TestServeGameOver(t *testing.T) {
+	g := startServer(t)
+	g.send("position fen rnb1kbnr/pppp1ppp/8/4p3/6Pq/5P2/PPPPP2P/RNBQKBNR w KQkq - 1 3")
+	g.send("go depth 2")
+	g.expect("bestmove (none)")
+	g.expect("info string the game is over, there are no moves to search")
+}
+
+func TestServeSetOption(t *testing.T) {
+	g := startServer(t)
+	tests := []struct {
+		command, reply string
+	}{
+		{"setoption name Skill Level value 1", ""},
+		{"setoption name skill level value 3", ""},
+		{"setoption name Ponder value true", ""},
+		{"setoption name Skill Level value 9", "info string strength level must be between 1 and 8, got 9"},
+		{"setoption name Skill Level value strong", "info string invalid skill level 'strong'"},
+		{"setoption name Hash value 16", "info string unknown option 'Hash'"},
+		{"setoption name Clear Hash", "info string unknown option 'Clear Hash'"},
+		{"setoption name Book Depth value 4", ""},
+		{"setoption name Book Depth value -1", "info string invalid book depth '-1'"},
+		{"setoption name Book File value <empty>", ""},
+		{"setoption name Weights File value <empty>", ""},
+	}
+
+	for _, test := range tests {
+		g.send(test.command)
+		g.send("isready")
+		if test.reply != "" {
+			g.expect(test.reply)
+		}
+
+		g.expect("readyok")
+	}
+
+	// the last good level holds, and limits the depth below what the GUI asks for
+	g.send("position startpos")
+	g.send("go depth 10")
+	g.bestMove(chomp.NewGame())
+	for _, line := range g.infos {
+		if info, err := ParseInfo(line); err != nil || info.Depth > 3 {
+			t.Errorf("got the info %q at skill level 3", line)
+		}
+	}
+}
+
+func TestParseSearchParams(t *testing.T) {
+	p, err := ParseSearchParams(strings.Fields("wtime 60000 btime 59000 winc 1000 binc 500 movestogo 12 depth 8 nodes 100000 searchmoves e2e4 d2d4 infinite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p.WhiteTime != time.Minute || p.BlackTime != 59*time.Second || p.WhiteIncrement != time.Second || p.BlackIncrement != 500*time.Millisecond {
+		t.Errorf("got the clocks %+v", p)
+	}
+
+	if p.MovesToGo != 12 || p.Depth != 8 || p.Nodes != 100000 || !p.Infinite || len(p.SearchMoves) != 2 {
+		t.Errorf("got the params %+v", p)
+	}
+
+	// infinite searches ignore the clock
+	if limits := p.limits(); limits.WhiteTime != 0 || limits.Depth != 8 || len(limits.Moves) != 2 {
+		t.Errorf("got the limits %+v", limits)
+	}
+
+	if _, err := ParseSearchParams([]string{"depth", "deep"}); err == nil {
+		t.Errorf("a depth that isn't a number was accepted")
+	}
+}
+
+func TestServeBook(t *testing.T) {
+	games, err := pgn.ReadAll(strings.NewReader("1. d4 d5 *\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := book.NewBuilder(0)
+	if err := b.AddGame(games[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "book.bin")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := b.Book().Write(f); err != nil {
+		t.Fatal(err)
+	}
+
+	f.Close()
+
+	g := startServer(t)
+	g.send("setoption name Book File value " + path)
+	g.send("position startpos")
+	g.send("go depth 1")
+	g.expect("bestmove d2d4")
+
+	// past the book depth, the engine searches instead
+	g.send("setoption name Book Depth value 1")
+	g.send("position startpos moves d2d4")
+	g.send("go depth 1")
+	game := chomp.NewGame()
+	m, _ := game.ParseUCI("d2d4")
+	game.Apply(m)
+	g.bestMove(game)
+}
+
+func TestServeTablebase(t *testing.T) {
+	tb, err := syzygy.Open("../syzygy/testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer tb.Close()
+
+	g := startServer(t)
+	g.send("setoption name SyzygyPath value " + t.TempDir())
+	g.send("isready")
+	if line := g.next(); !strings.HasPrefix(line, "info string ") {
+		t.Errorf("got %q for a directory without tables, want an error", line)
+	}
+
+	g.expect("readyok")
+
+	// the engine plays a move that keeps the fastest win the tables know of
+	const fen = "k7/8/2K5/8/8/8/8/7R w - - 0 1"
+	g.send("setoption name SyzygyPath value ../syzygy/testdata")
+	g.send("position fen " + fen)
+	g.send("go depth 1")
+	game, _ := chomp.ParseFEN(fen)
+	m := g.bestMove(game)
+	r, err := tb.Probe(game)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, mr := range r.Moves {
+		if mr.Move == m && (mr.WDL != r.Moves[0].WDL || mr.DTZ != r.Moves[0].DTZ) {
+			t.Errorf("played %s, which is a %s in %d, want a %s in %d", m.UCI(), mr.WDL, mr.DTZ, r.Moves[0].WDL, r.Moves[0].DTZ)
+		}
+	}
+}
+
+func TestServeWeights(t *testing.T) {
+	dir := t.TempDir()
+	good, bad := filepath.Join(dir, "good.json"), filepath.Join(dir, "bad.json")
+	if err := os.WriteFile(good, []byte(`{"kingShield": 20}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(bad, []byte(`{"mobility": [1, 2]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	g := startServer(t)
+	g.send("setoption name Weights File value " + bad)
+	g.expect("info string invalid weights file " + bad + ": mobility has 2 values, want 6")
+	g.send("setoption name Weights File value " + good)
+	g.send("position startpos")
+	g.send("go depth 1")
+	g.bestMove(chomp.NewGame())
+}